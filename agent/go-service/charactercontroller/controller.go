@@ -3,6 +3,7 @@ package charactercontroller
 import (
 	"encoding/json"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/camerasens"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
 )
@@ -56,7 +57,7 @@ func (a *CharacterControllerYawDeltaAction) Run(ctx *maa.Context, arg *maa.Custo
 		return false
 	}
 	delta := params.Delta % 360
-	dx := delta * 2 // mapTracker RotationSpeed默认2
+	dx := camerasens.SwipePixels(ctx.GetTasker().GetController(), float64(delta))
 	rotateView(ctx, dx, 0)
 	return true
 }
@@ -72,7 +73,8 @@ func (a *CharacterControllerPitchDeltaAction) Run(ctx *maa.Context, arg *maa.Cus
 		return false
 	}
 	delta := params.Delta % 360
-	dy := delta * 2
+	// Pitch is not calibrated, assume the same sensitivity as yaw
+	dy := camerasens.SwipePixels(ctx.GetTasker().GetController(), float64(delta))
 	rotateView(ctx, 0, dy)
 	return true
 }
@@ -0,0 +1,287 @@
+// Copyright (c) 2026 Harry Huang
+
+// Command map-tracker-lint checks the parameters of all MapTrackerMove and MapTrackerAssertLocation
+// nodes in the pipelines against the map resources, so that broken routes are caught before running.
+//
+// Usage:
+//
+//	go run ./cmd/map-tracker-lint -resource ../../assets/resource [-pipeline <dir>] [-format json|text] [-strict]
+//
+// It exits with code 1 if any error is found (or any warning with -strict), which fits pre-commit hooks.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	maptracker "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// issue represents a lint issue located in a pipeline node
+type issue struct {
+	File      string `json:"file"`
+	Node      string `json:"node"`
+	Component string `json:"component"`
+	maptracker.LintIssue
+}
+
+// report represents the whole lint report
+type report struct {
+	FilesCount    int     `json:"filesCount"`
+	NodesCount    int     `json:"nodesCount"`
+	ErrorsCount   int     `json:"errorsCount"`
+	WarningsCount int     `json:"warningsCount"`
+	Issues        []issue `json:"issues"`
+}
+
+func main() {
+	resourceDir := flag.String("resource", "../../assets/resource", "resource directory containing image/MapTracker")
+	pipelineDir := flag.String("pipeline", "", "pipeline directory to check (default: <resource>/pipeline)")
+	format := flag.String("format", "text", "output format, json or text")
+	strict := flag.Bool("strict", false, "treat warnings as errors for the exit code")
+	verbose := flag.Bool("verbose", false, "print resource loading logs")
+	flag.Parse()
+
+	level := zerolog.WarnLevel
+	if *verbose {
+		level = zerolog.DebugLevel
+	}
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).Level(level).With().Timestamp().Logger()
+
+	if *format != "json" && *format != "text" {
+		fmt.Fprintln(os.Stderr, "Usage: map-tracker-lint [-format json|text] [options]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if *pipelineDir == "" {
+		*pipelineDir = filepath.Join(*resourceDir, "pipeline")
+	}
+
+	rep, err := lint(*resourceDir, *pipelineDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Lint failed")
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to marshal report")
+		}
+		fmt.Println(string(data))
+	} else {
+		printReport(rep)
+	}
+
+	if rep.ErrorsCount > 0 || (*strict && rep.WarningsCount > 0) {
+		os.Exit(1)
+	}
+}
+
+func lint(resourceDir, pipelineDir string) (*report, error) {
+	linter, err := maptracker.NewLinter(resourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create linter: %w", err)
+	}
+
+	rep := &report{Issues: make([]issue, 0)}
+	err = filepath.WalkDir(pipelineDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(strings.ToLower(d.Name()), ".json") {
+			return nil
+		}
+		rel, err := filepath.Rel(pipelineDir, path)
+		if err != nil {
+			rel = path
+		}
+		rel = filepath.ToSlash(rel)
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var nodes map[string]json.RawMessage
+		if err := json.Unmarshal(stripJSONC(data), &nodes); err != nil {
+			rep.addIssue(issue{File: rel, LintIssue: maptracker.LintIssue{
+				Severity: maptracker.LINT_ERROR, Code: "invalid_json", Message: err.Error(),
+			}})
+			return nil
+		}
+		rep.FilesCount++
+
+		names := make([]string, 0, len(nodes))
+		for name := range nodes {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if strings.HasPrefix(name, "$") {
+				continue
+			}
+			component, paramStr, ok := parseNode(nodes[name])
+			if !ok {
+				continue
+			}
+			var issues []maptracker.LintIssue
+			switch component {
+			case "MapTrackerMove":
+				issues = linter.LintMove(paramStr)
+			case "MapTrackerAssertLocation":
+				issues = linter.LintAssertLocation(paramStr)
+			default:
+				continue
+			}
+			rep.NodesCount++
+			for _, is := range issues {
+				rep.addIssue(issue{File: rel, Node: name, Component: component, LintIssue: is})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk pipeline directory: %w", err)
+	}
+	return rep, nil
+}
+
+func (r *report) addIssue(is issue) {
+	if is.Severity == maptracker.LINT_ERROR {
+		r.ErrorsCount++
+	} else {
+		r.WarningsCount++
+	}
+	r.Issues = append(r.Issues, is)
+}
+
+// pipelineNode holds the fields of a node that may refer to a custom component,
+// in both the flat form and the object form of action and recognition
+type pipelineNode struct {
+	Action                 json.RawMessage `json:"action"`
+	Recognition            json.RawMessage `json:"recognition"`
+	CustomAction           string          `json:"custom_action"`
+	CustomActionParam      json.RawMessage `json:"custom_action_param"`
+	CustomRecognition      string          `json:"custom_recognition"`
+	CustomRecognitionParam json.RawMessage `json:"custom_recognition_param"`
+}
+
+// parseNode returns the custom component name and its parameters of a node
+func parseNode(raw json.RawMessage) (string, string, bool) {
+	var node pipelineNode
+	if err := json.Unmarshal(raw, &node); err != nil {
+		return "", "", false
+	}
+
+	// Object form: {"type": "Custom", "param": {"custom_action": ..., "custom_action_param": ...}}
+	for _, field := range []json.RawMessage{node.Action, node.Recognition} {
+		var obj struct {
+			Param pipelineNode `json:"param"`
+		}
+		if len(field) == 0 || field[0] != '{' || json.Unmarshal(field, &obj) != nil {
+			continue
+		}
+		if obj.Param.CustomAction != "" {
+			return obj.Param.CustomAction, paramString(obj.Param.CustomActionParam), true
+		}
+		if obj.Param.CustomRecognition != "" {
+			return obj.Param.CustomRecognition, paramString(obj.Param.CustomRecognitionParam), true
+		}
+	}
+
+	if node.CustomAction != "" {
+		return node.CustomAction, paramString(node.CustomActionParam), true
+	}
+	if node.CustomRecognition != "" {
+		return node.CustomRecognition, paramString(node.CustomRecognitionParam), true
+	}
+	return "", "", false
+}
+
+// paramString converts a custom param, which may be a JSON object or a JSON string, to a string
+func paramString(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// stripJSONC removes comments, then trailing commas, outside of strings
+func stripJSONC(data []byte) []byte {
+	return scanJSON(scanJSON(data, true), false)
+}
+
+// scanJSON copies data skipping comments if comments is set, or trailing commas otherwise
+func scanJSON(data []byte, comments bool) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case comments && c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+		case comments && c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case !comments && c == ',':
+			// Drop the comma if the next significant character closes the object or array
+			rest := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(rest) > 0 && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+func printReport(rep *report) {
+	for _, is := range rep.Issues {
+		loc := is.File
+		if is.Node != "" {
+			loc += " " + is.Node
+		}
+		if is.Segment != nil {
+			loc += fmt.Sprintf(" segment %d", *is.Segment)
+		}
+		if is.Index != nil {
+			loc += fmt.Sprintf(" index %d", *is.Index)
+		}
+		fmt.Printf("%-7s %s [%s] %s\n", is.Severity, loc, is.Code, is.Message)
+	}
+	fmt.Printf("Files: %d, nodes: %d, errors: %d, warnings: %d\n",
+		rep.FilesCount, rep.NodesCount, rep.ErrorsCount, rep.WarningsCount)
+}
@@ -0,0 +1,351 @@
+// Copyright (c) 2026 Harry Huang
+
+// Command map-tracker-replay replays a directory of recorded 1280x720 frames through MapTrackerInfer
+// offline, and reports accuracy and latency statistics for regression checks.
+//
+// Usage:
+//
+//	go run ./cmd/map-tracker-replay -resource ../../assets/resource -frames ./frames [-truth truth.json] [-report report.json]
+//
+// The optional ground truth sidecar is a JSON object keyed by frame file name:
+//
+//	{"0001.png": {"map_name": "map02_lv001", "x": 688, "y": 350, "rot": 90, "timestamp_ms": 0}}
+//
+// Any field may be omitted. Frames without timestamp are spaced by -interval milliseconds.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	maptracker "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	xdraw "golang.org/x/image/draw"
+)
+
+// groundTruth represents the expected inference result of one frame
+type groundTruth struct {
+	MapName     string   `json:"map_name,omitempty"`
+	X           *int     `json:"x,omitempty"`
+	Y           *int     `json:"y,omitempty"`
+	Rot         *float64 `json:"rot,omitempty"`
+	TimestampMs *int64   `json:"timestamp_ms,omitempty"`
+}
+
+// frameRecord represents the replay result of one frame
+type frameRecord struct {
+	Frame       string   `json:"frame"`
+	TimestampMs int64    `json:"timestampMs"`
+	Hit         bool     `json:"hit"`
+	MapName     string   `json:"mapName,omitempty"`
+	X           int      `json:"x,omitempty"`
+	Y           int      `json:"y,omitempty"`
+	Rot         float64  `json:"rot,omitempty"`
+	CameraRot   float64  `json:"cameraRot,omitempty"`
+	LocConf     float64  `json:"locConf,omitempty"`
+	RotConf     float64  `json:"rotConf,omitempty"`
+	InferMode   string   `json:"inferMode,omitempty"`
+	LatencyMs   float64  `json:"latencyMs"`
+	PosError    *float64 `json:"posError,omitempty"`
+	RotError    *float64 `json:"rotError,omitempty"`
+	MapMismatch bool     `json:"mapMismatch,omitempty"`
+	Lost        bool     `json:"lost"`
+}
+
+// trackLossEvent represents a continuous run of lost frames
+type trackLossEvent struct {
+	StartFrame  string `json:"startFrame"`
+	EndFrame    string `json:"endFrame"`
+	FramesCount int    `json:"framesCount"`
+	DurationMs  int64  `json:"durationMs"`
+}
+
+// distribution summarizes a series of values
+type distribution struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// report represents the whole replay report
+type report struct {
+	FramesCount     int              `json:"framesCount"`
+	HitsCount       int              `json:"hitsCount"`
+	HitModes        map[string]int   `json:"hitModes"`
+	MapMismatches   int              `json:"mapMismatches"`
+	Latency         distribution     `json:"latencyMs"`
+	PosError        distribution     `json:"posError"`
+	RotError        distribution     `json:"rotError"`
+	TrackLossEvents []trackLossEvent `json:"trackLossEvents"`
+	Frames          []frameRecord    `json:"frames,omitempty"`
+}
+
+func main() {
+	resourceDir := flag.String("resource", "resource", "resource directory containing image/MapTracker")
+	framesDir := flag.String("frames", "", "directory of recorded frames (PNG or JPEG, sorted by file name)")
+	truthPath := flag.String("truth", "", "ground truth JSON sidecar (default: <frames>/ground_truth.json if exists)")
+	paramStr := flag.String("param", "", "custom_recognition_param JSON for MapTrackerInfer")
+	interval := flag.Int64("interval", maptracker.INFER_INTERVAL_MS, "milliseconds between frames without timestamp")
+	lostError := flag.Float64("lost-error", maptracker.CONVINCED_DISTANCE_THRESHOLD, "position error beyond which a frame counts as lost")
+	reportPath := flag.String("report", "", "write the JSON report (with per-frame records) to this file")
+	cacheBudget := flag.Int("cache-budget", -1, "memory budget of loaded map variants in megabytes (default: $"+maptracker.MAP_CACHE_BUDGET_ENV+", or 256)")
+	verbose := flag.Bool("verbose", false, "print inference debug logs")
+	flag.Parse()
+
+	level := zerolog.WarnLevel
+	if *verbose {
+		level = zerolog.DebugLevel
+	}
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).Level(level).With().Timestamp().Logger()
+
+	if *framesDir == "" {
+		fmt.Fprintln(os.Stderr, "Usage: map-tracker-replay -frames <dir> [options]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	if *cacheBudget >= 0 {
+		maptracker.SetMapCacheBudget(*cacheBudget)
+	}
+	rep, err := replay(*resourceDir, *framesDir, *truthPath, *paramStr, *interval, *lostError)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Replay failed")
+	}
+
+	printSummary(rep)
+
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to marshal report")
+		}
+		if err := os.WriteFile(*reportPath, data, 0644); err != nil {
+			log.Fatal().Err(err).Msg("Failed to write report")
+		}
+	}
+}
+
+func replay(resourceDir, framesDir, truthPath, paramStr string, interval int64, lostError float64) (*report, error) {
+	replayer, err := maptracker.NewReplayer(resourceDir, paramStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create replayer: %w", err)
+	}
+
+	frames, err := listFrames(framesDir)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no frames found in %s", framesDir)
+	}
+
+	truths, err := loadGroundTruth(framesDir, truthPath)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &report{HitModes: make(map[string]int)}
+	var latencies, posErrors, rotErrors []float64
+	lossStart := -1
+
+	// Tracking state treats zero timestamps as "never hit", so replay on top of the current epoch
+	baseMs := time.Now().UnixMilli()
+
+	for idx, name := range frames {
+		img, err := loadFrame(filepath.Join(framesDir, name))
+		if err != nil {
+			log.Warn().Err(err).Str("frame", name).Msg("Skipping unreadable frame")
+			continue
+		}
+
+		truth, hasTruth := truths[name]
+		timestampMs := int64(idx) * interval
+		if hasTruth && truth.TimestampMs != nil {
+			timestampMs = *truth.TimestampMs
+		}
+
+		t0 := time.Now()
+		result := replayer.Infer(img, baseMs+timestampMs)
+		latencyMs := float64(time.Since(t0).Microseconds()) / 1000.0
+
+		rec := frameRecord{Frame: name, TimestampMs: timestampMs, LatencyMs: latencyMs, Hit: result != nil}
+		latencies = append(latencies, latencyMs)
+
+		if result != nil {
+			rep.HitsCount++
+			rep.HitModes[result.InferMode]++
+			rec.MapName, rec.X, rec.Y, rec.Rot, rec.CameraRot = result.MapName, result.X, result.Y, result.Rot, result.CameraRot
+			rec.LocConf, rec.RotConf, rec.InferMode = result.LocConf, result.RotConf, result.InferMode
+
+			if hasTruth {
+				if truth.MapName != "" && truth.MapName != result.MapName {
+					rec.MapMismatch = true
+					rep.MapMismatches++
+				} else if truth.X != nil && truth.Y != nil {
+					e := math.Hypot(float64(result.X-*truth.X), float64(result.Y-*truth.Y))
+					rec.PosError = &e
+					posErrors = append(posErrors, e)
+				}
+				if truth.Rot != nil {
+					d := math.Mod(math.Abs(result.Rot-*truth.Rot), 360)
+					e := math.Min(d, 360-d)
+					rec.RotError = &e
+					rotErrors = append(rotErrors, e)
+				}
+			}
+		}
+
+		rec.Lost = result == nil || rec.MapMismatch || (rec.PosError != nil && *rec.PosError > lostError)
+		rep.Frames = append(rep.Frames, rec)
+
+		// Track loss events are continuous runs of lost frames
+		n := len(rep.Frames) - 1
+		if rec.Lost && lossStart < 0 {
+			lossStart = n
+		}
+		if !rec.Lost && lossStart >= 0 {
+			rep.TrackLossEvents = append(rep.TrackLossEvents, newTrackLossEvent(rep.Frames, lossStart, n-1))
+			lossStart = -1
+		}
+	}
+	if lossStart >= 0 {
+		rep.TrackLossEvents = append(rep.TrackLossEvents, newTrackLossEvent(rep.Frames, lossStart, len(rep.Frames)-1))
+	}
+
+	rep.FramesCount = len(rep.Frames)
+	rep.Latency = summarize(latencies)
+	rep.PosError = summarize(posErrors)
+	rep.RotError = summarize(rotErrors)
+	return rep, nil
+}
+
+func listFrames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read frames directory: %w", err)
+	}
+	frames := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".png", ".jpg", ".jpeg":
+			frames = append(frames, entry.Name())
+		}
+	}
+	slices.Sort(frames)
+	return frames, nil
+}
+
+func loadGroundTruth(framesDir, truthPath string) (map[string]groundTruth, error) {
+	truths := make(map[string]groundTruth)
+	if truthPath == "" {
+		truthPath = filepath.Join(framesDir, "ground_truth.json")
+		if _, err := os.Stat(truthPath); err != nil {
+			return truths, nil
+		}
+	}
+	data, err := os.ReadFile(truthPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ground truth: %w", err)
+	}
+	if err := json.Unmarshal(data, &truths); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ground truth: %w", err)
+	}
+	return truths, nil
+}
+
+// loadFrame decodes a frame and scales it to the working resolution if needed
+func loadFrame(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	if b.Dx() == maptracker.WORK_W && b.Dy() == maptracker.WORK_H {
+		return img, nil
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, maptracker.WORK_W, maptracker.WORK_H))
+	xdraw.BiLinear.Scale(dst, dst.Rect, img, b, xdraw.Src, nil)
+	return dst, nil
+}
+
+func newTrackLossEvent(frames []frameRecord, start, end int) trackLossEvent {
+	return trackLossEvent{
+		StartFrame:  frames[start].Frame,
+		EndFrame:    frames[end].Frame,
+		FramesCount: end - start + 1,
+		DurationMs:  frames[end].TimestampMs - frames[start].TimestampMs,
+	}
+}
+
+func summarize(values []float64) distribution {
+	if len(values) == 0 {
+		return distribution{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	percentile := func(p float64) float64 {
+		return sorted[min(len(sorted)-1, int(math.Ceil(p*float64(len(sorted))))-1)]
+	}
+	return distribution{
+		Count: len(sorted),
+		Mean:  sum / float64(len(sorted)),
+		P50:   percentile(0.50),
+		P90:   percentile(0.90),
+		P99:   percentile(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+func printSummary(rep *report) {
+	fmt.Printf("Frames: %d, hits: %d (%.1f%%), map mismatches: %d\n",
+		rep.FramesCount, rep.HitsCount, 100*float64(rep.HitsCount)/float64(max(rep.FramesCount, 1)), rep.MapMismatches)
+
+	modes := make([]string, 0, len(rep.HitModes))
+	for mode := range rep.HitModes {
+		modes = append(modes, mode)
+	}
+	slices.Sort(modes)
+	for _, mode := range modes {
+		fmt.Printf("  %-14s %d\n", mode, rep.HitModes[mode])
+	}
+
+	printDist := func(name string, d distribution) {
+		fmt.Printf("%-12s n=%-5d mean=%-8.2f p50=%-8.2f p90=%-8.2f p99=%-8.2f max=%.2f\n",
+			name, d.Count, d.Mean, d.P50, d.P90, d.P99, d.Max)
+	}
+	printDist("Latency(ms)", rep.Latency)
+	printDist("PosError", rep.PosError)
+	printDist("RotError", rep.RotError)
+
+	fmt.Printf("Track loss events: %d\n", len(rep.TrackLossEvents))
+	for _, e := range rep.TrackLossEvents {
+		fmt.Printf("  %s .. %s (%d frames, %d ms)\n", e.StartFrame, e.EndFrame, e.FramesCount, e.DurationMs)
+	}
+}
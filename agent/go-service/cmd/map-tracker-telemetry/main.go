@@ -0,0 +1,394 @@
+// Copyright (c) 2026 Harry Huang
+
+// Command map-tracker-telemetry aggregates the JSON-lines telemetry written by MapTrackerInfer and
+// MapTrackerMove with "telemetry": true, into percentiles and failure counts for tuning the constants.
+//
+// Usage:
+//
+//	go run ./cmd/map-tracker-telemetry [-format json|text] [-entry <regex>] [file or directory ...]
+//
+// Directories are expanded to the *.jsonl files inside. Without arguments, the latest file in
+// debug/map_tracker_telemetry is used, i.e. the last session of the agent started from this directory.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	maptracker "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// distribution summarizes a series of values
+type distribution struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// inferReport summarizes the inferences
+type inferReport struct {
+	Count       int                     `json:"count"`
+	HitsCount   int                     `json:"hitsCount"`
+	MissesCount int                     `json:"missesCount"`
+	HitModes    map[string]int          `json:"hitModes"`
+	LocConf     distribution            `json:"locConf"`
+	RotConf     distribution            `json:"rotConf"`
+	CameraConf  distribution            `json:"cameraConf"`
+	LocTimeMs   distribution            `json:"locTimeMs"`
+	RotTimeMs   distribution            `json:"rotTimeMs"`
+	InferTimeMs distribution            `json:"inferTimeMs"`
+	ModeTimeMs  map[string]distribution `json:"modeTimeMs"`
+}
+
+// moveReport summarizes the MapTrackerMove runs and their control decisions
+type moveReport struct {
+	RunsCount          int            `json:"runsCount"`
+	SucceededCount     int            `json:"succeededCount"`
+	FailedCount        int            `json:"failedCount"`
+	UnfinishedCount    int            `json:"unfinishedCount"`
+	FailureReasons     map[string]int `json:"failureReasons"`
+	DurationS          distribution   `json:"durationS"`
+	ModeChanges        map[string]int `json:"modeChanges"`
+	RotationsCount     int            `json:"rotationsCount"`
+	RotationDelta      distribution   `json:"rotationDelta"`
+	RotationSpeed      distribution   `json:"rotationSpeed"`
+	IdealRotationSpeed distribution   `json:"idealRotationSpeed"`
+	StuckCount         int            `json:"stuckCount"`
+	StuckRecoveries    map[string]int `json:"stuckRecoveries"`
+	StuckGiveUps       map[string]int `json:"stuckGiveUps"`
+}
+
+// report represents the whole telemetry summary
+type report struct {
+	Files        []string    `json:"files"`
+	RecordsCount int         `json:"recordsCount"`
+	InvalidLines int         `json:"invalidLines"`
+	StartTime    string      `json:"startTime,omitempty"`
+	EndTime      string      `json:"endTime,omitempty"`
+	Infer        inferReport `json:"infer"`
+	Move         moveReport  `json:"move"`
+}
+
+func main() {
+	format := flag.String("format", "text", "output format, json or text")
+	entry := flag.String("entry", "", "only aggregate records whose task entry matches this regex")
+	flag.Parse()
+
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).Level(zerolog.WarnLevel).With().Timestamp().Logger()
+
+	if *format != "json" && *format != "text" {
+		fmt.Fprintln(os.Stderr, "Usage: map-tracker-telemetry [-format json|text] [options] [file or directory ...]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	var entryRegex *regexp.Regexp
+	if *entry != "" {
+		var err error
+		if entryRegex, err = regexp.Compile(*entry); err != nil {
+			log.Fatal().Err(err).Msg("Invalid entry regex")
+		}
+	}
+
+	files, err := listFiles(flag.Args())
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to list telemetry files")
+	}
+	if len(files) == 0 {
+		log.Fatal().Msg("No telemetry file found")
+	}
+
+	rep, err := aggregate(files, entryRegex)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Aggregation failed")
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to marshal report")
+		}
+		fmt.Println(string(data))
+	} else {
+		printReport(rep)
+	}
+}
+
+// listFiles expands the arguments into telemetry files, defaulting to the latest file of the telemetry directory
+func listFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		files, err := listDir(maptracker.TELEMETRY_DIR)
+		if err != nil || len(files) == 0 {
+			return nil, err
+		}
+		return files[len(files)-1:], nil
+	}
+
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		dirFiles, err := listDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// listDir returns the *.jsonl files of a directory sorted by name, which is the session start time
+func listDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(strings.ToLower(e.Name()), ".jsonl") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+func aggregate(files []string, entryRegex *regexp.Regexp) (*report, error) {
+	rep := &report{
+		Files: files,
+		Infer: inferReport{HitModes: make(map[string]int), ModeTimeMs: make(map[string]distribution)},
+		Move: moveReport{
+			FailureReasons:  make(map[string]int),
+			ModeChanges:     make(map[string]int),
+			StuckRecoveries: make(map[string]int),
+			StuckGiveUps:    make(map[string]int),
+		},
+	}
+
+	var (
+		locConf, rotConf, cameraConf              []float64
+		locTimeMs, rotTimeMs, inferTimeMs         []float64
+		modeTimeMs                                = make(map[string][]float64)
+		durationS, rotDelta, rotSpeed, idealSpeed []float64
+		runs                                      = make(map[int64]bool) // Whether the run has ended
+		startMs, endMs                            int64
+	)
+
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var rec maptracker.TelemetryRecord
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				rep.InvalidLines++
+				continue
+			}
+			if entryRegex != nil && !entryRegex.MatchString(rec.Entry) {
+				continue
+			}
+			rep.RecordsCount++
+			if startMs == 0 || rec.TimeMs < startMs {
+				startMs = rec.TimeMs
+			}
+			endMs = max(endMs, rec.TimeMs)
+
+			switch rec.Event {
+			case maptracker.TELEMETRY_INFER:
+				rep.Infer.Count++
+				res := rec.Infer
+				if res == nil {
+					rep.Infer.MissesCount++
+					continue
+				}
+				rep.Infer.HitsCount++
+				rep.Infer.HitModes[res.InferMode]++
+				locConf = append(locConf, res.LocConf)
+				rotConf = append(rotConf, res.RotConf)
+				cameraConf = append(cameraConf, res.CameraConf)
+				locTimeMs = append(locTimeMs, float64(res.LocTimeMs))
+				rotTimeMs = append(rotTimeMs, float64(res.RotTimeMs))
+				inferTimeMs = append(inferTimeMs, float64(res.InferTimeMs))
+				modeTimeMs[res.InferMode] = append(modeTimeMs[res.InferMode], float64(res.InferTimeMs))
+			case maptracker.TELEMETRY_MOVE_START:
+				rep.Move.RunsCount++
+				runs[rec.Move] = false
+			case maptracker.TELEMETRY_MOVE_END:
+				runs[rec.Move] = true
+				if rec.Ok != nil && *rec.Ok {
+					rep.Move.SucceededCount++
+				} else {
+					rep.Move.FailedCount++
+					reason := rec.Reason
+					if reason == "" {
+						reason = "other"
+					}
+					rep.Move.FailureReasons[reason]++
+				}
+				if rec.Move > 0 {
+					durationS = append(durationS, float64(rec.TimeMs-rec.Move)/1000)
+				}
+			case maptracker.TELEMETRY_MODE:
+				prev := rec.PrevMode
+				if prev == "" {
+					prev = "none"
+				}
+				rep.Move.ModeChanges[prev+" -> "+rec.Mode]++
+			case maptracker.TELEMETRY_ROTATE:
+				rep.Move.RotationsCount++
+				rotDelta = append(rotDelta, math.Abs(rec.DeltaRot))
+			case maptracker.TELEMETRY_ROTATION_SPEED:
+				rotSpeed = append(rotSpeed, rec.RotationSpeed)
+				idealSpeed = append(idealSpeed, rec.IdealSpeed)
+			case maptracker.TELEMETRY_STUCK:
+				rep.Move.StuckCount++
+				if rec.Strategy != "" {
+					rep.Move.StuckRecoveries[rec.Strategy]++
+				} else if rec.Reason != "" {
+					rep.Move.StuckGiveUps[rec.Reason]++
+				}
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+
+	for _, ended := range runs {
+		if !ended {
+			rep.Move.UnfinishedCount++
+		}
+	}
+	if rep.RecordsCount > 0 {
+		rep.StartTime = time.UnixMilli(startMs).Format(time.DateTime)
+		rep.EndTime = time.UnixMilli(endMs).Format(time.DateTime)
+	}
+
+	rep.Infer.LocConf = summarize(locConf)
+	rep.Infer.RotConf = summarize(rotConf)
+	rep.Infer.CameraConf = summarize(cameraConf)
+	rep.Infer.LocTimeMs = summarize(locTimeMs)
+	rep.Infer.RotTimeMs = summarize(rotTimeMs)
+	rep.Infer.InferTimeMs = summarize(inferTimeMs)
+	for mode, values := range modeTimeMs {
+		rep.Infer.ModeTimeMs[mode] = summarize(values)
+	}
+	rep.Move.DurationS = summarize(durationS)
+	rep.Move.RotationDelta = summarize(rotDelta)
+	rep.Move.RotationSpeed = summarize(rotSpeed)
+	rep.Move.IdealRotationSpeed = summarize(idealSpeed)
+	return rep, nil
+}
+
+func summarize(values []float64) distribution {
+	if len(values) == 0 {
+		return distribution{}
+	}
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	percentile := func(p float64) float64 {
+		return sorted[min(len(sorted)-1, int(math.Ceil(p*float64(len(sorted))))-1)]
+	}
+	return distribution{
+		Count: len(sorted),
+		Mean:  sum / float64(len(sorted)),
+		P50:   percentile(0.50),
+		P90:   percentile(0.90),
+		P99:   percentile(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+func printReport(rep *report) {
+	fmt.Printf("Files: %d, records: %d, invalid lines: %d\n", len(rep.Files), rep.RecordsCount, rep.InvalidLines)
+	if rep.StartTime != "" {
+		fmt.Printf("Time: %s - %s\n", rep.StartTime, rep.EndTime)
+	}
+
+	printDist := func(name string, d distribution) {
+		if d.Count == 0 {
+			return
+		}
+		fmt.Printf("  %-22s n=%-6d mean=%-8.3f p50=%-8.3f p90=%-8.3f p99=%-8.3f max=%.3f\n",
+			name, d.Count, d.Mean, d.P50, d.P90, d.P99, d.Max)
+	}
+	printCounts := func(name string, counts map[string]int) {
+		if len(counts) == 0 {
+			return
+		}
+		keys := make([]string, 0, len(counts))
+		for k := range counts {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		fmt.Printf("  %s:\n", name)
+		for _, k := range keys {
+			fmt.Printf("    %-28s %d\n", k, counts[k])
+		}
+	}
+
+	in := &rep.Infer
+	fmt.Printf("Inferences: %d, hits: %d (%.1f%%), misses: %d\n",
+		in.Count, in.HitsCount, 100*float64(in.HitsCount)/float64(max(in.Count, 1)), in.MissesCount)
+	printCounts("Hit modes", in.HitModes)
+	printDist("Location confidence", in.LocConf)
+	printDist("Rotation confidence", in.RotConf)
+	printDist("Camera confidence", in.CameraConf)
+	printDist("Location time (ms)", in.LocTimeMs)
+	printDist("Rotation time (ms)", in.RotTimeMs)
+	printDist("Inference time (ms)", in.InferTimeMs)
+	modes := make([]string, 0, len(in.ModeTimeMs))
+	for mode := range in.ModeTimeMs {
+		modes = append(modes, mode)
+	}
+	slices.Sort(modes)
+	for _, mode := range modes {
+		printDist(mode+" (ms)", in.ModeTimeMs[mode])
+	}
+
+	mv := &rep.Move
+	fmt.Printf("Moves: %d, succeeded: %d, failed: %d, unfinished: %d\n",
+		mv.RunsCount, mv.SucceededCount, mv.FailedCount, mv.UnfinishedCount)
+	printCounts("Failure reasons", mv.FailureReasons)
+	printDist("Duration (s)", mv.DurationS)
+	printCounts("Mode changes", mv.ModeChanges)
+	fmt.Printf("  Rotation adjustments: %d\n", mv.RotationsCount)
+	printDist("Rotation delta (deg)", mv.RotationDelta)
+	printDist("Rotation speed", mv.RotationSpeed)
+	printDist("Ideal rotation speed", mv.IdealRotationSpeed)
+	fmt.Printf("  Stuck events: %d\n", mv.StuckCount)
+	printCounts("Stuck recoveries", mv.StuckRecoveries)
+	printCounts("Stuck give-ups", mv.StuckGiveUps)
+}
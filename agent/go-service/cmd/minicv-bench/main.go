@@ -0,0 +1,256 @@
+// Copyright (c) 2026 Harry Huang
+
+// Command minicv-bench times the template matching methods of minicv against each other,
+// on search areas from a whole map down to the neighbourhood searched while tracking,
+// and checks that every method agrees with ComputeNCC on the scores it reports.
+//
+// Usage:
+//
+//	go run ./cmd/minicv-bench [-format json|text] [-image <png>] [-tpl <size>] [-runs <n>] [-areas <sizes>]
+//
+// Without -image, a synthetic 1024x1024 image is used. The template is a square cropped from the image
+// with some noise added, like a mini-map matched against a map.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// result represents one method on one search area
+type result struct {
+	Area      string  `json:"area"`
+	Method    string  `json:"method"`
+	TimeMs    float64 `json:"timeMs"`             // Median over the runs
+	SpeedUp   float64 `json:"speedUp"`            // Reference time divided by this time
+	X         int     `json:"x"`                  // Top-left corner of the best match
+	Y         int     `json:"y"`                  // Top-left corner of the best match
+	Score     float64 `json:"score"`              // Score reported by the method
+	ScoreDiff float64 `json:"scoreDiff"`          // Difference from ComputeNCC at the same position
+	Error     int     `json:"error"`              // Chebyshev distance to the true position
+	Selected  string  `json:"selected,omitempty"` // Method picked by auto
+}
+
+// report represents the whole benchmark
+type report struct {
+	Image        string   `json:"image"`
+	ImageSize    [2]int   `json:"imageSize"`
+	TemplateSize int      `json:"templateSize"`
+	Truth        [2]int   `json:"truth"` // Top-left corner the template was cropped at
+	Runs         int      `json:"runs"`
+	Results      []result `json:"results"`
+	MaxScoreDiff float64  `json:"maxScoreDiff"`
+}
+
+var methods = []minicv.MatchMethod{
+	minicv.MatchReference,
+	minicv.MatchPacked,
+	minicv.MatchFFT,
+	minicv.MatchAuto,
+}
+
+func main() {
+	format := flag.String("format", "text", "output format, json or text")
+	imagePath := flag.String("image", "", "image to match on, e.g. a map of image/MapTracker/map (default: synthetic)")
+	tplSize := flag.Int("tpl", 80, "side length of the square template")
+	runs := flag.Int("runs", 5, "runs of each method on each area")
+	areas := flag.String("areas", "full,400,100,16", "comma-separated side lengths of the square search areas around the truth, or full")
+	seed := flag.Uint64("seed", 1, "random seed of the synthetic image, crop position and noise")
+	flag.Parse()
+
+	log.Logger = zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).Level(zerolog.WarnLevel).With().Timestamp().Logger()
+
+	if (*format != "json" && *format != "text") || *tplSize <= 0 || *runs <= 0 {
+		fmt.Fprintln(os.Stderr, "Usage: minicv-bench [-format json|text] [options]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	rng := rand.New(rand.NewPCG(*seed, *seed))
+	var img *image.RGBA
+	if *imagePath == "" {
+		img = syntheticImage(rng, 1024, 1024)
+	} else {
+		var err error
+		if img, err = loadImage(*imagePath); err != nil {
+			log.Fatal().Err(err).Str("path", *imagePath).Msg("Failed to load image")
+		}
+	}
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	if *tplSize > iw || *tplSize > ih {
+		log.Fatal().Int("tpl", *tplSize).Int("width", iw).Int("height", ih).Msg("Template larger than image")
+	}
+
+	tx, ty := rng.IntN(iw-*tplSize+1), rng.IntN(ih-*tplSize+1)
+	tpl := noisyCrop(rng, img, tx, ty, *tplSize)
+
+	rep := &report{
+		Image:        *imagePath,
+		ImageSize:    [2]int{iw, ih},
+		TemplateSize: *tplSize,
+		Truth:        [2]int{tx, ty},
+		Runs:         *runs,
+	}
+	if rep.Image == "" {
+		rep.Image = "synthetic"
+	}
+
+	imgIntArr := minicv.GetIntegralArray(img)
+	tplStats := minicv.GetImageStats(tpl)
+	for _, a := range strings.Split(*areas, ",") {
+		a = strings.TrimSpace(a)
+		ax, ay, aw, ah := 0, 0, iw, ih
+		if a != "full" {
+			size, err := strconv.Atoi(a)
+			if err != nil || size <= 0 {
+				log.Fatal().Str("area", a).Msg("Invalid area size")
+			}
+			// Center the area on the true center of the template
+			ax, ay, aw, ah = tx+*tplSize/2-size/2, ty+*tplSize/2-size/2, size, size
+		}
+
+		var refMs float64
+		for _, method := range methods {
+			// Warm up once, so that the runs exclude one-off allocations such as the FFT plans
+			x, y, s := minicv.MatchTemplateInAreaWith(method, img, imgIntArr, tpl, tplStats, ax, ay, aw, ah)
+			times := make([]float64, 0, *runs)
+			for range *runs {
+				runtime.GC()
+				start := time.Now()
+				x, y, s = minicv.MatchTemplateInAreaWith(method, img, imgIntArr, tpl, tplStats, ax, ay, aw, ah)
+				times = append(times, float64(time.Since(start).Microseconds())/1000)
+			}
+			slices.Sort(times)
+			r := result{
+				Area:      a,
+				Method:    method.String(),
+				TimeMs:    times[len(times)/2],
+				X:         x,
+				Y:         y,
+				Score:     s,
+				ScoreDiff: math.Abs(s - minicv.ComputeNCC(img, imgIntArr, tpl, tplStats, x, y)),
+				Error:     max(abs(x-tx), abs(y-ty)),
+			}
+			if method == minicv.MatchReference {
+				refMs = r.TimeMs
+			}
+			if method == minicv.MatchAuto {
+				r.Selected = minicv.ChooseMatchMethod(img, tpl, ax, ay, aw, ah).String()
+			}
+			r.SpeedUp = refMs / max(r.TimeMs, 1e-3)
+			rep.MaxScoreDiff = max(rep.MaxScoreDiff, r.ScoreDiff)
+			rep.Results = append(rep.Results, r)
+		}
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(rep, "", "  ")
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to marshal report")
+		}
+		fmt.Println(string(data))
+	} else {
+		printReport(rep)
+	}
+}
+
+// syntheticImage generates smooth noise with some detail, so that the best match is unique but not trivial
+func syntheticImage(rng *rand.Rand, w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	type wave struct{ fx, fy, phase, amp float64 }
+	var waves [3][]wave
+	for c := range waves {
+		for range 12 {
+			waves[c] = append(waves[c], wave{
+				fx:    (rng.Float64() - 0.5) * 0.2,
+				fy:    (rng.Float64() - 0.5) * 0.2,
+				phase: rng.Float64() * 2 * math.Pi,
+				amp:   rng.Float64() * 20,
+			})
+		}
+	}
+	for y := range h {
+		for x := range w {
+			off := y*img.Stride + x*4
+			for c := range waves {
+				v := 128.0
+				for _, wv := range waves[c] {
+					v += wv.amp * math.Sin(wv.fx*float64(x)+wv.fy*float64(y)+wv.phase)
+				}
+				v += rng.NormFloat64() * 8
+				img.Pix[off+c] = uint8(max(0, min(255, math.Round(v))))
+			}
+			img.Pix[off+3] = 255
+		}
+	}
+	return img
+}
+
+// noisyCrop crops a square of the image and adds gaussian noise to it
+func noisyCrop(rng *rand.Rand, img *image.RGBA, x, y, size int) *image.RGBA {
+	tpl := image.NewRGBA(image.Rect(0, 0, size, size))
+	for j := range size {
+		for i := range size {
+			src := (y+j)*img.Stride + (x+i)*4
+			dst := j*tpl.Stride + i*4
+			for c := range 3 {
+				v := float64(img.Pix[src+c]) + rng.NormFloat64()*10
+				tpl.Pix[dst+c] = uint8(max(0, min(255, math.Round(v))))
+			}
+			tpl.Pix[dst+3] = 255
+		}
+	}
+	return tpl
+}
+
+func loadImage(path string) (*image.RGBA, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	return minicv.ImageConvertRGBA(img), nil
+}
+
+func printReport(rep *report) {
+	fmt.Printf("Image: %s (%dx%d), template: %dx%d at (%d, %d), runs: %d\n",
+		rep.Image, rep.ImageSize[0], rep.ImageSize[1], rep.TemplateSize, rep.TemplateSize, rep.Truth[0], rep.Truth[1], rep.Runs)
+	fmt.Printf("%-6s %-10s %10s %8s %12s %8s %10s %6s\n", "area", "method", "time(ms)", "speedup", "best", "score", "diff", "error")
+	for _, r := range rep.Results {
+		method := r.Method
+		if r.Selected != "" {
+			method += "=" + r.Selected
+		}
+		fmt.Printf("%-6s %-10s %10.2f %7.1fx %12s %8.4f %10.2e %6d\n",
+			r.Area, method, r.TimeMs, r.SpeedUp, fmt.Sprintf("(%d,%d)", r.X, r.Y), r.Score, r.ScoreDiff, r.Error)
+	}
+	fmt.Printf("Max score difference from ComputeNCC: %.2e\n", rep.MaxScoreDiff)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MapTrackerAnchor represents a teleport anchor and where the player lands after teleporting to it
+type MapTrackerAnchor struct {
+	// Task is the SceneManager node that teleports to the anchor, e.g. "SceneEnterWorldWulingJingyuValley10".
+	Task string `json:"task"`
+	// Pos is the [x, y] coordinate the player lands on, on the map the anchor is listed under.
+	Pos [2]int `json:"pos"`
+}
+
+var (
+	anchorStoreMu  sync.Mutex
+	anchorStoreGen uint64 // Resource generation the store is loaded from
+	anchorStore    map[string][]MapTrackerAnchor
+	anchorStoreErr error
+)
+
+// getAnchorStore returns the teleport anchors indexed by map name (thread-safe, reloads after the resource is reloaded)
+func getAnchorStore() (map[string][]MapTrackerAnchor, error) {
+	anchorStoreMu.Lock()
+	defer anchorStoreMu.Unlock()
+
+	gen := resourceGeneration.Load()
+	if anchorStoreGen == gen && (anchorStore != nil || anchorStoreErr != nil) {
+		return anchorStore, anchorStoreErr
+	}
+	anchorStoreGen = gen
+	anchorStore, anchorStoreErr = loadAnchors()
+	if anchorStoreErr != nil {
+		log.Error().Err(anchorStoreErr).Msg("Failed to load anchors")
+	} else {
+		count := 0
+		for _, anchors := range anchorStore {
+			count += len(anchors)
+		}
+		log.Info().Int("anchorsCount", count).Msg("Anchors loaded")
+	}
+	return anchorStore, anchorStoreErr
+}
+
+// loadAnchors loads the anchor file, which maps map names to the teleport anchors landing on them
+func loadAnchors() (map[string][]MapTrackerAnchor, error) {
+	anchors := make(map[string][]MapTrackerAnchor)
+
+	path := findResource(ANCHOR_FILE)
+	if path == "" {
+		log.Debug().Msg("Anchor file not found, anchor store is empty")
+		return anchors, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &anchors); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal anchor file: %w", err)
+	}
+	for mapName, list := range anchors {
+		for i, a := range list {
+			if a.Task == "" {
+				return nil, fmt.Errorf("task is required for anchor at index %d of map %q", i, mapName)
+			}
+		}
+	}
+	return anchors, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/MaaXYZ/maa-framework-go/v4"
@@ -13,12 +14,6 @@ import (
 
 type MapTrackerAssertLocation struct{}
 
-// LocationCondition represents a single condition to check
-type LocationCondition struct {
-	MapName string `json:"map_name"`
-	Target  [4]int `json:"target"` // [x, y, w, h]
-}
-
 // MapTrackerAssertLocationParam represents the parameters for AssertLocation
 type MapTrackerAssertLocationParam struct {
 	// Expected is a list of conditions to check, using OR logic.
@@ -31,6 +26,14 @@ type MapTrackerAssertLocationParam struct {
 	FastMode bool `json:"fast_mode,omitempty"`
 }
 
+// MapTrackerAssertLocationResult represents the detail of a satisfied location assertion
+type MapTrackerAssertLocationResult struct {
+	MapTrackerInferResult
+	ExpectedIndex int      `json:"expectedIndex"`    // Index of the satisfied expected condition
+	Region        string   `json:"region,omitempty"` // Name of the region that satisfied the condition
+	Regions       []string `json:"regions"`          // Names of all regions containing the location
+}
+
 var _ maa.CustomRecognitionRunner = &MapTrackerAssertLocation{}
 
 // Run implements maa.CustomRecognitionRunner
@@ -42,23 +45,37 @@ func (r *MapTrackerAssertLocation) Run(ctx *maa.Context, arg *maa.CustomRecognit
 		return nil, false
 	}
 
+	regions, err := getRegionStore()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get region store")
+		return nil, false
+	}
+
 	mapNameRegex := ".*"
 	if param.FastMode {
 		// Build map_name_regex based on expected conditions to focus the search
 		mapNamesMap := make(map[string]struct{})
-		var mapNames []string
-		for _, condition := range param.Expected {
-			if _, exists := mapNamesMap[condition.MapName]; !exists {
-				mapNamesMap[condition.MapName] = struct{}{}
-				mapNames = append(mapNames, regexp.QuoteMeta(condition.MapName))
+		bounded := true
+		for i := range param.Expected {
+			if !param.Expected[i].collectMapNames(regions, mapNamesMap) {
+				bounded = false
+				break
 			}
 		}
-		if len(mapNames) == 0 {
-			log.Error().Msg("Failed to extract map names from expected conditions")
-			return nil, false
+		if bounded {
+			if len(mapNamesMap) == 0 {
+				log.Error().Msg("Failed to extract map names from expected conditions")
+				return nil, false
+			}
+			mapNames := make([]string, 0, len(mapNamesMap))
+			for name := range mapNamesMap {
+				mapNames = append(mapNames, regexp.QuoteMeta(name))
+			}
+			sort.Strings(mapNames)
+			mapNameRegex = "^(" + strings.Join(mapNames, "|") + ")$"
+		} else {
+			log.Debug().Msg("Expected conditions contain not, fast mode searches all maps")
 		}
-
-		mapNameRegex = "^(" + strings.Join(mapNames, "|") + ")$"
 	}
 
 	// Prepare and run MapTrackerInfer
@@ -103,19 +120,28 @@ func (r *MapTrackerAssertLocation) Run(ctx *maa.Context, arg *maa.CustomRecognit
 	}
 
 	// Check if current location satisfies any of the expected conditions
-	for _, condition := range param.Expected {
-		if result.MapName == condition.MapName {
-			x, y, w, h := condition.Target[0], condition.Target[1], condition.Target[2], condition.Target[3]
-			if result.X >= x && result.X < x+w && result.Y >= y && result.Y < y+h {
-				log.Info().
-					Interface("expected", condition).
-					Msg("Location assertion satisfied")
-
-				return &maa.CustomRecognitionResult{
-					Box:    arg.Roi,
-					Detail: res.DetailJson,
-				}, true
+	for i := range param.Expected {
+		condition := &param.Expected[i]
+		if ok, region := condition.match(result.MapName, result.X, result.Y, regions); ok {
+			log.Info().
+				Interface("expected", condition).
+				Str("region", region).
+				Msg("Location assertion satisfied")
+
+			detail, err := json.Marshal(MapTrackerAssertLocationResult{
+				MapTrackerInferResult: result,
+				ExpectedIndex:         i,
+				Region:                region,
+				Regions:               regionsContaining(result.MapName, result.X, result.Y, regions),
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to marshal location assertion result")
+				return nil, false
 			}
+			return &maa.CustomRecognitionResult{
+				Box:    arg.Roi,
+				Detail: string(detail),
+			}, true
 		}
 	}
 
@@ -134,15 +160,13 @@ func (r *MapTrackerAssertLocation) parseParam(paramStr string) (*MapTrackerAsser
 	if len(param.Expected) == 0 {
 		return nil, fmt.Errorf("expected conditions must be provided")
 	}
-	for i, condition := range param.Expected {
-		if condition.MapName == "" {
-			return nil, fmt.Errorf("map_name must be provided for expected condition at index %d", i)
-		}
-		if len(condition.Target) != 4 {
-			return nil, fmt.Errorf("target must have 4 integers [x, y, w, h] for expected condition at index %d", i)
-		}
-		if condition.Target[2] <= 0 || condition.Target[3] <= 0 {
-			return nil, fmt.Errorf("width and height in target must be positive for expected condition at index %d", i)
+	regions, err := getRegionStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get region store: %w", err)
+	}
+	for i := range param.Expected {
+		if err := param.Expected[i].validate(regions); err != nil {
+			return nil, fmt.Errorf("invalid expected condition at index %d: %w", i, err)
 		}
 	}
 	// Precision and Threshold will be validated in MapTrackerInfer, omitted here
@@ -0,0 +1,281 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/camerasens"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// MinimapGeometry represents where the mini-map and the pointer are on the screen image
+type MinimapGeometry struct {
+	LocCenterX int     `json:"loc_center_x"` // Mini-map crop center X
+	LocCenterY int     `json:"loc_center_y"` // Mini-map crop center Y
+	LocRadius  int     `json:"loc_radius"`   // Mini-map crop radius
+	RotCenterX int     `json:"rot_center_x"` // Pointer crop center X
+	RotCenterY int     `json:"rot_center_y"` // Pointer crop center Y
+	RotRadius  int     `json:"rot_radius"`   // Pointer crop radius
+	Scale      float64 `json:"scale"`        // HUD scale relative to the default
+}
+
+// MapTrackerCalibrate is the custom action component that calibrates the mini-map geometry
+type MapTrackerCalibrate struct{}
+
+// MapTrackerCalibrateParam represents the custom_action_param for MapTrackerCalibrate
+type MapTrackerCalibrateParam struct {
+	// Reset discards the calibration of the current controller instead of calibrating,
+	// so that the next inference calibrates automatically.
+	Reset bool `json:"reset,omitempty"`
+	// NoPrint controls whether to suppress printing the calibration result to the GUI.
+	NoPrint bool `json:"no_print,omitempty"`
+}
+
+//go:embed messages/calibration_finished.html
+var calibrationFinishedHTML string
+
+var (
+	calibrationsOnce    sync.Once
+	calibrations        map[string]MinimapGeometry
+	sessionCalibrations map[string]MinimapGeometry // Automatic calibrations not confident enough to be saved
+	calibrationTries    map[string]int64           // Last automatic calibration attempt time of uncalibrated keys
+	calibrationsMu      sync.Mutex
+)
+
+// loadCalibrations loads the calibration file, the caller must hold calibrationsMu
+func loadCalibrations() {
+	calibrationsOnce.Do(func() {
+		calibrations = make(map[string]MinimapGeometry)
+		sessionCalibrations = make(map[string]MinimapGeometry)
+		calibrationTries = make(map[string]int64)
+		data, err := os.ReadFile(CALIBRATION_FILE)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Warn().Err(err).Msg("Failed to read calibration file")
+			}
+			return
+		}
+		if err := json.Unmarshal(data, &calibrations); err != nil {
+			log.Warn().Err(err).Msg("Failed to unmarshal calibration file, ignoring")
+			calibrations = make(map[string]MinimapGeometry)
+			return
+		}
+		log.Info().Int("calibrationsCount", len(calibrations)).Msg("Mini-map calibrations loaded")
+	})
+}
+
+// saveCalibrations writes all calibrations to the calibration file, the caller must hold calibrationsMu
+func saveCalibrations() error {
+	data, err := json.MarshalIndent(calibrations, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(CALIBRATION_FILE), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(CALIBRATION_FILE, data, 0644)
+}
+
+// setCalibration stores the geometry of the key, or discards it if geo is nil
+func setCalibration(key string, geo *MinimapGeometry) error {
+	calibrationsMu.Lock()
+	defer calibrationsMu.Unlock()
+	loadCalibrations()
+
+	if geo == nil {
+		delete(calibrations, key)
+	} else {
+		calibrations[key] = *geo
+	}
+	delete(sessionCalibrations, key)
+	delete(calibrationTries, key)
+	return saveCalibrations()
+}
+
+// setSessionCalibration stores the geometry of the key until the agent exits, without saving it
+func setSessionCalibration(key string, geo *MinimapGeometry) {
+	calibrationsMu.Lock()
+	defer calibrationsMu.Unlock()
+	loadCalibrations()
+
+	sessionCalibrations[key] = *geo
+	delete(calibrationTries, key)
+}
+
+// getMinimapGeometry returns the calibrated geometry of the controller.
+// If the controller is not calibrated yet, it calibrates on screenImg (at most once per retry interval),
+// and falls back to the default geometry on failure. Only confident results are saved,
+// the others are kept for this session, so that a poor detection is never stored permanently.
+func (i *MapTrackerInfer) getMinimapGeometry(ctrl *maa.Controller, screenImg *image.RGBA) *MinimapGeometry {
+	key, err := camerasens.Key(ctrl)
+	if err != nil {
+		log.Debug().Err(err).Msg("Mini-map calibration unavailable, using default geometry")
+		return &DEFAULT_MINIMAP_GEOMETRY
+	}
+
+	calibrationsMu.Lock()
+	loadCalibrations()
+	if geo, ok := calibrations[key]; ok {
+		calibrationsMu.Unlock()
+		return &geo
+	}
+	if geo, ok := sessionCalibrations[key]; ok {
+		calibrationsMu.Unlock()
+		return &geo
+	}
+	nowMs := time.Now().UnixMilli()
+	if nowMs-calibrationTries[key] < CALIBRATION_RETRY_INTERVAL_MS {
+		calibrationsMu.Unlock()
+		return &DEFAULT_MINIMAP_GEOMETRY
+	}
+	calibrationTries[key] = nowMs
+	calibrationsMu.Unlock()
+
+	geo, conf, err := i.calibrate(screenImg)
+	if err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Automatic mini-map calibration failed, using default geometry")
+		return &DEFAULT_MINIMAP_GEOMETRY
+	}
+	if *geo == DEFAULT_MINIMAP_GEOMETRY || conf < CALIBRATION_SAVE_THRESHOLD {
+		// The default needs no calibration, and a weak match may be wrong, so neither is saved
+		setSessionCalibration(key, geo)
+		log.Info().Str("key", key).Interface("geometry", geo).Float64("pointerConf", conf).
+			Msg("Mini-map calibrated automatically for this session")
+		return geo
+	}
+	if err := setCalibration(key, geo); err != nil {
+		log.Warn().Err(err).Msg("Failed to save calibration file")
+	}
+	log.Info().Str("key", key).Interface("geometry", geo).Float64("pointerConf", conf).
+		Msg("Mini-map calibrated automatically")
+	return geo
+}
+
+// calibrate finds the mini-map on the screen image. It accepts the default geometry if the pointer
+// matches confidently there, otherwise detects the mini-map circle in the top-left area of the screen
+// and verifies it by the pointer at its center. A circle close to the default is snapped to the default geometry.
+// Returns the geometry scaled by the detected HUD scale, and the pointer confidence it was verified with.
+func (i *MapTrackerInfer) calibrate(screenImg *image.RGBA) (*MinimapGeometry, float64, error) {
+	if i.getPointer() == nil {
+		return nil, 0, fmt.Errorf("pointer template is not loaded")
+	}
+
+	def := DEFAULT_MINIMAP_GEOMETRY
+	if rot := i.inferRotation(screenImg, &def, 4); rot != nil && rot.conf >= CALIBRATION_SAVE_THRESHOLD {
+		log.Debug().Float64("pointerConf", rot.conf).Msg("Default mini-map geometry matched")
+		return &def, rot.conf, nil
+	}
+
+	b := screenImg.Rect
+	area := screenImg.SubImage(image.Rect(b.Min.X, b.Min.Y, b.Min.X+b.Dx()/3, b.Min.Y+b.Dy()/2)).(*image.RGBA)
+	minR := int(math.Floor(MINIMAP_CIRCLE_RADIUS * CALIBRATION_MIN_SCALE))
+	maxR := int(math.Ceil(MINIMAP_CIRCLE_RADIUS * CALIBRATION_MAX_SCALE))
+	circles := minicv.HoughCircles(area, minR, maxR, CALIBRATION_CANDIDATES_COUNT, minR)
+
+	for _, c := range circles {
+		if c.Score < CALIBRATION_CIRCLE_THRESHOLD {
+			break
+		}
+		geo := geometryFromCircle(c)
+		rot := i.inferRotation(screenImg, geo, 4)
+		if rot == nil {
+			continue
+		}
+		log.Debug().Int("x", c.X).Int("y", c.Y).Int("r", c.R).
+			Float64("circleScore", c.Score).Float64("pointerConf", rot.conf).
+			Msg("Mini-map circle candidate")
+		if rot.conf >= CALIBRATION_POINTER_THRESHOLD {
+			return geo, rot.conf, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("mini-map not found (%d circle candidates)", len(circles))
+}
+
+// geometryFromCircle returns the geometry of a detected mini-map circle,
+// or the default geometry if the circle is within tolerance of the default one
+func geometryFromCircle(c minicv.CircleCandidate) *MinimapGeometry {
+	scale := float64(c.R) / MINIMAP_CIRCLE_RADIUS
+	def := DEFAULT_MINIMAP_GEOMETRY
+	if math.Abs(scale-def.Scale) <= CALIBRATION_SNAP_SCALE &&
+		math.Hypot(float64(c.X-def.LocCenterX), float64(c.Y-def.LocCenterY)) <= CALIBRATION_SNAP_DISTANCE {
+		return &def
+	}
+	return &MinimapGeometry{
+		LocCenterX: c.X,
+		LocCenterY: c.Y,
+		LocRadius:  int(math.Round(LOC_RADIUS * scale)),
+		RotCenterX: c.X,
+		RotCenterY: c.Y,
+		RotRadius:  int(math.Round(ROT_RADIUS * scale)),
+		Scale:      scale,
+	}
+}
+
+var _ maa.CustomActionRunner = &MapTrackerCalibrate{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerCalibrate) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	var param MapTrackerCalibrateParam
+	if arg.CustomActionParam != "" {
+		if err := json.Unmarshal([]byte(arg.CustomActionParam), &param); err != nil {
+			log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerCalibrate")
+			return false
+		}
+	}
+
+	ctrl := ctx.GetTasker().GetController()
+	key, err := camerasens.Key(ctrl)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get calibration key")
+		return false
+	}
+
+	if param.Reset {
+		if err := setCalibration(key, nil); err != nil {
+			log.Error().Err(err).Msg("Failed to save calibration file")
+			return false
+		}
+		log.Info().Str("key", key).Msg("Mini-map calibration reset")
+		return true
+	}
+
+	infer := mapTrackerInferRunner.(*MapTrackerInfer)
+	if err := infer.initPointer(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize pointer")
+		return false
+	}
+
+	ctrl.PostScreencap().Wait()
+	img, err := ctrl.CacheImage()
+	if err != nil || img == nil {
+		log.Error().Err(err).Msg("Failed to get cached image")
+		return false
+	}
+
+	geo, _, err := infer.calibrate(minicv.ImageConvertRGBA(img))
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Mini-map calibration failed")
+		return false
+	}
+	if err := setCalibration(key, geo); err != nil {
+		log.Error().Err(err).Msg("Failed to save calibration file")
+		return false
+	}
+
+	log.Info().Str("key", key).Interface("geometry", geo).Msg("Mini-map calibrated")
+	if !param.NoPrint {
+		maafocus.NodeActionStarting(ctx, fmt.Sprintf(calibrationFinishedHTML, geo.LocCenterX, geo.LocCenterY, geo.Scale*100))
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"testing"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+)
+
+func TestGeometryFromCircle(t *testing.T) {
+	def := DEFAULT_MINIMAP_GEOMETRY
+	tests := []struct {
+		name   string
+		circle minicv.CircleCandidate
+		want   MinimapGeometry
+	}{
+		{
+			name:   "exactly the default",
+			circle: minicv.CircleCandidate{X: LOC_CENTER_X, Y: LOC_CENTER_Y, R: MINIMAP_CIRCLE_RADIUS},
+			want:   def,
+		},
+		{
+			name:   "snapped to the default",
+			circle: minicv.CircleCandidate{X: LOC_CENTER_X + 2, Y: LOC_CENTER_Y - 2, R: MINIMAP_CIRCLE_RADIUS + 2},
+			want:   def,
+		},
+		{
+			name:   "center too far to snap",
+			circle: minicv.CircleCandidate{X: LOC_CENTER_X + 5, Y: LOC_CENTER_Y, R: MINIMAP_CIRCLE_RADIUS},
+			want: MinimapGeometry{
+				LocCenterX: LOC_CENTER_X + 5, LocCenterY: LOC_CENTER_Y, LocRadius: LOC_RADIUS,
+				RotCenterX: LOC_CENTER_X + 5, RotCenterY: LOC_CENTER_Y, RotRadius: ROT_RADIUS,
+				Scale: 1.0,
+			},
+		},
+		{
+			name:   "larger HUD scale",
+			circle: minicv.CircleCandidate{X: 150, Y: 160, R: 88},
+			want: MinimapGeometry{
+				LocCenterX: 150, LocCenterY: 160, LocRadius: 60,
+				RotCenterX: 150, RotCenterY: 160, RotRadius: 18,
+				Scale: 88.0 / 59,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := geometryFromCircle(tt.circle)
+			if *got != tt.want {
+				t.Errorf("geometryFromCircle(%+v) = %+v, want %+v", tt.circle, *got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 // Copyright (c) 2026 Harry Huang
 package maptracker
 
+import "regexp"
+
 const (
 	WORK_W = 1280
 	WORK_H = 720
@@ -22,6 +24,42 @@ const (
 	ROT_RADIUS   = 12
 )
 
+// Camera heading inference configuration
+const (
+	CAMERA_CONE_BIN_DEG     = 4   // Angular bin size of the brightness profile (degrees)
+	CAMERA_CONE_WIDTH_DEG   = 88  // Approximate opening angle of the view cone, a multiple of the bin size (degrees)
+	CAMERA_CONE_INNER_RATIO = 1.5 // Inner radius of the sampled annulus relative to the pointer crop radius
+	CAMERA_CONE_RINGS       = 5   // Rings the sampled annulus is divided into
+)
+
+// Mini-map calibration configuration
+const (
+	MINIMAP_CIRCLE_RADIUS         = 59    // Mini-map circle radius at the default HUD scale (px)
+	CALIBRATION_MIN_SCALE         = 0.6   // Min HUD scale to search for
+	CALIBRATION_MAX_SCALE         = 1.6   // Max HUD scale to search for
+	CALIBRATION_CIRCLE_THRESHOLD  = 0.4   // Min fraction of the mini-map circle supported by edges
+	CALIBRATION_POINTER_THRESHOLD = 0.5   // Min pointer confidence at the circle center
+	CALIBRATION_SAVE_THRESHOLD    = 0.75  // Min pointer confidence to save an automatic calibration, or to accept the default geometry
+	CALIBRATION_SNAP_SCALE        = 0.05  // Max HUD scale difference from the default to snap to the default geometry
+	CALIBRATION_SNAP_DISTANCE     = 4     // Max mini-map center distance from the default to snap to the default geometry (px)
+	CALIBRATION_CANDIDATES_COUNT  = 4     // Circles to verify with the pointer
+	CALIBRATION_RETRY_INTERVAL_MS = 10000 // Min interval between automatic calibration attempts
+
+	CALIBRATION_FILE = "debug/map_tracker_calibration.json" // Relative to the working directory
+)
+
+// Camera rotation sensitivity calibration configuration
+const (
+	ROTATION_CALIBRATION_SWIPE_MS  = 75  // Duration of each swipe, the same as during movement
+	ROTATION_CALIBRATION_SETTLE_MS = 300 // Wait after each swipe before measuring
+	ROTATION_CALIBRATION_FRAMES    = 3   // Screenshots averaged for each heading measurement
+	ROTATION_CALIBRATION_MIN_CONF  = 0.3 // Min camera heading confidence of a screenshot
+	ROTATION_CALIBRATION_MAX_RMS   = 6.0 // Max RMS fitting error to accept (degrees)
+)
+
+// Default swipe distances of rotation calibration, in ascending order so that large turns can be unwrapped
+var ROTATION_CALIBRATION_SWIPES = []int{15, 30, 60, 120, 240}
+
 // Time-series empirical optimization configuration
 const (
 	PENDING_TAKEOVER_TIME_MS         = 1000
@@ -30,12 +68,61 @@ const (
 	CONVINCED_VALID_TIME_MS          = 2000
 )
 
+// Kalman tracking filter configuration
+const (
+	KALMAN_ACCEL_STD         = 10.0 // Process noise as acceleration std (px/s^2)
+	KALMAN_INIT_SPEED_STD    = 8.0  // Initial velocity std (px/s)
+	KALMAN_MEASUREMENT_STD   = 1.5  // Location measurement std at full confidence (px)
+	KALMAN_HEADING_SPEED_STD = 2.0  // Velocity pseudo-measurement std from heading (px/s)
+	KALMAN_HEADING_MIN_SPEED = 0.5  // Min estimated speed to trust heading as moving direction (px/s)
+	KALMAN_GATE_CHI2         = 13.8 // Mahalanobis gate for outliers (chi-square, 2 dof, 99.9%)
+	KALMAN_REINIT_COUNT      = 3    // Consecutive consistent outliers to reinitialize the filter
+	KALMAN_MAX_POS_STD       = 30.0 // Max position std to keep producing virtual hits (px)
+)
+
+// Pyramid search configuration
+const (
+	PYRAMID_MIN_TEMPLATE_SIZE = 8  // Min mini-map size on the coarse level (px)
+	PYRAMID_MIN_TOP_K         = 4  // Candidates to refine at the lowest precision
+	PYRAMID_MAX_TOP_K         = 16 // Candidates to refine at the highest precision
+	PYRAMID_NMS_DISTANCE      = 2  // Min distance between candidates on the same coarse map (px)
+)
+
+// Map region hint configuration
+var (
+	// SCENE_REGION_NODE_REGEX matches the SceneManager nodes that indicate the region the player is in
+	SCENE_REGION_NODE_REGEX = regexp.MustCompile(`(?:^InMap|EnterWorld|EnterMap)(ValleyIV|Wuling|Dijiang)`)
+	// SCENE_REGION_MAP_PREFIXES maps the SceneManager region names to the map name prefixes
+	SCENE_REGION_MAP_PREFIXES = map[string]string{
+		"ValleyIV": "map01",
+		"Wuling":   "map02",
+		"Dijiang":  "base01",
+	}
+)
+
+const (
+	REGION_HINT_MIN_CONF = 0.7 // Min confidence to accept a match in the candidate region without searching other maps
+)
+
 // Resource paths
 const (
 	MAP_DIR      = "image/MapTracker/map"
+	ROUTE_DIR    = "image/MapTracker/route"
+	WALKABLE_DIR = "image/MapTracker/walkable"
+	REGION_FILE  = "image/MapTracker/map/regions.json"
+	ANCHOR_FILE  = "image/MapTracker/anchors.json"
 	POINTER_PATH = "image/MapTracker/pointer.png"
 )
 
+// Map data cache configuration
+const (
+	MAP_CACHE_DIR     = "debug/map_tracker_cache" // Relative to the working directory
+	MAP_CACHE_VERSION = 1                         // Bump when the cache file layout or preprocessing changes
+
+	MAP_CACHE_DEFAULT_BUDGET_MB = 256                          // Memory budget of loaded map variants, see SetMapCacheBudget
+	MAP_CACHE_BUDGET_ENV        = "MAAEND_MAP_CACHE_BUDGET_MB" // Environment variable overriding the memory budget (MB)
+)
+
 // Move action configuration
 const (
 	INFER_INTERVAL_MS      = 100
@@ -44,18 +131,66 @@ const (
 	ROTATION_MIN_SPEED     = 1.0
 )
 
+// Navigate action configuration
+const (
+	NAV_SNAP_RADIUS         = 15   // Max distance to snap an unwalkable start/goal onto the mask
+	NAV_PREFERRED_CLEARANCE = 4.0  // Preferred distance to obstacles in planned paths
+	NAV_CLEARANCE_PENALTY   = 2.0  // Extra cost factor for cells closer to obstacles than preferred
+	NAV_MAX_SEGMENT_LENGTH  = 60.0 // Max distance between two planned waypoints
+)
+
+// GoTo action configuration
+const (
+	GOTO_DEFAULT_TELEPORT_COST = 150.0 // Walking distance a teleport is considered to take, about 12s of sprinting
+)
+
+// Record action configuration
+const (
+	RECORD_OUTPUT_DIR = "debug/map_tracker_routes"       // Relative to the working directory
+	USER_ROUTE_DIR    = "debug/map_tracker_routes/store" // Routes recorded into the route store, relative to the working directory
+)
+
+// Telemetry configuration
+const (
+	TELEMETRY_DIR = "debug/map_tracker_telemetry" // Relative to the working directory
+)
+
+// Debug image configuration
+const (
+	DEBUG_IMAGE_DIR              = "debug/map_tracker_images" // Relative to the working directory
+	DEBUG_IMAGE_MAP_SCALE        = 2.0                        // Scale of the map in route panels
+	DEBUG_IMAGE_MAP_MARGIN       = 40                         // Margin around the route in route panels (px on the map)
+	DEBUG_IMAGE_MINIMAP_SIZE     = 240                        // Size of the mini-map panel (px)
+	DEBUG_IMAGE_HEADING_INTERVAL = 5                          // Trajectory samples between two drawn headings
+)
+
+// Mini-map geometry at 1280x720 and the default HUD scale
+var DEFAULT_MINIMAP_GEOMETRY = MinimapGeometry{
+	LocCenterX: LOC_CENTER_X,
+	LocCenterY: LOC_CENTER_Y,
+	LocRadius:  LOC_RADIUS,
+	RotCenterX: ROT_CENTER_X,
+	RotCenterY: ROT_CENTER_Y,
+	RotRadius:  ROT_RADIUS,
+	Scale:      1.0,
+}
+
 // MapTrackerInfer parameters default values
 var DEFAULT_INFERENCE_PARAM = MapTrackerInferParam{
 	MapNameRegex: "^map\\d+_lv\\d+$",
 	Precision:    0.5,
 	Threshold:    0.4,
+	Filter:       FILTER_HEURISTIC,
+	SearchMode:   SEARCH_MODE_EXHAUSTIVE,
 }
 
 // MapTrackerInfer parameters for MapTrackerMove action default values
 // (MapNameRegex is omitted here since MapTrackerMove always sets it)
 var DEFAULT_INFERENCE_PARAM_FOR_MOVE = MapTrackerInferParam{
-	Precision: 0.7,
-	Threshold: 0.3,
+	Precision:  0.7,
+	Threshold:  0.3,
+	Filter:     FILTER_HEURISTIC,
+	SearchMode: SEARCH_MODE_EXHAUSTIVE,
 }
 
 // MapTrackerMove parameters default values
@@ -66,9 +201,41 @@ var DEFAULT_MOVING_PARAM = MapTrackerMoveParam{
 	RotationUpperThreshold: 60.0,
 	SprintThreshold:        20.0,
 	StuckThreshold:         2000,
-	StuckTimeout:           10000,
+	StuckTimeout:           10000, // Raised to cover the stuck_recovery chain, see stuckChainTimeout
+	StuckRecovery: []StuckRecoveryType{
+		STUCK_RECOVERY_JUMP,
+		STUCK_RECOVERY_STRAFE_LEFT,
+		STUCK_RECOVERY_STRAFE_RIGHT,
+		STUCK_RECOVERY_BACK_OFF,
+		STUCK_RECOVERY_REPLAN,
+		STUCK_RECOVERY_RESET_CAMERA,
+	},
+	Steering: STEERING_WAYPOINT,
+	Backend:  BACKEND_AUTO,
 }
 
+// MapTrackerMove segment transition default values
+const (
+	TRANSITION_DEFAULT_TIMEOUT_MS = 15000
+)
+
+// MapTrackerMove stuck recovery related values
+const (
+	STUCK_STRAFE_DURATION_MS   = 600
+	STUCK_BACK_OFF_DURATION_MS = 800
+)
+
+// MapTrackerMove pure-pursuit steering related values
+const (
+	PURSUIT_LOOKAHEAD_MIN  = 4.0 // Lookahead distance when standing still (px)
+	PURSUIT_LOOKAHEAD_TIME = 1.0 // Lookahead time at the current movement speed (s)
+)
+
+// MapTrackerMove waypoint action related values
+const (
+	WAYPOINT_FACE_MAX_ATTEMPTS = 5
+)
+
 // Win32 action related codes
 const (
 	KEY_W     = 0x57
@@ -79,4 +246,52 @@ const (
 	KEY_CTRL  = 0x11
 	KEY_ALT   = 0x12
 	KEY_SPACE = 0x20
+	KEY_F     = 0x46
+	KEY_F8    = 0x77
+)
+
+// Touch movement controls in the working resolution, for controllers without keyboard such as ADB
+const (
+	TOUCH_JOYSTICK_X      = 200 // Center of the on-screen joystick
+	TOUCH_JOYSTICK_Y      = 560
+	TOUCH_JOYSTICK_RADIUS = 100 // Joystick push distance to run, half of it to walk
+	TOUCH_STEP_MIN_MS     = 100 // Min duration of a joystick push for a step
+
+	TOUCH_CAMERA_X         = 880 // Start of camera rotation swipes, in an empty area of the right half
+	TOUCH_CAMERA_Y         = 300
+	TOUCH_CAMERA_MAX_SWIPE = 320 // Max distance of one camera rotation swipe, longer ones are split
+	TOUCH_CAMERA_SCALE     = 1.0 // Swipe distance per camera rotation pixel of the mouse
+
+	TOUCH_CAMERA_MOVE_INTERVAL_MS = 16 // Interval between the touch moves of one camera rotation swipe
+
+	TOUCH_SPRINT_X   = 1180 // Sprint button
+	TOUCH_SPRINT_Y   = 520
+	TOUCH_JUMP_X     = 1100 // Jump button
+	TOUCH_JUMP_Y     = 630
+	TOUCH_INTERACT_X = 860 // Interact button shown next to interactable objects
+	TOUCH_INTERACT_Y = 420
+
+	TOUCH_CONTACT_JOYSTICK = 0 // Touch contacts used at the same time
+	TOUCH_CONTACT_CAMERA   = 1
+	TOUCH_CONTACT_BUTTON   = 2
 )
+
+// MapTrackerGeofence related values
+const (
+	GEOFENCE_DEFAULT_INTERVAL_MS = 1000
+	GEOFENCE_SESSION             = "_geofence" // Tracking session used by background sampling
+	GEOFENCE_MAX_PENDING_EVENTS  = 64          // Max events queued until polled, the oldest are dropped beyond
+)
+
+// Route linter configuration
+const (
+	LINT_MAX_POINT_DISTANCE = 60.0 // Max distance between consecutive path points before warning (px)
+)
+
+// MapTrackerRecord parameters default values
+var DEFAULT_RECORD_PARAM = MapTrackerRecordParam{
+	MapNameRegex:   "^map\\d+_lv\\d+(_tier_\\d+)?$",
+	SampleDistance: 3.0,
+	Tolerance:      1.5,
+	StopKey:        KEY_F8,
+}
@@ -0,0 +1,309 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	debugColorBackground = color.RGBA{32, 32, 32, 255}
+	debugColorText       = color.RGBA{235, 235, 235, 255}
+	debugColorRoute      = color.RGBA{0, 140, 255, 255}
+	debugColorTrajectory = color.RGBA{40, 220, 90, 255}
+	debugColorHeading    = color.RGBA{255, 170, 0, 255}
+	debugColorCamera     = color.RGBA{0, 230, 230, 255}
+	debugColorStuck      = color.RGBA{255, 40, 40, 255}
+	debugColorWindow     = color.RGBA{255, 0, 220, 255}
+)
+
+// moveDebugSample represents one tracked location of the player during movement
+type moveDebugSample struct {
+	x, y int
+	rot  float64
+}
+
+// moveDebugSegment collects the planned route and what actually happened on one segment
+type moveDebugSegment struct {
+	mapName    string
+	path       []MapTrackerPoint
+	trajectory []moveDebugSample
+	stuck      []moveDebugSample
+}
+
+// moveDebugRecorder collects one MapTrackerMove run and renders it into one stitched image
+type moveDebugRecorder struct {
+	entry      string
+	startTime  time.Time
+	segments   []moveDebugSegment
+	current    int
+	lastResult *MapTrackerInferResult
+}
+
+// newMoveDebugRecorder creates a recorder for the planned segments
+func newMoveDebugRecorder(ctx *maa.Context, segments []MapTrackerMoveSegment) *moveDebugRecorder {
+	r := &moveDebugRecorder{startTime: time.Now(), entry: taskEntry(ctx)}
+	for _, seg := range segments {
+		r.segments = append(r.segments, moveDebugSegment{mapName: seg.MapName, path: seg.Path})
+	}
+	return r
+}
+
+// beginSegment switches the segment the following samples belong to, no-op if r is nil
+func (r *moveDebugRecorder) beginSegment(index int) {
+	if r == nil {
+		return
+	}
+	r.current = index
+}
+
+// track records a tracked location, no-op if r is nil
+func (r *moveDebugRecorder) track(result *MapTrackerInferResult) {
+	if r == nil || r.current >= len(r.segments) {
+		return
+	}
+	seg := &r.segments[r.current]
+	seg.trajectory = append(seg.trajectory, moveDebugSample{result.X, result.Y, result.Rot})
+	r.lastResult = result
+}
+
+// markStuck records a location where stuck is detected, no-op if r is nil
+func (r *moveDebugRecorder) markStuck(x, y int) {
+	if r == nil || r.current >= len(r.segments) {
+		return
+	}
+	seg := &r.segments[r.current]
+	seg.stuck = append(seg.stuck, moveDebugSample{x: x, y: y})
+}
+
+// save renders the run with the mini-map of the latest screenshot and writes it to the debug directory,
+// no-op if r is nil
+func (r *moveDebugRecorder) save(ctrl *maa.Controller, ok bool) {
+	if r == nil {
+		return
+	}
+	infer := mapTrackerInferRunner.(*MapTrackerInfer)
+	outcome := "succeeded"
+	if !ok {
+		outcome = "failed"
+	}
+
+	panels := make([]*image.RGBA, 0, len(r.segments)+1)
+	if img, err := ctrl.CacheImage(); err == nil && img != nil {
+		screenImg := minicv.ImageConvertRGBA(img)
+		panels = append(panels, renderMinimapPanel(screenImg, infer.getMinimapGeometry(ctrl, screenImg), r.lastResult))
+	}
+	for i := range r.segments {
+		if panel := renderRoutePanel(&r.segments[i], r.lastResult); panel != nil {
+			panels = append(panels, panel)
+		}
+	}
+
+	title := fmt.Sprintf("MapTrackerMove %s | %s | %s | %.1fs",
+		r.entry, r.startTime.Format(time.DateTime), outcome, time.Since(r.startTime).Seconds())
+	name := fmt.Sprintf("%s_move_%s.png", r.startTime.Format("20060102-150405.000"), outcome)
+	if path, err := saveDebugImage(name, stitchDebugPanels(title, panels)); err != nil {
+		log.Warn().Err(err).Msg("Failed to save navigation debug image")
+	} else {
+		log.Info().Str("path", path).Msg("Navigation debug image saved")
+	}
+}
+
+// saveInferDebugImage renders one inference with the mini-map and the matched window on the map,
+// and writes it to the debug directory. The result may be nil if not hit.
+func (i *MapTrackerInfer) saveInferDebugImage(screenImg *image.RGBA, geo *MinimapGeometry, result *MapTrackerInferResult) {
+	now := time.Now()
+	panels := []*image.RGBA{renderMinimapPanel(screenImg, geo, result)}
+	outcome := "miss"
+	if result != nil {
+		outcome = result.MapName
+		seg := &moveDebugSegment{mapName: result.MapName}
+		if panel := renderRoutePanel(seg, result); panel != nil {
+			panels = append(panels, panel)
+		}
+	}
+
+	title := fmt.Sprintf("MapTrackerInfer | %s", now.Format(time.DateTime))
+	name := fmt.Sprintf("%s_infer_%s.png", now.Format("20060102-150405.000"), outcome)
+	if path, err := saveDebugImage(name, stitchDebugPanels(title, panels)); err != nil {
+		log.Warn().Err(err).Msg("Failed to save inference debug image")
+	} else {
+		log.Debug().Str("path", path).Msg("Inference debug image saved")
+	}
+}
+
+// renderMinimapPanel renders the mini-map crop with the pointer and camera headings of the result (may be nil)
+func renderMinimapPanel(screenImg *image.RGBA, geo *MinimapGeometry, result *MapTrackerInferResult) *image.RGBA {
+	crop := minicv.ImageCropSquareByRadius(screenImg, geo.LocCenterX, geo.LocCenterY, geo.LocRadius)
+	img := minicv.ImageScale(crop, float64(DEBUG_IMAGE_MINIMAP_SIZE)/float64(max(1, crop.Rect.Dx())))
+	cx, cy := img.Rect.Dx()/2, img.Rect.Dy()/2
+
+	lines := []string{"Mini-map"}
+	if result != nil {
+		length := img.Rect.Dx() / 3
+		if result.CameraConf > 0 {
+			minicv.DrawArrow(img, cx, cy, result.CameraRot, length, 1, debugColorCamera)
+		}
+		minicv.DrawArrow(img, cx, cy, result.Rot, length, 1, debugColorHeading)
+		lines = append(lines,
+			fmt.Sprintf("rot %.1f (conf %.2f)", result.Rot, result.RotConf),
+			fmt.Sprintf("camera %.1f (conf %.2f)", result.CameraRot, result.CameraConf),
+			fmt.Sprintf("%s [%d, %d]", result.InferMode, result.X, result.Y),
+			fmt.Sprintf("loc conf %.2f", result.LocConf),
+		)
+	} else {
+		lines = append(lines, "not hit")
+	}
+	return labelDebugPanel(img, lines)
+}
+
+// renderRoutePanel renders the part of the map covering the planned route, the trajectory and the result (may be nil).
+// The matched window of the result is drawn if it is on this map. Returns nil if the map fails to load.
+func renderRoutePanel(seg *moveDebugSegment, result *MapTrackerInferResult) *image.RGBA {
+	lease := mapStore.acquire()
+	defer lease.release()
+	m, ok := lease.get(seg.mapName, 1.0, 1)
+	if !ok {
+		return nil
+	}
+
+	// Bounding box of everything to draw, in map coordinates
+	onMap := result != nil && result.MapName == seg.mapName
+	points := make([]image.Point, 0, len(seg.path)+len(seg.trajectory)+1)
+	for _, p := range seg.path {
+		points = append(points, image.Pt(p.X, p.Y))
+	}
+	for _, s := range seg.trajectory {
+		points = append(points, image.Pt(s.x, s.y))
+	}
+	if onMap {
+		points = append(points, image.Pt(result.X, result.Y))
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	bounds := image.Rectangle{points[0], points[0].Add(image.Pt(1, 1))}
+	for _, p := range points[1:] {
+		bounds = bounds.Union(image.Rectangle{p, p.Add(image.Pt(1, 1))})
+	}
+	bounds = bounds.Inset(-DEBUG_IMAGE_MAP_MARGIN).Sub(image.Pt(m.OffsetX, m.OffsetY)).Intersect(m.Img.Rect)
+	if bounds.Empty() {
+		return nil
+	}
+
+	crop := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(crop, crop.Rect, m.Img, bounds.Min, draw.Src)
+	img := minicv.ImageScale(crop, DEBUG_IMAGE_MAP_SCALE)
+	toImg := func(x, y int) (int, int) {
+		return int(float64(x-m.OffsetX-bounds.Min.X) * DEBUG_IMAGE_MAP_SCALE),
+			int(float64(y-m.OffsetY-bounds.Min.Y) * DEBUG_IMAGE_MAP_SCALE)
+	}
+
+	// Planned route
+	for idx, p := range seg.path {
+		x, y := toImg(p.X, p.Y)
+		if idx > 0 {
+			px, py := toImg(seg.path[idx-1].X, seg.path[idx-1].Y)
+			minicv.DrawLine(img, px, py, x, y, 1, debugColorRoute)
+		}
+		minicv.DrawPoint(img, x, y, 3, debugColorRoute)
+		minicv.DrawText(img, x+5, y-6, fmt.Sprint(idx), debugColorText)
+	}
+
+	// Actual trajectory with sampled headings
+	for idx, s := range seg.trajectory {
+		x, y := toImg(s.x, s.y)
+		if idx > 0 {
+			px, py := toImg(seg.trajectory[idx-1].x, seg.trajectory[idx-1].y)
+			minicv.DrawLine(img, px, py, x, y, 0, debugColorTrajectory)
+		}
+		if idx%DEBUG_IMAGE_HEADING_INTERVAL == 0 {
+			minicv.DrawArrow(img, x, y, s.rot, 12, 0, debugColorHeading)
+		}
+	}
+
+	// Stuck points
+	for _, s := range seg.stuck {
+		x, y := toImg(s.x, s.y)
+		minicv.DrawCircle(img, x, y, 8, 1, debugColorStuck)
+	}
+
+	// Matched window of the mini-map
+	if onMap {
+		x, y := toImg(result.X, result.Y)
+		r := int(LOC_RADIUS * DEBUG_IMAGE_MAP_SCALE)
+		minicv.DrawRect(img, image.Rect(x-r, y-r, x+r+1, y+r+1), 1, debugColorWindow)
+		minicv.DrawArrow(img, x, y, result.Rot, r/2, 1, debugColorHeading)
+	}
+
+	lines := []string{seg.mapName}
+	if len(seg.path) > 0 || len(seg.trajectory) > 0 {
+		lines = append(lines, fmt.Sprintf("route %d points, trajectory %d samples, stuck %d times",
+			len(seg.path), len(seg.trajectory), len(seg.stuck)))
+	}
+	return labelDebugPanel(img, lines)
+}
+
+// labelDebugPanel puts text lines below an image
+func labelDebugPanel(img *image.RGBA, lines []string) *image.RGBA {
+	const lineHeight = 15
+	w := img.Rect.Dx()
+	for _, line := range lines {
+		w = max(w, len(line)*7+8)
+	}
+	panel := image.NewRGBA(image.Rect(0, 0, w, img.Rect.Dy()+len(lines)*lineHeight+8))
+	minicv.FillRect(panel, panel.Rect, debugColorBackground)
+	draw.Draw(panel, img.Rect, img, image.Point{}, draw.Src)
+	for idx, line := range lines {
+		minicv.DrawText(panel, 4, img.Rect.Dy()+4+idx*lineHeight, line, debugColorText)
+	}
+	return panel
+}
+
+// stitchDebugPanels places the panels side by side under a title line
+func stitchDebugPanels(title string, panels []*image.RGBA) *image.RGBA {
+	const gap, titleHeight = 8, 24
+	w, h := len(title)*7+2*gap, 0
+	panelsW := gap
+	for _, p := range panels {
+		panelsW += p.Rect.Dx() + gap
+		h = max(h, p.Rect.Dy())
+	}
+	canvas := image.NewRGBA(image.Rect(0, 0, max(w, panelsW), titleHeight+h+gap))
+	minicv.FillRect(canvas, canvas.Rect, debugColorBackground)
+	minicv.DrawText(canvas, gap, 6, title, debugColorText)
+
+	x := gap
+	for _, p := range panels {
+		draw.Draw(canvas, p.Rect.Add(image.Pt(x, titleHeight)), p, image.Point{}, draw.Src)
+		x += p.Rect.Dx() + gap
+	}
+	return canvas
+}
+
+// saveDebugImage writes a PNG image to the debug image directory, returns the path written
+func saveDebugImage(name string, img image.Image) (string, error) {
+	if err := os.MkdirAll(DEBUG_IMAGE_DIR, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(DEBUG_IMAGE_DIR, name)
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		return "", err
+	}
+	return path, nil
+}
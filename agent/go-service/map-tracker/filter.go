@@ -0,0 +1,257 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"math"
+)
+
+// InferFilterType is the time-series tracking filter used by MapTrackerInfer
+type InferFilterType string
+
+const (
+	// FILTER_HEURISTIC uses the convinced/pending heuristic with linear virtual hits
+	FILTER_HEURISTIC InferFilterType = "heuristic"
+	// FILTER_KALMAN uses a constant-velocity Kalman filter fused with the rotation heading
+	FILTER_KALMAN InferFilterType = "kalman"
+)
+
+// KalmanTracker is a constant-velocity Kalman filter over the player's location.
+// State is [x, y, vx, vy] in map pixels and pixels per second.
+type KalmanTracker struct {
+	mapName     string
+	x           [4]float64
+	p           [4][4]float64
+	lastTime    int64
+	lastHitTime int64
+	outliers    int
+	outlier     InferLocationRawResult
+}
+
+// reset clears the tracker so that the next hit reinitializes it
+func (k *KalmanTracker) reset() {
+	*k = KalmanTracker{}
+}
+
+// init initializes the tracker at the given location hit with zero velocity
+func (k *KalmanTracker) init(loc *InferLocationRawResult, nowMs int64) {
+	r := measurementVariance(loc.conf)
+	v := KALMAN_INIT_SPEED_STD * KALMAN_INIT_SPEED_STD
+	*k = KalmanTracker{
+		mapName:     loc.mapName,
+		x:           [4]float64{float64(loc.x), float64(loc.y), 0, 0},
+		p:           [4][4]float64{{r, 0, 0, 0}, {0, r, 0, 0}, {0, 0, v, 0}, {0, 0, 0, v}},
+		lastTime:    nowMs,
+		lastHitTime: nowMs,
+	}
+}
+
+// predict propagates the state to nowMs with the constant-velocity model
+func (k *KalmanTracker) predict(nowMs int64) {
+	dt := float64(nowMs-k.lastTime) / 1000.0
+	if dt <= 0 {
+		return
+	}
+	k.lastTime = nowMs
+
+	// x' = F x
+	k.x[0] += k.x[2] * dt
+	k.x[1] += k.x[3] * dt
+
+	// P' = F P F^T + Q, where F = [I dt*I; 0 I]
+	p := k.p
+	for i := range 4 {
+		p[i][0] += p[i][2] * dt
+		p[i][1] += p[i][3] * dt
+	}
+	for j := range 4 {
+		p[0][j] += p[2][j] * dt
+		p[1][j] += p[3][j] * dt
+	}
+	q := KALMAN_ACCEL_STD * KALMAN_ACCEL_STD
+	dt2, dt3, dt4 := dt*dt, dt*dt*dt, dt*dt*dt*dt
+	for a := range 2 {
+		p[a][a] += q * dt4 / 4
+		p[a][a+2] += q * dt3 / 2
+		p[a+2][a] += q * dt3 / 2
+		p[a+2][a+2] += q * dt2
+	}
+	k.p = p
+}
+
+// update applies a 2D measurement z of the location with isotropic variance r,
+// returns whether it was applied (false if rejected by Mahalanobis gating)
+func (k *KalmanTracker) update(z [2]float64, r float64) bool {
+	// Innovation y = z - H x, S = H P H^T + R
+	y := [2]float64{z[0] - k.x[0], z[1] - k.x[1]}
+	s := [2][2]float64{
+		{k.p[0][0] + r, k.p[0][1]},
+		{k.p[1][0], k.p[1][1] + r},
+	}
+	det := s[0][0]*s[1][1] - s[0][1]*s[1][0]
+	if det <= 1e-12 {
+		return false
+	}
+	si := [2][2]float64{{s[1][1] / det, -s[0][1] / det}, {-s[1][0] / det, s[0][0] / det}}
+	d2 := y[0]*(si[0][0]*y[0]+si[0][1]*y[1]) + y[1]*(si[1][0]*y[0]+si[1][1]*y[1])
+	if d2 > KALMAN_GATE_CHI2 {
+		return false
+	}
+
+	// K = P H^T S^-1
+	var kg [4][2]float64
+	for i := range 4 {
+		ph0, ph1 := k.p[i][0], k.p[i][1]
+		kg[i][0] = ph0*si[0][0] + ph1*si[1][0]
+		kg[i][1] = ph0*si[0][1] + ph1*si[1][1]
+	}
+
+	// x = x + K y, P = (I - K H) P
+	for i := range 4 {
+		k.x[i] += kg[i][0]*y[0] + kg[i][1]*y[1]
+	}
+	p := k.p
+	for i := range 4 {
+		for j := range 4 {
+			p[i][j] -= kg[i][0]*k.p[0][j] + kg[i][1]*k.p[1][j]
+		}
+	}
+	k.p = p
+	return true
+}
+
+// correctLocation fuses a location hit, rejecting outliers by Mahalanobis gating.
+// The filter is reinitialized after KALMAN_REINIT_COUNT consecutive rejections close to each other.
+func (k *KalmanTracker) correctLocation(loc *InferLocationRawResult, nowMs int64) bool {
+	accepted := false
+	if loc.mapName == k.mapName {
+		accepted = k.update([2]float64{float64(loc.x), float64(loc.y)}, measurementVariance(loc.conf))
+	}
+	if accepted {
+		k.outliers = 0
+		k.lastHitTime = nowMs
+		return true
+	}
+
+	isCloseToOutlier := k.outliers > 0 && k.outlier.mapName == loc.mapName &&
+		math.Hypot(float64(k.outlier.x-loc.x), float64(k.outlier.y-loc.y)) < CONVINCED_DISTANCE_THRESHOLD
+	if isCloseToOutlier {
+		k.outliers++
+	} else {
+		k.outliers = 1
+	}
+	k.outlier = *loc
+	if k.outliers >= KALMAN_REINIT_COUNT {
+		k.init(loc, nowMs)
+		return true
+	}
+	return false
+}
+
+// correctHeading fuses the player's heading as a pseudo-measurement of the moving direction,
+// by observing the velocity component perpendicular to the heading as zero.
+// It only applies when the player is estimated to be moving forward, since heading carries no speed.
+func (k *KalmanTracker) correctHeading(rot float64) {
+	rad := rot * math.Pi / 180.0
+	hx, hy := math.Sin(rad), -math.Cos(rad)
+	if k.x[2]*hx+k.x[3]*hy < KALMAN_HEADING_MIN_SPEED {
+		return
+	}
+
+	// Scalar update with H = [0, 0, nx, ny], where n is perpendicular to the heading
+	nx, ny := -hy, hx
+	y := -(k.x[2]*nx + k.x[3]*ny)
+	var ph [4]float64
+	for i := range 4 {
+		ph[i] = k.p[i][2]*nx + k.p[i][3]*ny
+	}
+	s := ph[2]*nx + ph[3]*ny + KALMAN_HEADING_SPEED_STD*KALMAN_HEADING_SPEED_STD
+	for i := range 4 {
+		k.x[i] += ph[i] / s * y
+	}
+	for i := range 4 {
+		for j := range 4 {
+			k.p[i][j] -= ph[i] * ph[j] / s
+		}
+	}
+}
+
+// posStd returns the larger standard deviation of the location estimate
+func (k *KalmanTracker) posStd() float64 {
+	return math.Sqrt(max(k.p[0][0], k.p[1][1]))
+}
+
+// measurementVariance returns the location measurement variance for a match confidence
+func measurementVariance(conf float64) float64 {
+	std := KALMAN_MEASUREMENT_STD / max(conf, 0.1)
+	return std * std
+}
+
+// fuseKalman fuses a location hit (nil if missed) and a rotation hit (nil if missed) with the Kalman filter,
+// returns the filtered location or nil. The caller must hold s.mu.
+func (s *InferState) fuseKalman(loc *InferLocationRawResult, rot *InferRotationRawResult, nowMs int64) *InferLocationRawResult {
+	if s.kalman == nil {
+		s.kalman = &KalmanTracker{}
+	}
+	k := s.kalman
+
+	if k.mapName != "" && nowMs-k.lastHitTime >= CONVINCED_VALID_TIME_MS {
+		k.reset()
+	}
+
+	accepted := false
+	if k.mapName == "" {
+		if loc == nil {
+			return nil
+		}
+		k.init(loc, nowMs)
+		accepted = true
+	} else {
+		k.predict(nowMs)
+		if loc != nil {
+			accepted = k.correctLocation(loc, nowMs)
+		}
+		if rot != nil {
+			k.correctHeading(rot.rot)
+		}
+	}
+
+	if k.posStd() > KALMAN_MAX_POS_STD {
+		k.reset()
+		return nil
+	}
+
+	cov := [2][2]float64{{k.p[0][0], k.p[0][1]}, {k.p[1][0], k.p[1][1]}}
+	result := &InferLocationRawResult{
+		mapName: k.mapName,
+		x:       int(math.Round(k.x[0])),
+		y:       int(math.Round(k.x[1])),
+		source:  VIRTUAL_HIT,
+		cov:     &cov,
+	}
+	if accepted {
+		result.conf = loc.conf
+		result.source = loc.source
+		result.elapsedTimeMs = loc.elapsedTimeMs
+	}
+
+	return result
+}
+
+// trackedLocation returns the location last tracked by the filter and the time of its last hit,
+// and whether it is recent and settled enough to center fast search on. Each filter keeps its own state,
+// so that the Kalman filter never alters the heuristic one. The caller must hold s.mu.
+func (s *InferState) trackedLocation(filter InferFilterType, nowMs int64) (string, int, int, int64, bool) {
+	if filter == FILTER_KALMAN {
+		k := s.kalman
+		if k == nil || k.mapName == "" {
+			return "", 0, 0, 0, false
+		}
+		stable := nowMs-k.lastHitTime < CONVINCED_VALID_TIME_MS
+		return k.mapName, int(math.Round(k.x[0])), int(math.Round(k.x[1])), k.lastHitTime, stable
+	}
+	if s.convinced.mapName == "" {
+		return "", 0, 0, 0, false
+	}
+	stable := nowMs-s.convincedLastHitTime < CONVINCED_VALID_TIME_MS && s.pendingHitCount == 0
+	return s.convinced.mapName, s.convinced.x, s.convinced.y, s.convincedLastHitTime, stable
+}
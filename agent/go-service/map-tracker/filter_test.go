@@ -0,0 +1,169 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"math"
+	"testing"
+)
+
+// kalmanStep is one inference fed to the Kalman filter, where loc and rot are nil if missed
+type kalmanStep struct {
+	nowMs int64
+	loc   *InferLocationRawResult
+	rot   *InferRotationRawResult
+}
+
+// kalmanWalk returns hits every stepMs from t0 to t1 on a straight walk starting at (x, y) with velocity (vx, vy) in px/s.
+// The heading is given along with the hits if withHeading is set.
+func kalmanWalk(t0, t1, stepMs int64, x, y, vx, vy float64, withHeading bool) []kalmanStep {
+	var steps []kalmanStep
+	for t := t0; t <= t1; t += stepMs {
+		dt := float64(t-t0) / 1000.0
+		step := kalmanStep{nowMs: t, loc: &InferLocationRawResult{
+			mapName: "map",
+			x:       int(math.Round(x + vx*dt)),
+			y:       int(math.Round(y + vy*dt)),
+			conf:    0.9,
+			source:  FULL_SEARCH_HIT,
+		}}
+		if withHeading {
+			step.rot = &InferRotationRawResult{rot: math.Atan2(vx, -vy) * 180 / math.Pi, conf: 0.9}
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// kalmanHit returns a single location hit at (x, y) on the map
+func kalmanHit(nowMs int64, mapName string, x, y int) kalmanStep {
+	return kalmanStep{nowMs: nowMs, loc: &InferLocationRawResult{mapName: mapName, x: x, y: y, conf: 0.9, source: FULL_SEARCH_HIT}}
+}
+
+func TestFuseKalman(t *testing.T) {
+	stationary := kalmanWalk(0, 2000, 200, 100, 200, 0, 0, false)
+	walking := kalmanWalk(0, 3000, 200, 100, 200, 20, 0, true)
+
+	tests := []struct {
+		name         string
+		steps        []kalmanStep
+		wantNil      bool
+		wantMap      string
+		wantX, wantY float64
+		wantSource   InferLocationHitMode
+		wantV        *[2]float64 // nil if the velocity is not checked
+	}{
+		{
+			name:    "stationary",
+			steps:   stationary,
+			wantMap: "map", wantX: 100, wantY: 200,
+			wantSource: FULL_SEARCH_HIT,
+			wantV:      &[2]float64{0, 0},
+		},
+		{
+			name:    "constant velocity with heading",
+			steps:   walking,
+			wantMap: "map", wantX: 160, wantY: 200,
+			wantSource: FULL_SEARCH_HIT,
+			wantV:      &[2]float64{20, 0},
+		},
+		{
+			name:    "single outlier rejected",
+			steps:   append(stationary[:len(stationary):len(stationary)], kalmanHit(2200, "map", 300, 200)),
+			wantMap: "map", wantX: 100, wantY: 200,
+			wantSource: VIRTUAL_HIT,
+		},
+		{
+			name:    "hit on another map rejected",
+			steps:   append(stationary[:len(stationary):len(stationary)], kalmanHit(2200, "other", 100, 200)),
+			wantMap: "map", wantX: 100, wantY: 200,
+			wantSource: VIRTUAL_HIT,
+		},
+		{
+			name: "consistent outliers reinitialize",
+			steps: append(stationary[:len(stationary):len(stationary)],
+				kalmanHit(2200, "map", 300, 200), kalmanHit(2400, "map", 301, 200), kalmanHit(2600, "map", 300, 201)),
+			wantMap: "map", wantX: 300, wantY: 201,
+			wantSource: FULL_SEARCH_HIT,
+		},
+		{
+			name:    "miss filled by prediction",
+			steps:   append(walking[:len(walking):len(walking)], kalmanStep{nowMs: 3500}),
+			wantMap: "map", wantX: 170, wantY: 200,
+			wantSource: VIRTUAL_HIT,
+		},
+		{
+			name:    "stale track reset",
+			steps:   append(stationary[:len(stationary):len(stationary)], kalmanStep{nowMs: 2000 + CONVINCED_VALID_TIME_MS}),
+			wantNil: true,
+		},
+		{
+			name:    "no hit yet",
+			steps:   []kalmanStep{{nowMs: 0}, {nowMs: 200}},
+			wantNil: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &InferState{}
+			var got *InferLocationRawResult
+			for _, step := range tt.steps {
+				got = s.fuseKalman(step.loc, step.rot, step.nowMs)
+			}
+
+			// The Kalman filter must never alter the heuristic filter state
+			if s.convinced != (InferLocationRawResult{}) || s.convincedLastHitTime != 0 || s.pendingHitCount != 0 {
+				t.Errorf("fuseKalman modified the heuristic state: %+v", s.convinced)
+			}
+
+			if tt.wantNil {
+				if got != nil {
+					t.Fatalf("fuseKalman() = %+v, want nil", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("fuseKalman() = nil, want a location")
+			}
+			if got.mapName != tt.wantMap || got.source != tt.wantSource {
+				t.Errorf("fuseKalman() map = %q, source = %q, want %q, %q", got.mapName, got.source, tt.wantMap, tt.wantSource)
+			}
+			if d := math.Hypot(float64(got.x)-tt.wantX, float64(got.y)-tt.wantY); d > 2 {
+				t.Errorf("fuseKalman() = (%d, %d), want (%.0f, %.0f)", got.x, got.y, tt.wantX, tt.wantY)
+			}
+			if got.cov == nil {
+				t.Errorf("fuseKalman() has no covariance")
+			}
+			if tt.wantV != nil {
+				vx, vy := s.kalman.x[2], s.kalman.x[3]
+				if math.Hypot(vx-tt.wantV[0], vy-tt.wantV[1]) > 2 {
+					t.Errorf("velocity = (%.2f, %.2f), want (%.0f, %.0f)", vx, vy, tt.wantV[0], tt.wantV[1])
+				}
+			}
+		})
+	}
+}
+
+func TestKalmanHeadingOnlyWhenMovingForward(t *testing.T) {
+	tests := []struct {
+		name   string
+		v      [2]float64
+		rot    float64
+		wantVx float64 // the lateral velocity is observed as zero, so only vx along the heading is checked
+		wantVy float64
+	}{
+		{"moving along the heading", [2]float64{20, 3}, 90, 20, 0},
+		{"standing still", [2]float64{0, 0}, 90, 0, 0},
+		{"moving backwards", [2]float64{-20, 3}, 90, -20, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := &KalmanTracker{}
+			k.init(&InferLocationRawResult{mapName: "map", x: 100, y: 100, conf: 1}, 0)
+			k.x[2], k.x[3] = tt.v[0], tt.v[1]
+			k.correctHeading(tt.rot)
+			if math.Abs(k.x[2]-tt.wantVx) > 1 || math.Abs(k.x[3]-tt.wantVy) > 1 {
+				t.Errorf("velocity = (%.2f, %.2f), want (%.0f, %.0f)", k.x[2], k.x[3], tt.wantVx, tt.wantVy)
+			}
+		})
+	}
+}
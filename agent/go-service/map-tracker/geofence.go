@@ -0,0 +1,341 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// MapTrackerGeofence is the custom action component that arms or disarms the geofence of the current task
+type MapTrackerGeofence struct{}
+
+// MapTrackerGeofenceParam represents the custom_action_param for MapTrackerGeofence
+type MapTrackerGeofenceParam struct {
+	// Disable disarms the geofence of the current task instead of arming it.
+	Disable bool `json:"disable,omitempty"`
+	// Poll handles the events queued since the last poll instead of arming the geofence.
+	Poll bool `json:"poll,omitempty"`
+	// Regions is the names of the regions in the region file to watch (required unless Disable or Poll is set).
+	Regions []string `json:"regions,omitempty"`
+	// Interval is the sampling interval in milliseconds.
+	Interval int64 `json:"interval,omitempty"`
+	// OnEnter is a pipeline node to run on polling when the player has entered a watched region.
+	OnEnter string `json:"on_enter,omitempty"`
+	// OnLeave is a pipeline node to run on polling when the player has left a watched region.
+	OnLeave string `json:"on_leave,omitempty"`
+	// StopOnLeave stops the task when the player leaves a watched region.
+	StopOnLeave bool `json:"stop_on_leave,omitempty"`
+	// NoPrint controls whether to suppress printing geofence events to the GUI on polling.
+	NoPrint bool `json:"no_print,omitempty"`
+}
+
+//go:embed messages/geofence_event.html
+var geofenceEventHTML string
+
+// geofenceEvent represents a region transition waiting to be polled
+type geofenceEvent struct {
+	region  string
+	entered bool
+	mapName string
+	x, y    int
+}
+
+// geofence samples the player location in background and queues region transitions.
+// The sampling loop never touches the context of the arming action, which is only valid while it runs,
+// so the configured nodes and GUI messages are handled by polling from the pipeline instead.
+type geofence struct {
+	tasker  *maa.Tasker
+	param   *MapTrackerGeofenceParam
+	regions map[string]*LocationCondition
+	stop    chan struct{}
+	done    chan struct{} // Closed when the sampling loop exits
+
+	mu      sync.Mutex
+	stopped bool
+	events  []geofenceEvent
+}
+
+var (
+	geofences   = make(map[maa.Tasker]*geofence)
+	geofencesMu sync.Mutex
+)
+
+// startGeofence arms a geofence for the tasker, replacing the armed one if any.
+// The replaced one is halted without holding geofencesMu, as its sampling loop may be in the middle of an inference.
+func startGeofence(tasker *maa.Tasker, g *geofence) {
+	geofencesMu.Lock()
+	old, ok := geofences[*tasker]
+	geofences[*tasker] = g
+	geofencesMu.Unlock()
+
+	if ok {
+		old.halt()
+	}
+	go g.run()
+}
+
+// stopGeofence disarms the geofence of the tasker and waits for its sampling loop to exit,
+// returns false if none is armed
+func stopGeofence(tasker *maa.Tasker) bool {
+	geofencesMu.Lock()
+	g, ok := geofences[*tasker]
+	delete(geofences, *tasker)
+	geofencesMu.Unlock()
+
+	if ok {
+		g.halt()
+	}
+	return ok
+}
+
+// getGeofence returns the armed geofence of the tasker, or nil if none
+func getGeofence(tasker *maa.Tasker) *geofence {
+	geofencesMu.Lock()
+	defer geofencesMu.Unlock()
+	return geofences[*tasker]
+}
+
+// halt signals the sampling loop to stop and waits for it to exit
+func (g *geofence) halt() {
+	g.mu.Lock()
+	g.stopped = true
+	g.mu.Unlock()
+	close(g.stop)
+	<-g.done
+}
+
+// geofenceSink disarms the geofence of a tasker when its task finishes
+type geofenceSink struct{}
+
+var _ maa.TaskerEventSink = &geofenceSink{}
+
+// OnTaskerTask handles tasker task events
+func (s *geofenceSink) OnTaskerTask(tasker *maa.Tasker, event maa.EventStatus, detail maa.TaskerTaskDetail) {
+	if event == maa.EventStatusStarting || detail.Entry == "MaaTaskerPostStop" {
+		return
+	}
+	if stopGeofence(tasker) {
+		log.Info().Uint64("task_id", detail.TaskID).Str("entry", detail.Entry).Msg("Geofence disarmed on task finished")
+	}
+}
+
+var _ maa.CustomActionRunner = &MapTrackerGeofence{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerGeofence) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	param, err := a.parseParam(arg.CustomActionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerGeofence")
+		return false
+	}
+
+	tasker := ctx.GetTasker()
+	if param.Disable {
+		if stopGeofence(tasker) {
+			log.Info().Msg("Geofence disarmed")
+		} else {
+			log.Info().Msg("No geofence armed, nothing to disarm")
+		}
+		return true
+	}
+	if param.Poll {
+		g := getGeofence(tasker)
+		if g == nil {
+			log.Info().Msg("No geofence armed, nothing to poll")
+			return true
+		}
+		g.poll(ctx)
+		return true
+	}
+
+	store, err := getRegionStore()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get region store")
+		return false
+	}
+	regions := make(map[string]*LocationCondition, len(param.Regions))
+	for _, name := range param.Regions {
+		cond, ok := store[name]
+		if !ok {
+			log.Error().Str("region", name).Msg("Region is not found in the region file")
+			return false
+		}
+		regions[name] = cond
+	}
+
+	infer := mapTrackerInferRunner.(*MapTrackerInfer)
+	mapsErr, pointerErr := infer.initMaps(ctx), infer.initPointer(ctx)
+	if mapsErr != nil || pointerErr != nil {
+		log.Error().AnErr("mapsErr", mapsErr).AnErr("pointerErr", pointerErr).Msg("Failed to initialize geofence inference")
+		return false
+	}
+
+	startGeofence(tasker, &geofence{
+		tasker:  tasker,
+		param:   param,
+		regions: regions,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	})
+	log.Info().Strs("regions", param.Regions).Int64("interval", param.Interval).Msg("Geofence armed")
+	return true
+}
+
+// run samples the location periodically until the geofence is disarmed or the task is stopping
+func (g *geofence) run() {
+	defer close(g.done)
+	ctrl := g.tasker.GetController()
+	infer := mapTrackerInferRunner.(*MapTrackerInfer)
+
+	// Search only the maps of the watched regions if possible
+	mapNameRegex := regexp.MustCompile(DEFAULT_INFERENCE_PARAM.MapNameRegex)
+	mapNamesMap := make(map[string]struct{})
+	bounded := true
+	for _, cond := range g.regions {
+		bounded = bounded && cond.collectMapNames(nil, mapNamesMap)
+	}
+	if bounded && len(mapNamesMap) > 0 {
+		mapNames := make([]string, 0, len(mapNamesMap))
+		for name := range mapNamesMap {
+			mapNames = append(mapNames, name)
+		}
+		sort.Strings(mapNames)
+		mapNameRegex = regexp.MustCompile(buildMapNameRegex(mapNames))
+	}
+
+	inferParam := DEFAULT_INFERENCE_PARAM_FOR_MOVE
+	inferParam.MapNameRegex = mapNameRegex.String()
+	state := getInferState(g.tasker, GEOFENCE_SESSION)
+	inside := make(map[string]bool, len(g.regions))
+
+	ticker := time.NewTicker(time.Duration(g.param.Interval) * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+		if g.tasker.Stopping() {
+			return
+		}
+
+		// Sample the latest screenshot taken by the running task
+		img, err := ctrl.CacheImage()
+		if err != nil || img == nil {
+			continue
+		}
+		screenImg := minicv.ImageConvertRGBA(img)
+		result := infer.infer(screenImg, infer.getMinimapGeometry(ctrl, screenImg), mapNameRegex, &inferParam, state, getRegionHint(g.tasker), time.Now().UnixMilli())
+		if result == nil || result.InferMode == string(VIRTUAL_HIT) {
+			continue
+		}
+		setRegionHint(g.tasker, mapRegion(result.MapName), "tracking")
+
+		for _, name := range g.param.Regions {
+			now, _ := g.regions[name].match(result.MapName, result.X, result.Y, nil)
+			was, known := inside[name]
+			inside[name] = now
+			if !known {
+				log.Debug().Str("region", name).Bool("inside", now).Msg("Geofence initial state")
+				continue
+			}
+			if now != was {
+				g.report(geofenceEvent{region: name, entered: now, mapName: result.MapName, x: result.X, y: result.Y})
+			}
+		}
+	}
+}
+
+// report logs a region transition and queues it for polling, stopping the task if configured
+func (g *geofence) report(ev geofenceEvent) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopped {
+		return
+	}
+
+	log.Info().Str("region", ev.region).Bool("entered", ev.entered).
+		Str("map", ev.mapName).Int("x", ev.x).Int("y", ev.y).
+		Msg("Geofence event")
+
+	if len(g.events) >= GEOFENCE_MAX_PENDING_EVENTS {
+		log.Warn().Str("region", g.events[0].region).Msg("Geofence event dropped as it is not polled")
+		g.events = g.events[1:]
+	}
+	g.events = append(g.events, ev)
+
+	if !ev.entered && g.param.StopOnLeave {
+		log.Warn().Str("region", ev.region).Msg("Player left the geofence, stopping task")
+		g.tasker.PostStop()
+	}
+}
+
+// poll takes the queued events and handles them on the pipeline thread of ctx
+func (g *geofence) poll(ctx *maa.Context) {
+	g.mu.Lock()
+	events := g.events
+	g.events = nil
+	g.mu.Unlock()
+
+	for _, ev := range events {
+		verb, task := "离开", g.param.OnLeave
+		if ev.entered {
+			verb, task = "进入", g.param.OnEnter
+		}
+		if !g.param.NoPrint {
+			maafocus.NodeActionStarting(ctx, fmt.Sprintf(geofenceEventHTML, verb, ev.region, ev.x, ev.y, ev.mapName))
+		}
+		if task != "" {
+			if _, err := ctx.RunTask(task); err != nil {
+				log.Error().Err(err).Str("task", task).Msg("Failed to run geofence task")
+			}
+		}
+	}
+	log.Debug().Int("eventsCount", len(events)).Msg("Geofence events polled")
+}
+
+func (a *MapTrackerGeofence) parseParam(paramStr string) (*MapTrackerGeofenceParam, error) {
+	var param MapTrackerGeofenceParam
+	if paramStr != "" {
+		if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+			return nil, fmt.Errorf("failed to parse parameters: %w", err)
+		}
+	}
+	if param.Disable && param.Poll {
+		return nil, fmt.Errorf("disable and poll are mutually exclusive")
+	}
+	if param.Disable || param.Poll {
+		return &param, nil
+	}
+
+	if len(param.Regions) == 0 {
+		return nil, fmt.Errorf("regions must be provided")
+	}
+	for i, name := range param.Regions {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("region name must not be empty at index %d", i)
+		}
+	}
+
+	if param.Interval < 0 {
+		return nil, fmt.Errorf("interval must be non-negative")
+	} else if param.Interval == 0 {
+		param.Interval = GEOFENCE_DEFAULT_INTERVAL_MS
+	} else if param.Interval < INFER_INTERVAL_MS {
+		return nil, fmt.Errorf("interval must be at least %d ms", INFER_INTERVAL_MS)
+	}
+
+	return &param, nil
+}
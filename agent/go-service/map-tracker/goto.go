@@ -0,0 +1,162 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type MapTrackerGoTo struct{}
+
+// MapTrackerGoToParam represents the custom_action_param for MapTrackerGoTo
+type MapTrackerGoToParam struct {
+	// Target is the [x, y] coordinate to reach (required).
+	Target *[2]int `json:"target"`
+	// TeleportCost is the walking distance a teleport is considered to take, in pixels.
+	TeleportCost float64 `json:"teleport_cost,omitempty"`
+	// MapTrackerMoveParam holds the map name and movement parameters (path and route are not allowed).
+	MapTrackerMoveParam
+}
+
+var _ maa.CustomActionRunner = &MapTrackerGoTo{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerGoTo) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	param, err := a.parseParam(arg.CustomActionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerGoTo")
+		return false
+	}
+	moveParam := &param.MapTrackerMoveParam
+	mapName, target := moveParam.MapName, *param.Target
+
+	// Validate the movement parameters and set defaults before the first inference uses them,
+	// with the target standing in for the path until it is planned
+	moveParam.Path = makePath([][2]int{target})
+	if err := moveParam.normalize(); err != nil {
+		log.Error().Err(err).Msg("Invalid movement parameters for MapTrackerGoTo")
+		return false
+	}
+
+	anchors, err := getAnchorStore()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get anchor store")
+		return false
+	}
+
+	// Walking distances are measured along planned paths if the walkable mask exists
+	mask, err := getWalkableMask(mapName)
+	if err != nil {
+		log.Debug().Err(err).Str("map", mapName).Msg("Walkable mask unavailable, using straight distances")
+		mask = nil
+	}
+
+	// Walking from the current location is preferred if it is cheaper than any teleport.
+	// The distances from the current location and all anchors are measured by one search from the target.
+	ctrl := ctx.GetTasker().GetController()
+	mapAnchors := anchors[mapName]
+	froms := make([][2]int, 0, len(mapAnchors)+1)
+	for _, anchor := range mapAnchors {
+		froms = append(froms, anchor.Pos)
+	}
+	allMapsRegex := DEFAULT_INFERENCE_PARAM.MapNameRegex + "|" + buildMapNameRegex([]string{mapName})
+	cur, err := doInferRegex(ctx, ctrl, moveParam, allMapsRegex)
+	onMap := err == nil && cur.MapName == mapName
+	if onMap {
+		froms = append(froms, [2]int{cur.X, cur.Y})
+	}
+	dists := walkDistances(mask, froms, target)
+
+	bestTask, bestCost := "", math.Inf(1)
+	if onMap {
+		bestCost = dists[len(mapAnchors)]
+		log.Info().Int("x", cur.X).Int("y", cur.Y).Float64("cost", bestCost).Msg("Already on the target map")
+	}
+	for i, anchor := range mapAnchors {
+		cost := param.TeleportCost + dists[i]
+		log.Debug().Str("task", anchor.Task).Float64("cost", cost).Msg("Teleport anchor candidate")
+		if cost < bestCost {
+			bestTask, bestCost = anchor.Task, cost
+		}
+	}
+	if math.IsInf(bestCost, 1) {
+		log.Error().Str("map", mapName).Int("x", target[0]).Int("y", target[1]).
+			Msg("No teleport anchor or current location can reach the target")
+		return false
+	}
+
+	// Teleport via the SceneManager node of the anchor
+	if bestTask != "" {
+		log.Info().Str("task", bestTask).Float64("cost", bestCost).Msg("Teleporting to the nearest anchor")
+		if err := runTask(ctx, bestTask); err != nil {
+			log.Error().Err(err).Str("task", bestTask).Msg("Failed to run teleport task")
+			return false
+		}
+	}
+
+	// Hand off to the movement loop from where the player actually is
+	cur, err = doInfer(ctx, ctrl, moveParam, mapName)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to infer current location after teleport")
+		return false
+	}
+	path := [][2]int{target}
+	if mask != nil {
+		if path, err = planPath(mask, [2]int{cur.X, cur.Y}, target); err != nil {
+			log.Error().Err(err).Int("fromX", cur.X).Int("fromY", cur.Y).Msg("Failed to plan path to target")
+			return false
+		}
+	}
+	log.Info().Str("map", mapName).
+		Int("fromX", cur.X).Int("fromY", cur.Y).
+		Int("toX", target[0]).Int("toY", target[1]).
+		Interface("path", path).
+		Msg("Moving to target")
+
+	moveParam.Path = makePath(path)
+	moveParam.Segments[0].Path = moveParam.Path
+	return runMove(ctx, arg.CurrentTaskName, moveParam)
+}
+
+// walkDistances returns the walking distance from each point to the target, along planned paths if mask is not nil.
+// Returns +Inf for points with no path.
+func walkDistances(mask *WalkableMask, froms [][2]int, to [2]int) []float64 {
+	if mask != nil {
+		return mask.pathLengthsTo(to, froms)
+	}
+	dists := make([]float64, len(froms))
+	for i, from := range froms {
+		dists[i] = math.Hypot(float64(to[0]-from[0]), float64(to[1]-from[1]))
+	}
+	return dists
+}
+
+func (a *MapTrackerGoTo) parseParam(paramStr string) (*MapTrackerGoToParam, error) {
+	var param MapTrackerGoToParam
+	if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters: %w", err)
+	}
+	if len(param.MapName) == 0 {
+		return nil, fmt.Errorf("map_name is required in parameters, got empty")
+	}
+	if param.Target == nil {
+		return nil, fmt.Errorf("target is required in parameters, got empty")
+	}
+	if len(param.Path) > 0 || param.Route != "" || len(param.Segments) > 0 {
+		return nil, fmt.Errorf("path, route and segments are not allowed, the path starts from the nearest anchor")
+	}
+	if param.PathTrim {
+		return nil, fmt.Errorf("path_trim is not allowed, the path always starts from the current location")
+	}
+	if param.TeleportCost < 0 {
+		return nil, fmt.Errorf("teleport_cost must be non-negative")
+	}
+	if param.TeleportCost == 0 {
+		param.TeleportCost = GOTO_DEFAULT_TELEPORT_COST
+	}
+	return &param, nil
+}
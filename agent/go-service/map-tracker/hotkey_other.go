@@ -0,0 +1,11 @@
+// Copyright (c) 2026 Harry Huang
+
+//go:build !windows
+
+package maptracker
+
+// isHotkeyPressed always returns false on non-Windows platforms
+// Host hotkey detection is only supported on Windows
+func isHotkeyPressed(vk int) bool {
+	return false
+}
@@ -0,0 +1,18 @@
+// Copyright (c) 2026 Harry Huang
+
+//go:build windows
+
+package maptracker
+
+import "golang.org/x/sys/windows"
+
+var procGetAsyncKeyState = windows.NewLazySystemDLL("user32.dll").NewProc("GetAsyncKeyState")
+
+// isHotkeyPressed reports whether the key of the virtual-key code is currently held down on the host
+func isHotkeyPressed(vk int) bool {
+	if vk <= 0 {
+		return false
+	}
+	r, _, _ := procGetAsyncKeyState.Call(uintptr(vk))
+	return r&0x8000 != 0
+}
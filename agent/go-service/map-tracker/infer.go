@@ -6,13 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
-	"image/draw"
 	_ "image/png"
 	"math"
 	"os"
-	"path/filepath"
 	"regexp"
-	"strings"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -27,13 +26,17 @@ type MapTrackerInferResult struct {
 	MapName     string  `json:"mapName"`     // Map name
 	X           int     `json:"x"`           // X coordinate on the map
 	Y           int     `json:"y"`           // Y coordinate on the map
-	Rot         int     `json:"rot"`         // Rotation angle (0-359 degrees)
+	Rot         float64 `json:"rot"`         // Rotation angle of the pointer [0, 360) degrees
 	LocConf     float64 `json:"locConf"`     // Location confidence
 	RotConf     float64 `json:"rotConf"`     // Rotation confidence
+	CameraRot   float64 `json:"cameraRot"`   // Camera heading from the view cone [0, 360) degrees
+	CameraConf  float64 `json:"cameraConf"`  // Camera heading confidence
 	LocTimeMs   int64   `json:"locTimeMs"`   // Location inference time in ms
 	RotTimeMs   int64   `json:"rotTimeMs"`   // Rotation inference time in ms
 	InferMode   string  `json:"inferMode"`   // Inference mode ("FullSearchHit", "FastSearchHit", "VirtualHit")
 	InferTimeMs int64   `json:"inferTimeMs"` // Total inference time in ms
+
+	LocCov *[2][2]float64 `json:"locCov,omitempty"` // Location covariance (only with tracking filter)
 }
 
 // MapTrackerInferParam represents the custom_recognition_param for MapTrackerInfer
@@ -46,31 +49,38 @@ type MapTrackerInferParam struct {
 	Precision float64 `json:"precision,omitempty"`
 	// Threshold controls the minimum confidence required to consider the inference successful.
 	Threshold float64 `json:"threshold,omitempty"`
+	// Filter selects the time-series tracking filter ("heuristic" or "kalman").
+	Filter InferFilterType `json:"filter,omitempty"`
+	// Session is the name of the tracking session; each tasker has its own default session.
+	Session string `json:"session,omitempty"`
+	// SearchMode selects the full search strategy ("exhaustive" or "pyramid").
+	SearchMode InferSearchMode `json:"search_mode,omitempty"`
+	// Debug controls whether to save an annotated image of each inference to the debug directory.
+	Debug bool `json:"debug,omitempty"`
+	// Telemetry controls whether to record each inference to the telemetry stream.
+	Telemetry bool `json:"telemetry,omitempty"`
 }
 
-// MapCache represents a preloaded map image
+// MapCache represents a loaded map image variant
 type MapCache struct {
 	Name     string
 	Img      *image.RGBA
 	Integral minicv.IntegralArray
 	OffsetX  int
 	OffsetY  int
+
+	srcID   string // Source ID for the map data cache
+	mapping []byte // Memory mapping of the cache file, nil if built in memory
 }
 
-// MapTrackerInfer is the custom recognition component for map tracking
+// MapTrackerInfer is the custom recognition component for map tracking,
+// which loads maps on demand from the shared map store
 type MapTrackerInfer struct {
-	// Cache for preloaded resources
-	mapsOnce    sync.Once
-	pointerOnce sync.Once
-	maps        []MapCache
-	pointer     *image.RGBA
-	mapsErr     error
-	pointerErr  error
-
-	// Cache for scaled maps
-	scaledMu    sync.Mutex
-	scaledScale float64
-	scaledMaps  []MapCache
+	// Cache for the pointer template, reloaded when the resource is reloaded
+	pointerMu  sync.Mutex
+	pointerGen uint64
+	pointer    *image.RGBA
+	pointerErr error
 }
 
 type InferState struct {
@@ -83,11 +93,11 @@ type InferState struct {
 	pendingFirstHitTime int64
 	pendingHitCount     int
 
+	kalman *KalmanTracker
+
 	mu sync.Mutex
 }
 
-var globalInferState InferState
-
 type InferLocationHitMode string
 
 const (
@@ -96,6 +106,13 @@ const (
 	VIRTUAL_HIT     InferLocationHitMode = "VirtualHit"
 )
 
+type InferSearchMode string
+
+const (
+	SEARCH_MODE_EXHAUSTIVE InferSearchMode = "exhaustive"
+	SEARCH_MODE_PYRAMID    InferSearchMode = "pyramid"
+)
+
 type InferLocationRawResult struct {
 	mapName       string
 	x             int
@@ -103,12 +120,13 @@ type InferLocationRawResult struct {
 	conf          float64
 	source        InferLocationHitMode
 	elapsedTimeMs int64
+	cov           *[2][2]float64
 }
 
-var emptyLocationRawResult = InferLocationRawResult{"", 0, 0, 0.0, "", 0}
+var emptyLocationRawResult = InferLocationRawResult{"", 0, 0, 0.0, "", 0, nil}
 
 type InferRotationRawResult struct {
-	rot           int
+	rot           float64
 	conf          float64
 	elapsedTimeMs int64
 }
@@ -137,24 +155,78 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 		return nil, false
 	}
 
-	rotStep := max(2, min(8, int(math.Round(8-param.Precision*6))))
-
-	// Initialize resources on first run
-	i.initMaps(ctx)
-	i.initPointer(ctx)
-
-	// Check for initialization errors
-	if i.mapsErr != nil {
-		log.Error().Err(i.mapsErr).Msg("Failed to initialize maps")
+	// Initialize resources, which are reloaded after the resource is reloaded
+	if err := i.initMaps(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize maps")
 		return nil, false
 	}
-	if i.pointerErr != nil {
-		log.Error().Err(i.pointerErr).Msg("Failed to initialize pointer")
+	if err := i.initPointer(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize pointer")
 		return nil, false
 	}
 
 	// Perform inference
 	screenImg := minicv.ImageConvertRGBA(arg.Img)
+	geo := i.getMinimapGeometry(ctx.GetTasker().GetController(), screenImg)
+	tasker := ctx.GetTasker()
+	state := getInferState(tasker, param.Session)
+	result := i.infer(screenImg, geo, mapNameRegex, param, state, getRegionHint(tasker), time.Now().UnixMilli())
+	if param.Debug {
+		i.saveInferDebugImage(screenImg, geo, result)
+	}
+	if param.Telemetry {
+		telemetry.record(&TelemetryRecord{Event: TELEMETRY_INFER, Entry: taskEntry(ctx), Node: arg.CurrentTaskName, Session: param.Session, Infer: result})
+	}
+
+	if result == nil {
+		if param.Print {
+			maafocus.NodeActionStarting(ctx, inferenceFailedHTML)
+		}
+
+		// Return as not hit
+		return &maa.CustomRecognitionResult{
+			Box:    arg.Roi,
+			Detail: "",
+		}, false
+	}
+
+	if result.InferMode != string(VIRTUAL_HIT) {
+		setRegionHint(tasker, mapRegion(result.MapName), "tracking")
+	}
+
+	// Serialize result to JSON
+	detailJSON, err := json.Marshal(result)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal result")
+		return nil, false
+	}
+
+	log.Info().Str("InferMode", result.InferMode).
+		Int64("InferTimeMs", result.InferTimeMs).
+		Str("MapName", result.MapName).
+		Int("X", result.X).Int("Y", result.Y).
+		Float64("Rot", result.Rot).
+		Float64("CameraRot", result.CameraRot).
+		Float64("LocConf", result.LocConf).
+		Float64("RotConf", result.RotConf).
+		Msg("Map tracking inference completed")
+	if param.Print {
+		maafocus.NodeActionStarting(ctx, fmt.Sprintf(inferenceFinishedHTML, result.X, result.Y, result.Rot, result.MapName))
+	}
+
+	// Return as hit
+	return &maa.CustomRecognitionResult{
+		Box:    arg.Roi,
+		Detail: string(detailJSON),
+	}, true
+}
+
+// infer runs location and rotation inference on a screen image with the given mini-map geometry,
+// and fuses the location with the time-series tracking state at time nowMs.
+// Full search tries the maps of the candidate region from state or hint (may be nil) first.
+// Returns nil if not hit.
+func (i *MapTrackerInfer) infer(screenImg *image.RGBA, geo *MinimapGeometry, mapNameRegex *regexp.Regexp, param *MapTrackerInferParam, state *InferState, hint *mapRegionHint, nowMs int64) *MapTrackerInferResult {
+	rotStep := max(2, min(8, int(math.Round(8-param.Precision*6))))
 	t0 := time.Now()
 
 	var wg sync.WaitGroup
@@ -162,15 +234,17 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 
 	var loc *InferLocationRawResult
 	var rot *InferRotationRawResult
+	var cam *InferRotationRawResult
 
 	go func() {
 		defer wg.Done()
-		loc = i.inferLocation(screenImg, mapNameRegex, param)
+		loc = i.inferLocation(screenImg, geo, mapNameRegex, param, state, hint, nowMs)
 	}()
 
 	go func() {
 		defer wg.Done()
-		rot = i.inferRotation(screenImg, rotStep)
+		rot = i.inferRotation(screenImg, geo, rotStep)
+		cam = i.inferCameraRotation(screenImg, geo)
 	}()
 
 	wg.Wait()
@@ -183,92 +257,142 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 	var finalLoc *InferLocationRawResult
 	var finalRot *InferRotationRawResult
 
-	globalInferState.mu.Lock()
-	nowMs := time.Now().UnixMilli()
+	state.mu.Lock()
 
-	// Process internal location hit
+	// Process internal rotation hit
+	if internalRotHit {
+		finalRot = rot
+	}
+
+	// Fuse internal location hit with time-series state
+	var hitLoc *InferLocationRawResult
 	if internalLocHit {
+		hitLoc = loc
+	}
+	if param.Filter == FILTER_KALMAN {
+		finalLoc = state.fuseKalman(hitLoc, finalRot, nowMs)
+	} else {
+		finalLoc = state.fuseHeuristic(hitLoc, nowMs)
+	}
+
+	state.mu.Unlock()
+
+	finalHit := finalLoc != nil && finalRot != nil
+	finalElapsedTimeMs := time.Since(t0).Milliseconds()
+
+	if !finalHit {
+		log.Info().Bool("finalLocHit", finalLoc != nil).Bool("finalRotHit", finalRot != nil).Msg("Map tracking inference did not hit")
+		return nil
+	}
+
+	// Build hit result
+	result := &MapTrackerInferResult{
+		MapName:     finalLoc.mapName,
+		X:           finalLoc.x,
+		Y:           finalLoc.y,
+		Rot:         finalRot.rot,
+		LocConf:     finalLoc.conf,
+		RotConf:     finalRot.conf,
+		LocTimeMs:   finalLoc.elapsedTimeMs,
+		RotTimeMs:   finalRot.elapsedTimeMs,
+		InferMode:   string(finalLoc.source),
+		InferTimeMs: finalElapsedTimeMs,
+		LocCov:      finalLoc.cov,
+	}
+	if cam != nil {
+		result.CameraRot, result.CameraConf = cam.rot, cam.conf
+	}
+	return result
+}
+
+// fuseHeuristic fuses a location hit (nil if missed) with the convinced/pending heuristic,
+// returns the final location or nil. The caller must hold s.mu.
+func (s *InferState) fuseHeuristic(loc *InferLocationRawResult, nowMs int64) *InferLocationRawResult {
+	var finalLoc *InferLocationRawResult
+
+	// Process internal location hit
+	if loc != nil {
 		isCloseToConvinced := func() bool {
-			if globalInferState.convinced.mapName == "" || globalInferState.convinced.mapName != loc.mapName {
+			if s.convinced.mapName == "" || s.convinced.mapName != loc.mapName {
 				return false
 			}
-			dx := float64(globalInferState.convinced.x - loc.x)
-			dy := float64(globalInferState.convinced.y - loc.y)
+			dx := float64(s.convinced.x - loc.x)
+			dy := float64(s.convinced.y - loc.y)
 			return math.Hypot(dx, dy) < CONVINCED_DISTANCE_THRESHOLD
 		}
 
 		isCloseToPending := func() bool {
-			if globalInferState.pending.mapName == "" || globalInferState.pending.mapName != loc.mapName {
+			if s.pending.mapName == "" || s.pending.mapName != loc.mapName {
 				return false
 			}
-			dx := float64(globalInferState.pending.x - loc.x)
-			dy := float64(globalInferState.pending.y - loc.y)
+			dx := float64(s.pending.x - loc.x)
+			dy := float64(s.pending.y - loc.y)
 			return math.Hypot(dx, dy) < CONVINCED_DISTANCE_THRESHOLD
 		}
 
 		if isCloseToConvinced() {
 			// This hit is close to the currently convinced location
-			dt := nowMs - globalInferState.convincedLastHitTime
+			dt := nowMs - s.convincedLastHitTime
 			if dt > 0 {
-				dx := float64(loc.x - globalInferState.convinced.x)
-				dy := float64(loc.y - globalInferState.convinced.y)
+				dx := float64(loc.x - s.convinced.x)
+				dy := float64(loc.y - s.convinced.y)
 				dist := math.Hypot(dx, dy)
-				globalInferState.convincedMoveSpeed = dist / float64(dt)
-				globalInferState.convincedMoveDirection = math.Atan2(dy, dx)
+				s.convincedMoveSpeed = dist / float64(dt)
+				s.convincedMoveDirection = math.Atan2(dy, dx)
 			}
-			globalInferState.convinced = *loc
-			globalInferState.convincedLastHitTime = nowMs
+			s.convinced = *loc
+			s.convincedLastHitTime = nowMs
 			finalLoc = loc
 
 		} else if isCloseToPending() {
 			// This hit is close to the pending location
-			globalInferState.pending.x = loc.x
-			globalInferState.pending.y = loc.y
-			globalInferState.pendingHitCount++
+			s.pending.x = loc.x
+			s.pending.y = loc.y
+			s.pendingHitCount++
 
-			if globalInferState.convinced.mapName == "" ||
-				nowMs-globalInferState.pendingFirstHitTime >= PENDING_TAKEOVER_TIME_MS ||
-				globalInferState.pendingHitCount >= PENDING_TAKEOVER_COUNT_THRESHOLD {
+			if s.convinced.mapName == "" ||
+				nowMs-s.pendingFirstHitTime >= PENDING_TAKEOVER_TIME_MS ||
+				s.pendingHitCount >= PENDING_TAKEOVER_COUNT_THRESHOLD {
 				// Do takeover (replace convinced with pending)
-				globalInferState.convinced = globalInferState.pending
-				globalInferState.convincedLastHitTime = nowMs
-				globalInferState.convincedMoveSpeed = 0
-				globalInferState.convincedMoveDirection = 0
-				globalInferState.pending = emptyLocationRawResult
-				globalInferState.pendingHitCount = 0
-				finalLoc = &globalInferState.convinced
+				s.convinced = s.pending
+				s.convincedLastHitTime = nowMs
+				s.convincedMoveSpeed = 0
+				s.convincedMoveDirection = 0
+				s.pending = emptyLocationRawResult
+				s.pendingHitCount = 0
+				finalLoc = &s.convinced
 			}
 		} else {
 			// This hit is far from both convinced and pending locations
-			if nowMs-globalInferState.convincedLastHitTime < CONVINCED_VALID_TIME_MS {
+			if nowMs-s.convincedLastHitTime < CONVINCED_VALID_TIME_MS {
 				// It's an immediate track loss, start a new pending
-				globalInferState.pending = *loc
-				globalInferState.pendingFirstHitTime = nowMs
-				globalInferState.pendingHitCount = 1
+				s.pending = *loc
+				s.pendingFirstHitTime = nowMs
+				s.pendingHitCount = 1
 			} else {
 				// It's a stale track loss, directly replace convinced with this new hit
-				globalInferState.convinced = *loc
-				globalInferState.convincedLastHitTime = nowMs
-				globalInferState.convincedMoveSpeed = 0
-				globalInferState.convincedMoveDirection = 0
-				globalInferState.pending = emptyLocationRawResult
-				globalInferState.pendingHitCount = 0
-				finalLoc = &globalInferState.convinced
+				s.convinced = *loc
+				s.convincedLastHitTime = nowMs
+				s.convincedMoveSpeed = 0
+				s.convincedMoveDirection = 0
+				s.pending = emptyLocationRawResult
+				s.pendingHitCount = 0
+				finalLoc = &s.convinced
 			}
 		}
 	}
 
 	if finalLoc == nil {
-		if globalInferState.convinced.mapName != "" && nowMs-globalInferState.convincedLastHitTime < CONVINCED_VALID_TIME_MS {
+		if s.convinced.mapName != "" && nowMs-s.convincedLastHitTime < CONVINCED_VALID_TIME_MS {
 			// This is a temporary miss, but we can generate a virtual result
-			dt := nowMs - globalInferState.convincedLastHitTime
-			sx := globalInferState.convincedMoveSpeed * math.Cos(globalInferState.convincedMoveDirection)
-			sy := globalInferState.convincedMoveSpeed * math.Sin(globalInferState.convincedMoveDirection)
-			vx := globalInferState.convinced.x + int(sx*float64(dt))
-			vy := globalInferState.convinced.y + int(sy*float64(dt))
+			dt := nowMs - s.convincedLastHitTime
+			sx := s.convincedMoveSpeed * math.Cos(s.convincedMoveDirection)
+			sy := s.convincedMoveSpeed * math.Sin(s.convincedMoveDirection)
+			vx := s.convinced.x + int(sx*float64(dt))
+			vy := s.convinced.y + int(sy*float64(dt))
 
 			finalLoc = &InferLocationRawResult{
-				mapName:       globalInferState.convinced.mapName,
+				mapName:       s.convinced.mapName,
 				x:             vx,
 				y:             vy,
 				conf:          0,
@@ -278,67 +402,7 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 		}
 	}
 
-	// Process internal rotation hit
-	if internalRotHit {
-		finalRot = rot
-	}
-
-	globalInferState.mu.Unlock()
-
-	finalHit := finalLoc != nil && finalRot != nil
-	finalElapsedTimeMs := time.Since(t0).Milliseconds()
-
-	if !finalHit {
-		log.Info().Bool("finalLocHit", finalLoc != nil).Bool("finalRotHit", finalRot != nil).Msg("Map tracking inference did not hit")
-		if param.Print {
-			maafocus.NodeActionStarting(ctx, inferenceFailedHTML)
-		}
-
-		// Return as not hit
-		return &maa.CustomRecognitionResult{
-			Box:    arg.Roi,
-			Detail: "",
-		}, false
-	}
-
-	// Build hit result
-	result := MapTrackerInferResult{
-		MapName:     finalLoc.mapName,
-		X:           finalLoc.x,
-		Y:           finalLoc.y,
-		Rot:         finalRot.rot,
-		LocConf:     finalLoc.conf,
-		RotConf:     finalRot.conf,
-		LocTimeMs:   finalLoc.elapsedTimeMs,
-		RotTimeMs:   finalRot.elapsedTimeMs,
-		InferMode:   string(finalLoc.source),
-		InferTimeMs: finalElapsedTimeMs,
-	}
-
-	// Serialize result to JSON
-	detailJSON, err := json.Marshal(result)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to marshal result")
-		return nil, false
-	}
-
-	log.Info().Str("InferMode", result.InferMode).
-		Int64("InferTimeMs", result.InferTimeMs).
-		Str("MapName", result.MapName).
-		Int("X", result.X).Int("Y", result.Y).
-		Int("Rot", result.Rot).
-		Float64("LocConf", result.LocConf).
-		Float64("RotConf", result.RotConf).
-		Msg("Map tracking inference completed")
-	if param.Print {
-		maafocus.NodeActionStarting(ctx, fmt.Sprintf(inferenceFinishedHTML, finalLoc.x, finalLoc.y, result.Rot, finalLoc.mapName))
-	}
-
-	// Return as hit
-	return &maa.CustomRecognitionResult{
-		Box:    arg.Roi,
-		Detail: string(detailJSON),
-	}, true
+	return finalLoc
 }
 
 func (r *MapTrackerInfer) parseParam(paramStr string) (*MapTrackerInferParam, error) {
@@ -360,6 +424,22 @@ func (r *MapTrackerInfer) parseParam(paramStr string) (*MapTrackerInferParam, er
 			} else if param.Threshold < 0.0 || param.Threshold > 1.0 {
 				return nil, fmt.Errorf("invalid threshold value: %f", param.Threshold)
 			}
+
+			switch param.Filter {
+			case "":
+				param.Filter = DEFAULT_INFERENCE_PARAM.Filter
+			case FILTER_HEURISTIC, FILTER_KALMAN:
+			default:
+				return nil, fmt.Errorf("invalid filter value: %s", param.Filter)
+			}
+
+			switch param.SearchMode {
+			case "":
+				param.SearchMode = DEFAULT_INFERENCE_PARAM.SearchMode
+			case SEARCH_MODE_EXHAUSTIVE, SEARCH_MODE_PYRAMID:
+			default:
+				return nil, fmt.Errorf("invalid search_mode value: %s", param.SearchMode)
+			}
 		} else {
 			return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
 		}
@@ -369,123 +449,35 @@ func (r *MapTrackerInfer) parseParam(paramStr string) (*MapTrackerInferParam, er
 	}
 }
 
-// initMaps initializes the map cache (thread-safe, runs once)
-func (i *MapTrackerInfer) initMaps(ctx *maa.Context) {
-	i.mapsOnce.Do(func() {
-		i.maps, i.mapsErr = i.loadMaps(ctx)
-		if i.mapsErr != nil {
-			log.Error().Err(i.mapsErr).Msg("Failed to load maps")
-		} else {
-			log.Info().Int("mapsCount", len(i.maps)).Msg("Map images loaded")
-		}
-	})
-}
-
-// initPointer initializes the pointer template cache (thread-safe, runs once)
-func (i *MapTrackerInfer) initPointer(ctx *maa.Context) {
-	i.pointerOnce.Do(func() {
-		i.pointer, i.pointerErr = i.loadPointer(ctx)
-		if i.pointerErr != nil {
-			log.Error().Err(i.pointerErr).Msg("Failed to load pointer template")
-		} else {
-			log.Info().Msg("Pointer template image loaded")
-		}
-	})
+// initMaps ensures the map catalog of the current resource is scanned, maps are loaded on demand
+func (i *MapTrackerInfer) initMaps(ctx *maa.Context) error {
+	return mapStore.scan()
 }
 
-// loadMaps loads all map images from the resource directory
-// and try crops them if map bbox data exists
-func (i *MapTrackerInfer) loadMaps(ctx *maa.Context) ([]MapCache, error) {
-	// Find map directory using search strategy
-	mapDir := findResource(MAP_DIR)
-	if mapDir == "" {
-		return nil, fmt.Errorf("map directory not found (searched in cache and standard locations)")
-	}
-
-	// Read map_bbox.json if it exists
-	rectList := make(map[string][]int)
-	rectPath := filepath.Join(mapDir, "map_bbox.json")
-	if data, err := os.ReadFile(rectPath); err == nil {
-		if err := json.Unmarshal(data, &rectList); err != nil {
-			log.Warn().Err(err).Str("path", rectPath).Msg("Failed to unmarshal map_bbox.json")
-		} else {
-			log.Info().Msg("Map bbox JSON loaded")
-		}
-	}
-
-	// Read directory entries
-	entries, err := os.ReadDir(mapDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read map directory: %w", err)
-	}
-
-	// Load all PNG files
-	maps := make([]MapCache, 0)
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
+// initPointer initializes the pointer template cache (thread-safe, reloads after the resource is reloaded)
+func (i *MapTrackerInfer) initPointer(ctx *maa.Context) error {
+	i.pointerMu.Lock()
+	defer i.pointerMu.Unlock()
 
-		filename := entry.Name()
-		if !strings.HasSuffix(filename, ".png") {
-			continue
-		}
-
-		// Load image
-		imgPath := filepath.Join(mapDir, filename)
-		file, err := os.Open(imgPath)
-		if err != nil {
-			log.Warn().Err(err).Str("path", imgPath).Msg("Failed to open map image")
-			continue
-		}
-
-		img, _, err := image.Decode(file)
-		file.Close()
-		if err != nil {
-			log.Warn().Err(err).Str("path", imgPath).Msg("Failed to decode map image")
-			continue
-		}
-
-		// Extract map name (remove ".png" suffix)
-		name := strings.TrimSuffix(filename, ".png")
-
-		var imgRGBA *image.RGBA
-		offsetX, offsetY := 0, 0
-
-		// Crop if valid rect exists
-		if r, ok := rectList[name]; ok && len(r) == 4 {
-			rect := image.Rect(r[0], r[1], r[2], r[3])
-			expand := LOC_RADIUS / 2
-			rect = image.Rect(rect.Min.X-expand, rect.Min.Y-expand, rect.Max.X+expand, rect.Max.Y+expand)
-
-			// Crop precisely using drawing
-			b := img.Bounds()
-			r0 := rect.Intersect(b)
-			dst := image.NewRGBA(image.Rect(0, 0, r0.Dx(), r0.Dy()))
-			draw.Draw(dst, dst.Bounds(), img, r0.Min, draw.Src)
-			imgRGBA = dst
-			offsetX, offsetY = r0.Min.X, r0.Min.Y
-		} else {
-			imgRGBA = minicv.ImageConvertRGBA(img)
-		}
-
-		// Precompute integral image
-		integral := minicv.GetIntegralArray(imgRGBA)
-
-		maps = append(maps, MapCache{
-			Name:     name,
-			Img:      imgRGBA,
-			Integral: integral,
-			OffsetX:  offsetX,
-			OffsetY:  offsetY,
-		})
+	gen := resourceGeneration.Load()
+	if i.pointerGen == gen && (i.pointer != nil || i.pointerErr != nil) {
+		return i.pointerErr
 	}
-
-	if len(maps) == 0 {
-		return nil, fmt.Errorf("no valid map images found in %s", mapDir)
+	i.pointerGen = gen
+	i.pointer, i.pointerErr = i.loadPointer(ctx)
+	if i.pointerErr != nil {
+		log.Error().Err(i.pointerErr).Msg("Failed to load pointer template")
+	} else {
+		log.Info().Msg("Pointer template image loaded")
 	}
+	return i.pointerErr
+}
 
-	return maps, nil
+// getPointer returns the loaded pointer template, or nil if it is not loaded
+func (i *MapTrackerInfer) getPointer() *image.RGBA {
+	i.pointerMu.Lock()
+	defer i.pointerMu.Unlock()
+	return i.pointer
 }
 
 // loadPointer loads the pointer template image
@@ -514,20 +506,17 @@ func (i *MapTrackerInfer) loadPointer(ctx *maa.Context) (*image.RGBA, error) {
 
 // inferLocation infers the player's location on the map.
 // Returns a raw result with mapName, x/y (map coordinates), conf, source, and elapsedTimeMs.
-func (i *MapTrackerInfer) inferLocation(screenImg *image.RGBA, mapNameRegex *regexp.Regexp, param *MapTrackerInferParam) *InferLocationRawResult {
+func (i *MapTrackerInfer) inferLocation(screenImg *image.RGBA, geo *MinimapGeometry, mapNameRegex *regexp.Regexp, param *MapTrackerInferParam, state *InferState, hint *mapRegionHint, nowMs int64) *InferLocationRawResult {
 	t0 := time.Now()
 
-	// Use cached scaled maps
+	// Maps returned by the store stay valid until released
 	scale := param.Precision
-	scaledMaps := i.getScaledMaps(scale)
-	if len(scaledMaps) == 0 {
-		log.Warn().Msg("No maps available for matching")
-		return nil
-	}
+	lease := mapStore.acquire()
+	defer lease.release()
 
-	// Crop and scale mini-map area from screen
-	miniMap := minicv.ImageCropSquareByRadius(screenImg, LOC_CENTER_X, LOC_CENTER_Y, LOC_RADIUS)
-	miniMap = minicv.ImageScale(miniMap, scale)
+	// Crop and scale mini-map area from screen, normalizing the HUD scale to the map scale
+	miniMap := minicv.ImageCropSquareByRadius(screenImg, geo.LocCenterX, geo.LocCenterY, geo.LocRadius)
+	miniMap = minicv.ImageScale(miniMap, scale*float64(LOC_RADIUS)/float64(geo.LocRadius))
 	miniMapBounds := miniMap.Bounds()
 	miniMapW, miniMapH := miniMapBounds.Dx(), miniMapBounds.Dy()
 
@@ -538,69 +527,120 @@ func (i *MapTrackerInfer) inferLocation(screenImg *image.RGBA, mapNameRegex *reg
 	}
 
 	// Time-series empirical optimization
-	// If the user is in a stable state (convinced location updated recently, no pending drifts),
-	// try to match the convinced map around the convinced location first.
-	globalInferState.mu.Lock()
-
-	isStable := globalInferState.convinced.mapName != "" &&
-		(time.Now().UnixMilli()-globalInferState.convincedLastHitTime < CONVINCED_VALID_TIME_MS) &&
-		globalInferState.pendingHitCount == 0
-	stableMapName := globalInferState.convinced.mapName
-	stableLocX := globalInferState.convinced.x
-	stableLocY := globalInferState.convinced.y
-
-	globalInferState.mu.Unlock()
+	// If the user is in a stable state (tracked location of the filter updated recently, no pending drifts),
+	// try to match the tracked map around the tracked location first.
+	state.mu.Lock()
+	stableMapName, stableLocX, stableLocY, _, isStable := state.trackedLocation(param.Filter, nowMs)
+	state.mu.Unlock()
 
 	// Try fast search if stable
 	if isStable && mapNameRegex.MatchString(stableMapName) {
-		for _, mapData := range scaledMaps {
-			if mapData.Name == stableMapName {
-				expectedCenterX := int(float64(stableLocX-mapData.OffsetX) * scale)
-				expectedCenterY := int(float64(stableLocY-mapData.OffsetY) * scale)
-				searchRadius := max(int(float64(CONVINCED_DISTANCE_THRESHOLD)*scale), 1)
-
-				matchX, matchY, matchVal := minicv.MatchTemplateInArea(
-					mapData.Img,
-					mapData.Integral,
-					miniMap,
-					miniStats,
-					expectedCenterX-searchRadius,
-					expectedCenterY-searchRadius,
-					searchRadius*2,
-					searchRadius*2,
-				)
-
-				if matchVal > param.Threshold {
-					// Fast search hit
-					bestX := int(float64(matchX+miniMapW/2)/scale) + mapData.OffsetX
-					bestY := int(float64(matchY+miniMapH/2)/scale) + mapData.OffsetY
-					elapsedTimeMs := time.Since(t0).Milliseconds()
-					log.Debug().Float64("conf", matchVal).
-						Str("map", stableMapName).
-						Int("X", bestX).
-						Int("Y", bestY).
-						Int64("elapsedTimeMs", elapsedTimeMs).
-						Msg("Internal fast search location inference completed")
-
-					return &InferLocationRawResult{
-						mapName:       mapData.Name,
-						x:             bestX,
-						y:             bestY,
-						conf:          matchVal,
-						source:        FAST_SEARCH_HIT,
-						elapsedTimeMs: elapsedTimeMs,
-					}
+		if mapData, ok := lease.get(stableMapName, scale, 1); ok {
+			expectedCenterX := int(float64(stableLocX-mapData.OffsetX) * scale)
+			expectedCenterY := int(float64(stableLocY-mapData.OffsetY) * scale)
+			searchRadius := max(int(float64(CONVINCED_DISTANCE_THRESHOLD)*scale), 1)
+
+			matchX, matchY, matchVal := minicv.MatchTemplateInArea(
+				mapData.Img,
+				mapData.Integral,
+				miniMap,
+				miniStats,
+				expectedCenterX-searchRadius,
+				expectedCenterY-searchRadius,
+				searchRadius*2,
+				searchRadius*2,
+			)
+
+			if matchVal > param.Threshold {
+				// Fast search hit
+				bestX := int(float64(matchX+miniMapW/2)/scale) + mapData.OffsetX
+				bestY := int(float64(matchY+miniMapH/2)/scale) + mapData.OffsetY
+				elapsedTimeMs := time.Since(t0).Milliseconds()
+				log.Debug().Float64("conf", matchVal).
+					Str("map", stableMapName).
+					Int("X", bestX).
+					Int("Y", bestY).
+					Int64("elapsedTimeMs", elapsedTimeMs).
+					Msg("Internal fast search location inference completed")
+
+				return &InferLocationRawResult{
+					mapName:       mapData.Name,
+					x:             bestX,
+					y:             bestY,
+					conf:          matchVal,
+					source:        FAST_SEARCH_HIT,
+					elapsedTimeMs: elapsedTimeMs,
 				}
-
-				// If fast search fails (low confidence), fallback to full search
-				log.Debug().Float64("conf", matchVal).Msg("Empirical fast search miss")
-				break
 			}
+
+			// If fast search fails (low confidence), fallback to full search
+			log.Debug().Float64("conf", matchVal).Msg("Empirical fast search miss")
 		}
 	} else {
 		log.Debug().Msg("Empirical fast search skipped, not in stable state or regex mismatch")
 	}
 
+	// Search the maps of the candidate region first, then the other maps only if nothing matches confidently,
+	// as a wrong region may still have a false match above the threshold
+	bestVal := -1.0
+	bestX, bestY := 0, 0
+	bestMapName := ""
+	triedCount := 0
+	search := func(match func(string) bool) {
+		val, x, y, name, tried := i.searchMaps(lease, match, miniMap, miniStats, scale, param.SearchMode)
+		triedCount += tried
+		if tried > 0 && val > bestVal {
+			bestVal, bestX, bestY, bestMapName = val, x, y, name
+		}
+	}
+
+	if region, source := candidateRegion(state, param.Filter, hint); region != "" {
+		search(func(name string) bool {
+			return mapRegion(name) == region && mapNameRegex.MatchString(name)
+		})
+		if bestVal > max(param.Threshold, REGION_HINT_MIN_CONF) {
+			log.Debug().Str("region", region).Str("source", source).
+				Float64("conf", bestVal).Msg("Candidate region search hit")
+		} else {
+			log.Debug().Str("region", region).Str("source", source).
+				Float64("conf", bestVal).Msg("Candidate region search miss, searching other maps")
+			search(func(name string) bool {
+				return mapRegion(name) != region && mapNameRegex.MatchString(name)
+			})
+		}
+	} else {
+		search(mapNameRegex.MatchString)
+	}
+
+	if triedCount == 0 {
+		log.Warn().Str("regex", mapNameRegex.String()).Msg("No maps matched the regex")
+	}
+	elapsedTimeMs := time.Since(t0).Milliseconds()
+
+	log.Debug().Int("triedMaps", triedCount).
+		Float64("bestConf", bestVal).
+		Str("bestMap", bestMapName).
+		Int("X", bestX).
+		Int("Y", bestY).
+		Int64("elapsedTimeMs", elapsedTimeMs).
+		Msg("Internal location inference completed")
+
+	return &InferLocationRawResult{
+		mapName:       bestMapName,
+		x:             bestX,
+		y:             bestY,
+		conf:          bestVal,
+		source:        FULL_SEARCH_HIT,
+		elapsedTimeMs: time.Since(t0).Milliseconds(),
+	}
+}
+
+// searchMaps matches the mini-map against all maps accepted by match with the given search mode,
+// loading the maps at the given scale on demand through the lease.
+// Returns (conf, x, y, mapName) of the best match in map coordinates, and the number of maps tried.
+func (i *MapTrackerInfer) searchMaps(lease *mapLease, match func(string) bool, miniMap *image.RGBA, miniStats minicv.StatsResult, scale float64, mode InferSearchMode) (float64, int, int, string, int) {
+	miniMapW, miniMapH := miniMap.Rect.Dx(), miniMap.Rect.Dy()
+
 	// Match against all maps in parallel
 	type mapResult struct {
 		val     float64
@@ -611,23 +651,26 @@ func (i *MapTrackerInfer) inferLocation(screenImg *image.RGBA, mapNameRegex *reg
 	bestVal := -1.0
 	bestX, bestY := 0, 0
 	bestMapName := ""
-	triedCount := 0
+
+	// Load the selected maps before matching
+	names := mapStore.list(match)
+	scaledMaps := make([]MapCache, 0, len(names))
+	for _, name := range names {
+		if m, ok := lease.get(name, scale, 1); ok {
+			scaledMaps = append(scaledMaps, m)
+		}
+	}
+	triedCount := len(scaledMaps)
 
 	// Special case: if there's only one map to check, run it directly to avoid goroutine overhead
 	var singleMapToTry *MapCache
-	for i := range scaledMaps {
-		if mapNameRegex.MatchString(scaledMaps[i].Name) {
-			triedCount++
-			if singleMapToTry == nil {
-				singleMapToTry = &scaledMaps[i]
-			} else {
-				singleMapToTry = nil // Found more than one
-				break
-			}
-		}
+	if triedCount == 1 {
+		singleMapToTry = &scaledMaps[0]
 	}
 
-	if singleMapToTry != nil {
+	if triedCount > 0 && mode == SEARCH_MODE_PYRAMID {
+		bestVal, bestX, bestY, bestMapName = i.searchPyramid(lease, scaledMaps, miniMap, miniStats, scale)
+	} else if singleMapToTry != nil {
 		matchX, matchY, matchVal := minicv.MatchTemplate(singleMapToTry.Img, singleMapToTry.Integral, miniMap, miniStats)
 		bestVal = matchVal
 		bestX = int(float64(matchX+miniMapW/2)/scale) + singleMapToTry.OffsetX
@@ -638,10 +681,6 @@ func (i *MapTrackerInfer) inferLocation(screenImg *image.RGBA, mapNameRegex *reg
 		var wg sync.WaitGroup
 
 		for _, mapData := range scaledMaps {
-			if !mapNameRegex.MatchString(mapData.Name) {
-				continue
-			}
-
 			wg.Add(1)
 			go func(m MapCache) {
 				defer wg.Done()
@@ -667,118 +706,161 @@ func (i *MapTrackerInfer) inferLocation(screenImg *image.RGBA, mapNameRegex *reg
 		}
 	}
 
-	if triedCount == 0 {
-		log.Warn().Str("regex", mapNameRegex.String()).Msg("No maps matched the regex")
+	return bestVal, bestX, bestY, bestMapName, triedCount
+}
+
+// searchPyramid matches the mini-map on coarse variants of the scaled maps first,
+// then refines the top candidates across all maps on the scaled maps.
+// Returns (conf, x, y, mapName) of the best match in map coordinates.
+func (i *MapTrackerInfer) searchPyramid(lease *mapLease, scaledMaps []MapCache, miniMap *image.RGBA, miniStats minicv.StatsResult, scale float64) (float64, int, int, string) {
+	miniMapW, miniMapH := miniMap.Rect.Dx(), miniMap.Rect.Dy()
+
+	// Map precision to the downsampling factor and the number of candidates to refine
+	factor := 1
+	for min(miniMapW, miniMapH)/(factor*2) >= PYRAMID_MIN_TEMPLATE_SIZE {
+		factor *= 2
 	}
-	elapsedTimeMs := time.Since(t0).Milliseconds()
+	topK := PYRAMID_MIN_TOP_K + int(math.Round(scale*float64(PYRAMID_MAX_TOP_K-PYRAMID_MIN_TOP_K)))
 
-	log.Debug().Int("triedMaps", triedCount).
-		Float64("bestConf", bestVal).
-		Str("bestMap", bestMapName).
-		Int("X", bestX).
-		Int("Y", bestY).
-		Int64("elapsedTimeMs", elapsedTimeMs).
-		Msg("Internal location inference completed")
+	coarseMaps := make([]MapCache, len(scaledMaps))
+	for idx, m := range scaledMaps {
+		coarseMaps[idx], _ = lease.get(m.Name, scale, factor)
+	}
+	coarseMini := minicv.ImageDownsample(miniMap, factor)
+	coarseStats := minicv.GetImageStats(coarseMini)
+	if coarseStats.Std < 1e-6 {
+		return -1.0, 0, 0, ""
+	}
 
-	return &InferLocationRawResult{
-		mapName:       bestMapName,
-		x:             bestX,
-		y:             bestY,
-		conf:          bestVal,
-		source:        FULL_SEARCH_HIT,
-		elapsedTimeMs: time.Since(t0).Milliseconds(),
+	// Coarse search on all maps in parallel
+	type candidate struct {
+		mapIdx int
+		minicv.MatchCandidate
 	}
-}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	candidates := make([]candidate, 0, topK*len(coarseMaps))
+	for idx := range coarseMaps {
+		if coarseMaps[idx].Img == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			m := &coarseMaps[idx]
+			found := minicv.MatchTemplateTopK(m.Img, m.Integral, coarseMini, coarseStats, topK, PYRAMID_NMS_DISTANCE)
+			mu.Lock()
+			for _, c := range found {
+				candidates = append(candidates, candidate{idx, c})
+			}
+			mu.Unlock()
+		}(idx)
+	}
+	wg.Wait()
 
-// getScaledMaps returns cached scaled maps or recomputes them
-func (i *MapTrackerInfer) getScaledMaps(scale float64) []MapCache {
-	i.scaledMu.Lock()
-	defer i.scaledMu.Unlock()
-
-	if i.scaledScale == scale && len(i.scaledMaps) > 0 {
-		return i.scaledMaps
-	}
-
-	log.Info().Float64("scale", scale).Msg("Recomputing scaled maps cache")
-	newScaled := make([]MapCache, 0, len(i.maps))
-	for _, m := range i.maps {
-		sImg := minicv.ImageScale(m.Img, scale)
-		newScaled = append(newScaled, MapCache{
-			Name:     m.Name,
-			Img:      sImg,
-			Integral: minicv.GetIntegralArray(sImg),
-			OffsetX:  m.OffsetX,
-			OffsetY:  m.OffsetY,
-		})
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].Score > candidates[b].Score
+	})
+	if len(candidates) > topK {
+		candidates = candidates[:topK]
+	}
+
+	// Refine the top candidates on the scaled maps
+	bestVal := -1.0
+	bestX, bestY := 0, 0
+	bestMapName := ""
+	for _, c := range candidates {
+		m := &scaledMaps[c.mapIdx]
+		matchX, matchY, matchVal := minicv.RefineMatch(m.Img, m.Integral, miniMap, miniStats, c.MatchCandidate, factor)
+		if matchVal > bestVal {
+			bestVal = matchVal
+			bestX = int(float64(matchX+miniMapW/2)/scale) + m.OffsetX
+			bestY = int(float64(matchY+miniMapH/2)/scale) + m.OffsetY
+			bestMapName = m.Name
+		}
 	}
-	i.scaledScale = scale
-	i.scaledMaps = newScaled
-	return i.scaledMaps
+
+	log.Debug().Int("factor", factor).Int("topK", topK).
+		Int("candidatesCount", len(candidates)).
+		Msg("Pyramid search completed")
+	return bestVal, bestX, bestY, bestMapName
 }
 
-// inferRotation infers the player's rotation angle
-// Returns (angle, confidence)
-func (i *MapTrackerInfer) inferRotation(screenImg *image.RGBA, rotStep int) *InferRotationRawResult {
+// inferRotation infers the player's rotation angle from the pointer.
+// The best discrete angle is refined by a parabolic fit on the match scores around it,
+// and the refined angle is kept only if its own match score is not worse.
+func (i *MapTrackerInfer) inferRotation(screenImg *image.RGBA, geo *MinimapGeometry, rotStep int) *InferRotationRawResult {
 	t0 := time.Now()
 
-	if i.pointer == nil {
+	pointer := i.getPointer()
+	if pointer == nil {
 		return nil
 	}
 
-	// Crop pointer area from screen
-	patch := minicv.ImageCropSquareByRadius(screenImg, ROT_CENTER_X, ROT_CENTER_Y, ROT_RADIUS)
+	// Crop pointer area from screen, normalizing the HUD scale to the pointer template scale
+	patch := minicv.ImageCropSquareByRadius(screenImg, geo.RotCenterX, geo.RotCenterY, geo.RotRadius)
+	patch = minicv.ImageScale(patch, float64(ROT_RADIUS)/float64(geo.RotRadius))
 
 	// Precompute needle (pointer) statistics
-	pointerStats := minicv.GetImageStats(i.pointer)
+	pointerStats := minicv.GetImageStats(pointer)
 	if pointerStats.Std < 1e-6 {
 		return nil
 	}
 
-	// Try all rotation angles in parallel
-	type result struct {
-		angle int
-		conf  float64
+	// matchAt rotates the patch and matches against pointer template
+	matchAt := func(angle float64) float64 {
+		rotatedRGBA := minicv.ImageRotateBilinear(patch, angle)
+		integral := minicv.GetIntegralArray(rotatedRGBA)
+		_, _, matchVal := minicv.MatchTemplate(rotatedRGBA, integral, pointer, pointerStats)
+		return matchVal
 	}
 
-	resChan := make(chan result, 360/rotStep+1)
-	var wg sync.WaitGroup
-
+	// Try all rotation angles in parallel
+	angles := make([]int, 0, 360/rotStep+1)
 	for angle := 0; angle < 360; angle += rotStep {
+		angles = append(angles, angle)
+	}
+	scores := make([]float64, len(angles))
+	var wg sync.WaitGroup
+	for idx, a := range angles {
 		wg.Add(1)
-		go func(a int) {
+		go func() {
 			defer wg.Done()
-			// Rotate the patch
-			rotatedRGBA := minicv.ImageRotate(patch, float64(a))
-
-			// Match against pointer template
-			integral := minicv.GetIntegralArray(rotatedRGBA)
-			_, _, matchVal := minicv.MatchTemplate(rotatedRGBA, integral, i.pointer, pointerStats)
-
-			resChan <- result{a, matchVal}
-		}(angle)
+			scores[idx] = matchAt(float64(a))
+		}()
 	}
+	wg.Wait()
 
-	go func() {
-		wg.Wait()
-		close(resChan)
-	}()
+	bestIdx := 0
+	for idx := range scores {
+		if scores[idx] > scores[bestIdx] {
+			bestIdx = idx
+		}
+	}
+	bestAngle, maxVal := float64(angles[bestIdx]), scores[bestIdx]
 
-	bestAngle := 0
-	maxVal := -1.0
-	for res := range resChan {
-		if res.conf > maxVal {
-			maxVal = res.conf
-			bestAngle = res.angle
+	// Refine around the peak, the neighbors are matched directly if the steps do not wrap evenly
+	prevVal, nextVal := 0.0, 0.0
+	if 360%rotStep == 0 {
+		prevVal = scores[(bestIdx+len(scores)-1)%len(scores)]
+		nextVal = scores[(bestIdx+1)%len(scores)]
+	} else {
+		prevVal, nextVal = matchAt(bestAngle-float64(rotStep)), matchAt(bestAngle+float64(rotStep))
+	}
+	if offset, ok := parabolicPeakOffset(prevVal, maxVal, nextVal); ok && math.Abs(offset) > 0.05 {
+		refinedAngle := bestAngle + offset*float64(rotStep)
+		if refinedVal := matchAt(refinedAngle); refinedVal >= maxVal {
+			bestAngle, maxVal = refinedAngle, refinedVal
 		}
 	}
 
 	// Convert to clockwise angle
-	bestAngle = (360 - bestAngle) % 360
+	bestAngle = normalizeRotation(-bestAngle)
 	elapsedTimeMs := time.Since(t0).Milliseconds()
 
 	log.Debug().
 		Float64("bestConf", maxVal).
-		Int("bestAngle", bestAngle).
+		Float64("bestAngle", bestAngle).
 		Int64("elapsedTimeMs", elapsedTimeMs).
 		Msg("Internal rotation inference completed")
 
@@ -788,3 +870,106 @@ func (i *MapTrackerInfer) inferRotation(screenImg *image.RGBA, rotStep int) *Inf
 		elapsedTimeMs: time.Since(t0).Milliseconds(),
 	}
 }
+
+// inferCameraRotation infers the camera heading from the view cone on the mini-map.
+// The view cone is brighter than its surroundings, so the angular brightness profile
+// of each ring around the pointer is correlated with a cone-shaped window.
+// The cone covers all rings while the map texture does not, so the median over rings is taken.
+// The confidence is the contrast of the best window, relative to that of an ideal cone profile.
+func (i *MapTrackerInfer) inferCameraRotation(screenImg *image.RGBA, geo *MinimapGeometry) *InferRotationRawResult {
+	t0 := time.Now()
+
+	// Angular brightness profiles of the rings, 0 degrees is North, increasing clockwise
+	const bins = 360 / CAMERA_CONE_BIN_DEG
+	const rings = CAMERA_CONE_RINGS
+	var sums [rings][bins]float64
+	var counts [rings][bins]int
+	rIn, rOut := float64(geo.RotRadius)*CAMERA_CONE_INNER_RATIO, float64(geo.LocRadius)
+	b := screenImg.Rect
+	ipx, is := screenImg.Pix, screenImg.Stride
+	for y := max(b.Min.Y, geo.LocCenterY-geo.LocRadius); y <= min(b.Max.Y-1, geo.LocCenterY+geo.LocRadius); y++ {
+		for x := max(b.Min.X, geo.LocCenterX-geo.LocRadius); x <= min(b.Max.X-1, geo.LocCenterX+geo.LocRadius); x++ {
+			dx, dy := float64(x-geo.LocCenterX), float64(y-geo.LocCenterY)
+			d := math.Hypot(dx, dy)
+			if d < rIn || d >= rOut {
+				continue
+			}
+			ring := int((d - rIn) / (rOut - rIn) * rings)
+			bin := int(normalizeRotation(math.Atan2(dx, -dy)*180.0/math.Pi)) / CAMERA_CONE_BIN_DEG
+			off := (y-b.Min.Y)*is + (x-b.Min.X)*4
+			sums[ring][bin] += float64(ipx[off]) + float64(ipx[off+1]) + float64(ipx[off+2])
+			counts[ring][bin]++
+		}
+	}
+
+	// Contrast of the window centered at each bin against the rest, normalized by the profile std of each ring
+	const width = CAMERA_CONE_WIDTH_DEG / CAMERA_CONE_BIN_DEG
+	var contrasts [rings][bins]float64
+	for r := range rings {
+		var profile [bins]float64
+		mean := 0.0
+		for k := range bins {
+			if counts[r][k] == 0 {
+				return nil
+			}
+			profile[k] = sums[r][k] / float64(counts[r][k]) / 3
+			mean += profile[k] / bins
+		}
+		std := 0.0
+		for k := range bins {
+			std += (profile[k] - mean) * (profile[k] - mean) / bins
+		}
+		std = math.Sqrt(std)
+		if std < 1e-6 {
+			continue
+		}
+		for c := range bins {
+			inside := 0.0
+			for k := c - width/2; k < c-width/2+width; k++ {
+				inside += profile[(k+bins)%bins]
+			}
+			outsideMean := (mean*bins - inside) / (bins - width)
+			contrasts[r][c] = (inside/width - outsideMean) / std
+		}
+	}
+	var scores [bins]float64
+	for c := range bins {
+		var values [rings]float64
+		for r := range rings {
+			values[r] = contrasts[r][c]
+		}
+		slices.Sort(values[:])
+		scores[c] = values[rings/2]
+	}
+
+	bestBin := 0
+	for c := range bins {
+		if scores[c] > scores[bestBin] {
+			bestBin = c
+		}
+	}
+	bestAngle := float64(bestBin) * CAMERA_CONE_BIN_DEG
+	if offset, ok := parabolicPeakOffset(scores[(bestBin+bins-1)%bins], scores[bestBin], scores[(bestBin+1)%bins]); ok {
+		bestAngle += offset * CAMERA_CONE_BIN_DEG
+	}
+
+	// An ideal cone profile (a box of the cone width) has contrast / std = 1 / sqrt(f * (1 - f))
+	f := float64(width) / bins
+	conf := max(0, min(1, scores[bestBin]*math.Sqrt(f*(1-f))))
+
+	return &InferRotationRawResult{
+		rot:           normalizeRotation(bestAngle),
+		conf:          conf,
+		elapsedTimeMs: time.Since(t0).Milliseconds(),
+	}
+}
+
+// parabolicPeakOffset fits a parabola through three equally spaced samples around a peak,
+// returns the offset of the vertex from the center sample in units of the spacing, within [-0.5, 0.5]
+func parabolicPeakOffset(prev, center, next float64) (float64, bool) {
+	denom := prev - 2*center + next
+	if denom >= -1e-12 {
+		return 0, false
+	}
+	return max(-0.5, min(0.5, 0.5*(prev-next)/denom)), true
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LintIssue represents a problem found in the parameters of a MapTracker node
+type LintIssue struct {
+	Severity string `json:"severity"`          // "error" or "warning"
+	Code     string `json:"code"`              // Stable identifier of the kind of problem
+	Message  string `json:"message"`           // Human-readable description
+	Segment  *int   `json:"segment,omitempty"` // Segment index of the related point
+	Index    *int   `json:"index,omitempty"`   // Index of the related point or condition
+}
+
+const (
+	LINT_ERROR   = "error"
+	LINT_WARNING = "warning"
+)
+
+// lintRange represents the range a movement parameter is expected to be in
+type lintRange struct {
+	name     string
+	value    func(p *MapTrackerMoveParam) float64
+	min, max float64
+}
+
+// lintRanges are the ranges the movement defaults are tuned for, values outside are likely mistakes
+var lintRanges = []lintRange{
+	{"arrival_threshold", func(p *MapTrackerMoveParam) float64 { return p.ArrivalThreshold }, 1.0, 10.0},
+	{"arrival_timeout", func(p *MapTrackerMoveParam) float64 { return float64(p.ArrivalTimeout) }, 5000, 300000},
+	{"rotation_lower_threshold", func(p *MapTrackerMoveParam) float64 { return p.RotationLowerThreshold }, 1.0, 30.0},
+	{"rotation_upper_threshold", func(p *MapTrackerMoveParam) float64 { return p.RotationUpperThreshold }, 20.0, 120.0},
+	{"sprint_threshold", func(p *MapTrackerMoveParam) float64 { return p.SprintThreshold }, 5.0, 100.0},
+	{"stuck_threshold", func(p *MapTrackerMoveParam) float64 { return float64(p.StuckThreshold) }, 500, 10000},
+	{"stuck_timeout", func(p *MapTrackerMoveParam) float64 { return float64(p.StuckTimeout) }, 2000, 60000},
+}
+
+// Linter checks MapTracker node parameters against the map resources
+type Linter struct {
+	mapSizes map[string]image.Point
+	bboxes   map[string][]int
+}
+
+// NewLinter creates a Linter loading map images, map bbox, routes and regions from resourceDir
+func NewLinter(resourceDir string) (*Linter, error) {
+	abs, err := filepath.Abs(resourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource directory: %w", err)
+	}
+	resourcePath.Store(abs)
+
+	mapDir := filepath.Join(abs, MAP_DIR)
+	entries, err := os.ReadDir(mapDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map directory: %w", err)
+	}
+
+	l := &Linter{mapSizes: make(map[string]image.Point), bboxes: make(map[string][]int)}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".png") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(mapDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode map image %s: %w", entry.Name(), err)
+		}
+		l.mapSizes[strings.TrimSuffix(entry.Name(), ".png")] = image.Pt(cfg.Width, cfg.Height)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(mapDir, "map_bbox.json")); err == nil {
+		if err := json.Unmarshal(data, &l.bboxes); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal map_bbox.json: %w", err)
+		}
+	}
+
+	if _, err := getRouteStore(); err != nil {
+		return nil, fmt.Errorf("failed to load routes: %w", err)
+	}
+	if _, err := getRegionStore(); err != nil {
+		return nil, fmt.Errorf("failed to load regions: %w", err)
+	}
+	return l, nil
+}
+
+// LintMove checks the custom_action_param of a MapTrackerMove node
+func (l *Linter) LintMove(paramStr string) []LintIssue {
+	param, err := (&MapTrackerMove{}).parseParam(paramStr)
+	if err != nil {
+		return []LintIssue{{Severity: LINT_ERROR, Code: "invalid_param", Message: err.Error()}}
+	}
+
+	var issues []LintIssue
+	for _, r := range lintRanges {
+		if v := r.value(param); v < r.min || v > r.max {
+			issues = append(issues, LintIssue{
+				Severity: LINT_WARNING,
+				Code:     "param_out_of_range",
+				Message:  fmt.Sprintf("%s is %g, expected in range [%g, %g]", r.name, v, r.min, r.max),
+			})
+		}
+	}
+	if param.RotationLowerThreshold >= param.RotationUpperThreshold {
+		issues = append(issues, LintIssue{
+			Severity: LINT_ERROR,
+			Code:     "param_inconsistent",
+			Message:  "rotation_lower_threshold must be less than rotation_upper_threshold",
+		})
+	}
+	if chainTimeout := stuckChainTimeout(param.StuckThreshold, param.StuckRecovery); param.StuckTimeout < chainTimeout {
+		issues = append(issues, LintIssue{
+			Severity: LINT_ERROR,
+			Code:     "stuck_timeout_too_short",
+			Message: fmt.Sprintf("stuck_timeout is %d, the stuck_recovery chain needs at least %d to finish",
+				param.StuckTimeout, chainTimeout),
+		})
+	}
+
+	// The farthest distance the player can walk before the arrival timeout
+	maxReachable := float64(param.ArrivalTimeout) / 1000 * MovementWalk.Speed
+	for si, seg := range param.Segments {
+		if !l.checkMap(seg.MapName, &issues, &si, nil) {
+			continue
+		}
+		for i, p := range seg.Path {
+			l.checkPoint(seg.MapName, p.X, p.Y, &issues, &si, &i)
+			if i == 0 {
+				continue
+			}
+			prev := seg.Path[i-1]
+			dist := math.Hypot(float64(p.X-prev.X), float64(p.Y-prev.Y))
+			switch {
+			case dist == 0:
+				issues = append(issues, LintIssue{
+					Severity: LINT_WARNING, Code: "duplicate_point", Segment: &si, Index: &i,
+					Message: fmt.Sprintf("point [%d, %d] duplicates the previous point", p.X, p.Y),
+				})
+			case dist < param.ArrivalThreshold:
+				issues = append(issues, LintIssue{
+					Severity: LINT_WARNING, Code: "points_too_close", Segment: &si, Index: &i,
+					Message: fmt.Sprintf("point [%d, %d] is %.1f px from the previous point, within arrival_threshold", p.X, p.Y, dist),
+				})
+			case dist > maxReachable:
+				issues = append(issues, LintIssue{
+					Severity: LINT_ERROR, Code: "points_unreachable", Segment: &si, Index: &i,
+					Message: fmt.Sprintf("point [%d, %d] is %.1f px from the previous point, cannot be reached before arrival_timeout", p.X, p.Y, dist),
+				})
+			case dist > LINT_MAX_POINT_DISTANCE:
+				issues = append(issues, LintIssue{
+					Severity: LINT_WARNING, Code: "points_too_far", Segment: &si, Index: &i,
+					Message: fmt.Sprintf("point [%d, %d] is %.1f px from the previous point, more than %g px", p.X, p.Y, dist, LINT_MAX_POINT_DISTANCE),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// LintAssertLocation checks the custom_recognition_param of a MapTrackerAssertLocation node
+func (l *Linter) LintAssertLocation(paramStr string) []LintIssue {
+	param, err := (&MapTrackerAssertLocation{}).parseParam(paramStr)
+	if err != nil {
+		return []LintIssue{{Severity: LINT_ERROR, Code: "invalid_param", Message: err.Error()}}
+	}
+
+	var issues []LintIssue
+	for i := range param.Expected {
+		l.checkCondition(&param.Expected[i], &issues, i)
+	}
+	return issues
+}
+
+// checkCondition checks the maps and shapes in a condition recursively, issues are reported at top-level index
+func (l *Linter) checkCondition(c *LocationCondition, issues *[]LintIssue, index int) {
+	switch {
+	case c.Target != nil:
+		t := c.Target
+		if l.checkMap(c.MapName, issues, nil, &index) {
+			l.checkPoint(c.MapName, t[0], t[1], issues, nil, &index)
+			l.checkPoint(c.MapName, t[0]+t[2]-1, t[1]+t[3]-1, issues, nil, &index)
+		}
+	case c.Circle != nil:
+		if l.checkMap(c.MapName, issues, nil, &index) {
+			l.checkPoint(c.MapName, c.Circle[0], c.Circle[1], issues, nil, &index)
+		}
+	case c.Polygon != nil:
+		if l.checkMap(c.MapName, issues, nil, &index) {
+			for _, p := range c.Polygon {
+				l.checkPoint(c.MapName, p[0], p[1], issues, nil, &index)
+			}
+		}
+	}
+	for i := range c.All {
+		l.checkCondition(&c.All[i], issues, index)
+	}
+	for i := range c.Any {
+		l.checkCondition(&c.Any[i], issues, index)
+	}
+	if c.Not != nil {
+		l.checkCondition(c.Not, issues, index)
+	}
+}
+
+// checkMap reports an unknown map, returns false if the map is unknown
+func (l *Linter) checkMap(mapName string, issues *[]LintIssue, segment, index *int) bool {
+	if _, ok := l.mapSizes[mapName]; ok {
+		return true
+	}
+	*issues = append(*issues, LintIssue{
+		Severity: LINT_ERROR, Code: "unknown_map", Segment: segment, Index: index,
+		Message: fmt.Sprintf("map %q is not found in the map directory", mapName),
+	})
+	return false
+}
+
+// checkPoint reports a point outside the map image or outside the map bbox
+func (l *Linter) checkPoint(mapName string, x, y int, issues *[]LintIssue, segment, index *int) {
+	size := l.mapSizes[mapName]
+	if x < 0 || y < 0 || x >= size.X || y >= size.Y {
+		*issues = append(*issues, LintIssue{
+			Severity: LINT_ERROR, Code: "outside_map", Segment: segment, Index: index,
+			Message: fmt.Sprintf("point [%d, %d] is outside the map image of size %dx%d", x, y, size.X, size.Y),
+		})
+		return
+	}
+	if r, ok := l.bboxes[mapName]; ok && len(r) == 4 {
+		if x < r[0] || y < r[1] || x >= r[2] || y >= r[3] {
+			*issues = append(*issues, LintIssue{
+				Severity: LINT_WARNING, Code: "outside_bbox", Segment: segment, Index: index,
+				Message: fmt.Sprintf("point [%d, %d] is outside the map bbox %v", x, y, r),
+			})
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/rs/zerolog/log"
+)
+
+// Map data cache file layout (native byte order):
+//
+//	header (64 bytes): magic, version, width, height, offsetX, offsetY, reserved
+//	pixels: width*height*4 bytes of RGBA, padded to 8 bytes
+//	integral sums: (width+1)*(height+1) float64
+//	integral squared sums: (width+1)*(height+1) float64
+const (
+	mapCacheMagic      = 0x4d54_4d43 // "MTMC"
+	mapCacheHeaderSize = 64
+)
+
+// mapSourceID derives the source ID of a map from its file content and crop rectangle
+func mapSourceID(data []byte, rect image.Rectangle) string {
+	fileSum := sha256.Sum256(data)
+	sum := sha256.Sum256(fmt.Appendf(nil, "v%d|%x|%v", MAP_CACHE_VERSION, fileSum, rect))
+	return hex.EncodeToString(sum[:8])
+}
+
+func mapCachePath(name, srcID string, scale float64) string {
+	return filepath.Join(MAP_CACHE_DIR, fmt.Sprintf("%s_%s_s%g.bin", name, srcID, scale))
+}
+
+// loadMapCacheFile memory-maps a cached map, returns false if absent or invalid
+func loadMapCacheFile(name, srcID string, scale float64) (MapCache, bool) {
+	path := mapCachePath(name, srcID, scale)
+	data, err := mmapFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to map map cache file")
+		}
+		return MapCache{}, false
+	}
+
+	ne := binary.NativeEndian
+	if len(data) < mapCacheHeaderSize || ne.Uint32(data[0:]) != mapCacheMagic || ne.Uint32(data[4:]) != MAP_CACHE_VERSION {
+		log.Warn().Str("path", path).Msg("Invalid map cache file header, ignoring")
+		munmapFile(data)
+		return MapCache{}, false
+	}
+	w, h := int(int32(ne.Uint32(data[8:]))), int(int32(ne.Uint32(data[12:])))
+	offsetX, offsetY := int(int32(ne.Uint32(data[16:]))), int(int32(ne.Uint32(data[20:])))
+
+	pixSize := w * h * 4
+	pixPadded := (pixSize + 7) &^ 7
+	intLen := (w + 1) * (h + 1)
+	if w <= 0 || h <= 0 || len(data) != mapCacheHeaderSize+pixPadded+intLen*8*2 {
+		log.Warn().Str("path", path).Msg("Invalid map cache file size, ignoring")
+		munmapFile(data)
+		return MapCache{}, false
+	}
+
+	body := data[mapCacheHeaderSize:]
+	sumBytes := body[pixPadded : pixPadded+intLen*8]
+	sumSqBytes := body[pixPadded+intLen*8:]
+	return MapCache{
+		Name: name,
+		Img: &image.RGBA{
+			Pix:    body[:pixSize:pixSize],
+			Stride: w * 4,
+			Rect:   image.Rect(0, 0, w, h),
+		},
+		Integral: minicv.IntegralArray{
+			Sum:   unsafe.Slice((*float64)(unsafe.Pointer(&sumBytes[0])), intLen),
+			SumSq: unsafe.Slice((*float64)(unsafe.Pointer(&sumSqBytes[0])), intLen),
+			W:     w,
+			H:     h,
+		},
+		OffsetX: offsetX,
+		OffsetY: offsetY,
+		mapping: data,
+	}, true
+}
+
+// storeMapCacheFile writes a map to the cache directory atomically
+func storeMapCacheFile(m *MapCache, srcID string, scale float64) error {
+	w, h := m.Img.Rect.Dx(), m.Img.Rect.Dy()
+	intLen := (w + 1) * (h + 1)
+	if len(m.Integral.Sum) != intLen || len(m.Integral.SumSq) != intLen {
+		return fmt.Errorf("integral array size mismatch")
+	}
+
+	if err := os.MkdirAll(MAP_CACHE_DIR, 0755); err != nil {
+		return err
+	}
+	path := mapCachePath(m.Name, srcID, scale)
+	tmp, err := os.CreateTemp(MAP_CACHE_DIR, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	ne := binary.NativeEndian
+	header := make([]byte, mapCacheHeaderSize)
+	ne.PutUint32(header[0:], mapCacheMagic)
+	ne.PutUint32(header[4:], MAP_CACHE_VERSION)
+	ne.PutUint32(header[8:], uint32(int32(w)))
+	ne.PutUint32(header[12:], uint32(int32(h)))
+	ne.PutUint32(header[16:], uint32(int32(m.OffsetX)))
+	ne.PutUint32(header[20:], uint32(int32(m.OffsetY)))
+
+	// Pixels are written row by row, since the image may be a sub-image with a larger stride
+	pixSize := w * h * 4
+	pix := make([]byte, (pixSize+7)&^7)
+	for y := range h {
+		off := m.Img.PixOffset(m.Img.Rect.Min.X, m.Img.Rect.Min.Y+y)
+		copy(pix[y*w*4:(y+1)*w*4], m.Img.Pix[off:off+w*4])
+	}
+
+	chunks := [][]byte{
+		header,
+		pix,
+		unsafe.Slice((*byte)(unsafe.Pointer(&m.Integral.Sum[0])), intLen*8),
+		unsafe.Slice((*byte)(unsafe.Pointer(&m.Integral.SumSq[0])), intLen*8),
+	}
+	for _, c := range chunks {
+		if _, err := tmp.Write(c); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// pruneMapCacheFiles removes cache files of the map that were built from other sources
+func pruneMapCacheFiles(name, srcID string) {
+	pattern := filepath.Join(MAP_CACHE_DIR, name+"_"+strings.Repeat("?", len(srcID))+"_s*.bin")
+	paths, _ := filepath.Glob(pattern)
+	for _, path := range paths {
+		if !strings.HasPrefix(filepath.Base(path), name+"_"+srcID+"_") {
+			if err := os.Remove(path); err == nil {
+				log.Debug().Str("path", path).Msg("Stale map cache file removed")
+			}
+		}
+	}
+}
+
+// getOrBuildMapCache returns the cached map of the source and scale, or builds and caches it
+func getOrBuildMapCache(name, srcID string, scale float64, build func() (MapCache, bool)) (MapCache, bool) {
+	if m, ok := loadMapCacheFile(name, srcID, scale); ok {
+		return m, true
+	}
+	m, ok := build()
+	if !ok {
+		return m, false
+	}
+	if err := storeMapCacheFile(&m, srcID, scale); err != nil {
+		log.Warn().Err(err).Str("map", name).Msg("Failed to store map cache file")
+	} else if scale == 1.0 {
+		pruneMapCacheFiles(name, srcID)
+	}
+	return m, true
+}
@@ -0,0 +1,426 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/rs/zerolog/log"
+)
+
+// resourceGeneration is bumped whenever a resource is loaded,
+// so that caches built from the previous resource files are invalidated
+var resourceGeneration atomic.Uint64
+
+// mapStoreKey identifies a variant of a map
+type mapStoreKey struct {
+	name   string
+	scale  float64
+	factor int // Extra downsampling factor on top of the scale for pyramid search, 1 for none
+}
+
+// mapStoreEntry is a loaded map variant in the LRU list
+type mapStoreEntry struct {
+	key     mapStoreKey
+	m       MapCache
+	bytes   int64
+	refs    int  // Leases holding the variant
+	evicted bool // Whether the variant left the LRU list, its memory mapping is released with the last lease
+}
+
+// mapStoreLoad is a variant being loaded, which other requests for the same variant wait for
+type mapStoreLoad struct {
+	done chan struct{}
+}
+
+// mapLease holds the variants returned by the store to one user, which stay valid until it is released
+type mapLease struct {
+	s    *MapStore
+	held []*mapStoreEntry
+}
+
+// mapSource is a map image file in the resource, which is loaded on demand
+type mapSource struct {
+	path  string
+	rect  image.Rectangle // Crop rectangle from map bbox, empty for no crop
+	srcID string          // Source ID of the file content, empty until the file is read
+	bad   bool            // Whether the file failed to load, skipped until the next scan
+}
+
+// MapStore loads map variants on demand and keeps them in an LRU list within a memory budget.
+// It is shared by all MapTrackerInfer instances, and rescans the map directory after the resource is reloaded.
+type MapStore struct {
+	mu sync.Mutex
+
+	// Map catalog of the current resource generation
+	gen     uint64
+	scanned bool
+	scanErr error
+	sources map[string]*mapSource
+	names   []string
+
+	// Loaded variants, the front is the most recently used
+	lru     *list.List
+	entries map[mapStoreKey]*list.Element
+	used    int64
+	budget  int64
+
+	// Variants being loaded outside the lock
+	loading map[mapStoreKey]*mapStoreLoad
+}
+
+var mapStore = &MapStore{
+	lru:     list.New(),
+	entries: make(map[mapStoreKey]*list.Element),
+	loading: make(map[mapStoreKey]*mapStoreLoad),
+	budget:  defaultMapCacheBudget() << 20,
+}
+
+// defaultMapCacheBudget returns the memory budget of loaded map variants in megabytes,
+// from the environment variable if it is set, or the default
+func defaultMapCacheBudget() int64 {
+	value := os.Getenv(MAP_CACHE_BUDGET_ENV)
+	if value == "" {
+		return MAP_CACHE_DEFAULT_BUDGET_MB
+	}
+	mb, err := strconv.Atoi(value)
+	if err != nil || mb < 0 {
+		log.Warn().Str("env", MAP_CACHE_BUDGET_ENV).Str("value", value).
+			Int("defaultMB", MAP_CACHE_DEFAULT_BUDGET_MB).Msg("Invalid map cache budget, using default")
+		return MAP_CACHE_DEFAULT_BUDGET_MB
+	}
+	log.Info().Int("budgetMB", mb).Msg("Map cache budget set by environment")
+	return int64(mb)
+}
+
+// SetMapCacheBudget sets the memory budget of loaded map variants in megabytes
+func SetMapCacheBudget(mb int) {
+	mapStore.mu.Lock()
+	defer mapStore.mu.Unlock()
+
+	mapStore.budget = int64(max(mb, 0)) << 20
+	mapStore.evictLocked(nil)
+}
+
+// acquire starts a lease, the variants returned by it stay valid until it is released
+func (s *MapStore) acquire() *mapLease {
+	return &mapLease{s: s}
+}
+
+// get returns the variant of the map at the given scale and extra downsampling factor, loading it if needed.
+// The variant must not be used after the lease is released.
+func (l *mapLease) get(name string, scale float64, factor int) (MapCache, bool) {
+	e, ok := l.s.getEntry(mapStoreKey{name, scale, max(factor, 1)})
+	if !ok {
+		return MapCache{}, false
+	}
+	l.held = append(l.held, e)
+	return e.m, true
+}
+
+// release ends the lease, releasing the memory mapping of each variant evicted meanwhile once no lease holds it
+func (l *mapLease) release() {
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+
+	for _, e := range l.held {
+		l.s.unrefLocked(e)
+	}
+	l.held = nil
+}
+
+// scan ensures the map catalog of the current resource is scanned, returns an error if there is no map
+func (s *MapStore) scan() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.scanLocked()
+}
+
+// list returns the sorted names of maps accepted by match (nil for all)
+func (s *MapStore) list(match func(string) bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.scanLocked() != nil {
+		return nil
+	}
+	names := make([]string, 0, len(s.names))
+	for _, name := range s.names {
+		if match == nil || match(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// scanLocked scans the map directory if the resource has been reloaded since the last scan
+func (s *MapStore) scanLocked() error {
+	gen := resourceGeneration.Load()
+	if s.scanned && s.gen == gen {
+		return s.scanErr
+	}
+	s.gen = gen
+	s.scanned = true
+	s.sources, s.scanErr = scanMapSources()
+	s.names = s.names[:0]
+	for name := range s.sources {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+
+	// Variants of maps no longer present are dropped, the others are verified by source ID when used
+	for key, elem := range s.entries {
+		if _, ok := s.sources[key.name]; !ok {
+			s.removeLocked(elem)
+		}
+	}
+
+	if s.scanErr != nil {
+		log.Error().Err(s.scanErr).Msg("Failed to scan maps")
+	} else {
+		log.Info().Int("mapsCount", len(s.names)).Msg("Map catalog scanned")
+	}
+	return s.scanErr
+}
+
+// getEntry returns the variant from the LRU list if its source is unchanged, or loads it, holding one reference.
+// Reading the source and loading run outside the lock, and only once at a time for each variant.
+func (s *MapStore) getEntry(key mapStoreKey) (*mapStoreEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.scanLocked() != nil {
+			return nil, false
+		}
+		src := s.sources[key.name]
+		if src == nil || src.bad {
+			return nil, false
+		}
+		if src.srcID == "" {
+			s.mu.Unlock()
+			data, err := os.ReadFile(src.path)
+			s.mu.Lock()
+			if err != nil {
+				log.Warn().Err(err).Str("path", src.path).Msg("Failed to read map image")
+				src.bad = true
+				return nil, false
+			}
+			if src.srcID == "" {
+				src.srcID = mapSourceID(data, src.rect)
+			}
+			continue
+		}
+
+		if elem, ok := s.entries[key]; ok {
+			e := elem.Value.(*mapStoreEntry)
+			if e.m.srcID == src.srcID {
+				s.lru.MoveToFront(elem)
+				e.refs++
+				return e, true
+			}
+			log.Debug().Str("map", key.name).Msg("Map source changed, reloading")
+			s.removeLocked(elem)
+		}
+
+		if ld, ok := s.loading[key]; ok {
+			s.mu.Unlock()
+			<-ld.done
+			s.mu.Lock()
+			continue
+		}
+
+		ld := &mapStoreLoad{done: make(chan struct{})}
+		s.loading[key] = ld
+		srcID := src.srcID
+		s.mu.Unlock()
+		m, ok := s.load(key, src, srcID)
+		s.mu.Lock()
+		delete(s.loading, key)
+		close(ld.done)
+		if !ok {
+			src.bad = true
+			return nil, false
+		}
+		m.srcID = srcID
+
+		e := &mapStoreEntry{key: key, m: m, bytes: int64(len(m.Img.Pix)) + int64(len(m.Integral.Sum)+len(m.Integral.SumSq))*8, refs: 1}
+		s.entries[key] = s.lru.PushFront(e)
+		s.used += e.bytes
+		s.evictLocked(e)
+		log.Debug().Str("map", key.name).Float64("scale", key.scale).Int("factor", key.factor).
+			Int64("usedMB", s.used>>20).Msg("Map variant loaded")
+		return e, true
+	}
+}
+
+// derive builds a variant from the base variant of key, holding the base only while building
+func (s *MapStore) derive(key mapStoreKey, build func(base MapCache) *image.RGBA) (MapCache, bool) {
+	base, ok := s.getEntry(key)
+	if !ok {
+		return MapCache{}, false
+	}
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.unrefLocked(base)
+	}()
+
+	img := build(base.m)
+	return MapCache{
+		Name:     key.name,
+		Img:      img,
+		Integral: minicv.GetIntegralArray(img),
+		OffsetX:  base.m.OffsetX,
+		OffsetY:  base.m.OffsetY,
+	}, true
+}
+
+// load builds the variant from the cache file or from the base variant at scale 1, without holding the lock
+func (s *MapStore) load(key mapStoreKey, src *mapSource, srcID string) (MapCache, bool) {
+	// Coarse variants are cheap to derive, so they are not cached on disk
+	if key.factor > 1 {
+		return s.derive(mapStoreKey{key.name, key.scale, 1}, func(base MapCache) *image.RGBA {
+			return minicv.ImageDownsample(base.Img, key.factor)
+		})
+	}
+
+	if key.scale != 1.0 {
+		return getOrBuildMapCache(key.name, srcID, key.scale, func() (MapCache, bool) {
+			return s.derive(mapStoreKey{key.name, 1.0, 1}, func(base MapCache) *image.RGBA {
+				return minicv.ImageScale(base.Img, key.scale)
+			})
+		})
+	}
+
+	// Load preprocessed data from cache, or decode, crop and precompute integral image
+	return getOrBuildMapCache(key.name, srcID, 1.0, func() (MapCache, bool) {
+		data, err := os.ReadFile(src.path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", src.path).Msg("Failed to read map image")
+			return MapCache{}, false
+		}
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			log.Warn().Err(err).Str("path", src.path).Msg("Failed to decode map image")
+			return MapCache{}, false
+		}
+
+		var imgRGBA *image.RGBA
+		offsetX, offsetY := 0, 0
+
+		// Crop if valid rect exists
+		if !src.rect.Empty() {
+			// Crop precisely using drawing
+			r0 := src.rect.Intersect(img.Bounds())
+			dst := image.NewRGBA(image.Rect(0, 0, r0.Dx(), r0.Dy()))
+			draw.Draw(dst, dst.Bounds(), img, r0.Min, draw.Src)
+			imgRGBA = dst
+			offsetX, offsetY = r0.Min.X, r0.Min.Y
+		} else {
+			imgRGBA = minicv.ImageConvertRGBA(img)
+		}
+
+		return MapCache{
+			Name:     key.name,
+			Img:      imgRGBA,
+			Integral: minicv.GetIntegralArray(imgRGBA),
+			OffsetX:  offsetX,
+			OffsetY:  offsetY,
+		}, true
+	})
+}
+
+// evictLocked evicts the least recently used variants except keep (may be nil) until the budget is met
+func (s *MapStore) evictLocked(keep *mapStoreEntry) {
+	for s.used > s.budget {
+		elem := s.lru.Back()
+		if elem == nil || elem.Value.(*mapStoreEntry) == keep {
+			return
+		}
+		e := elem.Value.(*mapStoreEntry)
+		log.Debug().Str("map", e.key.name).Float64("scale", e.key.scale).Int("factor", e.key.factor).
+			Msg("Map variant evicted")
+		s.removeLocked(elem)
+	}
+}
+
+// removeLocked removes the variant from the LRU list, and releases its memory mapping unless a lease holds it
+func (s *MapStore) removeLocked(elem *list.Element) {
+	e := s.lru.Remove(elem).(*mapStoreEntry)
+	delete(s.entries, e.key)
+	s.used -= e.bytes
+	e.evicted = true
+	if e.refs == 0 && e.m.mapping != nil {
+		munmapFile(e.m.mapping)
+	}
+}
+
+// unrefLocked drops one reference to the variant, and releases its memory mapping if it was the last one of an evicted variant
+func (s *MapStore) unrefLocked(e *mapStoreEntry) {
+	e.refs--
+	if e.refs == 0 && e.evicted && e.m.mapping != nil {
+		munmapFile(e.m.mapping)
+	}
+}
+
+// scanMapSources lists the map images in the resource directory
+// and their crop rectangles if map bbox data exists
+func scanMapSources() (map[string]*mapSource, error) {
+	// Find map directory using search strategy
+	mapDir := findResource(MAP_DIR)
+	if mapDir == "" {
+		return nil, fmt.Errorf("map directory not found (searched in cache and standard locations)")
+	}
+
+	// Read map_bbox.json if it exists
+	rectList := make(map[string][]int)
+	rectPath := filepath.Join(mapDir, "map_bbox.json")
+	if data, err := os.ReadFile(rectPath); err == nil {
+		if err := json.Unmarshal(data, &rectList); err != nil {
+			log.Warn().Err(err).Str("path", rectPath).Msg("Failed to unmarshal map_bbox.json")
+		}
+	}
+
+	// Read directory entries
+	entries, err := os.ReadDir(mapDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read map directory: %w", err)
+	}
+
+	sources := make(map[string]*mapSource)
+	for _, entry := range entries {
+		filename := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(filename, ".png") {
+			continue
+		}
+
+		// Extract map name (remove ".png" suffix)
+		name := strings.TrimSuffix(filename, ".png")
+
+		// Determine crop rect if valid rect exists
+		var rect image.Rectangle
+		if r, ok := rectList[name]; ok && len(r) == 4 {
+			expand := LOC_RADIUS / 2
+			rect = image.Rect(r[0]-expand, r[1]-expand, r[2]+expand, r[3]+expand)
+		}
+		sources[name] = &mapSource{path: filepath.Join(mapDir, filename), rect: rect}
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no map images found in %s", mapDir)
+	}
+	return sources, nil
+}
@@ -0,0 +1,35 @@
+// Copyright (c) 2026 Harry Huang
+
+//go:build !windows
+
+package maptracker
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmapFile maps the whole file into memory as read-only
+func mmapFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	return unix.Mmap(int(f.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED)
+}
+
+// munmapFile unmaps memory returned by mmapFile
+func munmapFile(data []byte) {
+	_ = unix.Munmap(data)
+}
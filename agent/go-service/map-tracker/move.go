@@ -7,8 +7,10 @@ import (
 	"fmt"
 	"math"
 	"regexp"
+	"strings"
 	"time"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/camerasens"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
@@ -18,10 +20,20 @@ type MapTrackerMove struct{}
 
 // MapTrackerMoveParam represents the custom_action_param for MapTrackerMove
 type MapTrackerMoveParam struct {
-	// MapName is the name of the map to navigate (required).
+	// MapName is the name of the map to navigate (required unless Route or Segments is set).
 	MapName string `json:"map_name"`
-	// Path is a sequence of [x, y] coordinate points to follow (required).
-	Path [][2]int `json:"path"`
+	// Path is a sequence of points to follow, each as [x, y] or an object with actions (required unless Route or Segments is set).
+	Path []MapTrackerPoint `json:"path"`
+	// Route is the name of a route in the route store, used in place of MapName and Path.
+	Route string `json:"route,omitempty"`
+	// RouteVersion pins a specific version of the route; the latest version is used if omitted.
+	RouteVersion int `json:"route_version,omitempty"`
+	// RouteReverse follows the route from its last point to its first point when enabled.
+	RouteReverse bool `json:"route_reverse,omitempty"`
+	// RouteSlice selects a part of the route as [start] or [start, end), negative indices count from the end.
+	RouteSlice []int `json:"route_slice,omitempty"`
+	// Segments is a sequence of paths on different maps or tiers, used in place of MapName and Path.
+	Segments []MapTrackerMoveSegment `json:"segments,omitempty"`
 	// PathTrim trims the path to start from the nearest point to the current location when enabled.
 	PathTrim bool `json:"path_trim,omitempty"`
 	// NoPrint controls whether to suppress printing navigation status to the GUI.
@@ -40,6 +52,22 @@ type MapTrackerMoveParam struct {
 	StuckThreshold int64 `json:"stuck_threshold,omitempty"`
 	// StuckTimeout is the maximum time in milliseconds to tolerate being stuck.
 	StuckTimeout int64 `json:"stuck_timeout,omitempty"`
+	// StuckRecovery is the chain of recovery strategies tried in turn when stuck.
+	StuckRecovery []StuckRecoveryType `json:"stuck_recovery,omitempty"`
+	// Steering selects how to steer along the path ("waypoint" or "pursuit").
+	Steering SteeringMode `json:"steering,omitempty"`
+	// Backend selects the movement controls ("auto", "keyboard" or "touch").
+	Backend MovementBackendType `json:"backend,omitempty"`
+	// InferFilter selects the tracking filter of MapTrackerInfer ("heuristic" or "kalman").
+	InferFilter InferFilterType `json:"infer_filter,omitempty"`
+	// InferSearchMode selects the full search strategy of MapTrackerInfer ("exhaustive" or "pyramid").
+	InferSearchMode InferSearchMode `json:"infer_search_mode,omitempty"`
+	// Session is the name of the tracking session used by MapTrackerInfer during movement.
+	Session string `json:"session,omitempty"`
+	// Debug controls whether to save an annotated image of the run to the debug directory when it ends.
+	Debug bool `json:"debug,omitempty"`
+	// Telemetry controls whether to record inferences and control decisions to the telemetry stream.
+	Telemetry bool `json:"telemetry,omitempty"`
 }
 
 // PlayerMovement represents different movement state in the game
@@ -57,7 +85,7 @@ var (
 // PlayerRotationAdjustmentState keeps track of one rotation adjustment
 type PlayerRotationAdjustmentState struct {
 	fromPos         [2]int        // Last position where rotation adjustment started to apply
-	fromRot         int           // Last rotation when rotation adjustment started to apply
+	fromRot         float64       // Last rotation when rotation adjustment started to apply
 	deltaRot        float64       // Last rotation difference to apply
 	startTime       time.Time     // Last time when rotation adjustment started to apply
 	expectedElapsed time.Duration // Expected time for this rotation adjustment to take effect
@@ -83,52 +111,163 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 		return false
 	}
 
+	return runMove(ctx, arg.CurrentTaskName, param)
+}
+
+// mover keeps the movement state of one navigation run across segments and targets
+type mover struct {
+	ctx   *maa.Context
+	ctrl  *maa.Controller
+	aw    *ActionWrapper
+	mv    MovementBackend
+	param *MapTrackerMoveParam
+
+	movement *PlayerMovement
+
+	// Calibrated camera rotation sensitivity of the controller
+	sensitivity camerasens.Sensitivity
+
+	// Adaptive rotation sensitivity local state
+	rotationSpeed                 float64
+	rotAdjState, rotAdjStateCache *PlayerRotationAdjustmentState
+
+	// Debug image recorder, nil if disabled
+	debug *moveDebugRecorder
+
+	// Telemetry recorder, nil if disabled
+	telemetry *moveTelemetry
+}
+
+// runMove controls the player to move along the segments given by the validated parameters, on behalf of the node
+func runMove(ctx *maa.Context, node string, param *MapTrackerMoveParam) (ok bool) {
 	ctrl := ctx.GetTasker().GetController()
 	aw := NewActionWrapper(ctx, ctrl)
-	loopInterval := time.Duration(INFER_INTERVAL_MS) * time.Millisecond
+	m := &mover{
+		ctx:           ctx,
+		ctrl:          ctrl,
+		aw:            aw,
+		mv:            newMovementBackend(aw, param.Backend),
+		param:         param,
+		sensitivity:   camerasens.Get(ctrl),
+		rotationSpeed: ROTATION_DEFAULT_SPEED,
+	}
 
-	if param.PathTrim && len(param.Path) > 1 {
-		if initRes, err := doInfer(ctx, ctrl, param); err == nil && initRes != nil {
-			closestIdx := 0
-			minDist := math.MaxFloat64
-			for i, p := range param.Path {
-				dist := math.Hypot(float64(initRes.X-p[0]), float64(initRes.Y-p[1]))
-				if dist < minDist {
-					minDist = dist
-					closestIdx = i
-				}
+	if param.PathTrim {
+		m.trimPath()
+	}
+	if param.Debug {
+		m.debug = newMoveDebugRecorder(ctx, param.Segments)
+		defer func() { m.debug.save(ctrl, ok) }()
+	}
+	if param.Telemetry {
+		m.telemetry = newMoveTelemetry(ctx, node, param)
+		defer func() { m.telemetry.end(ok) }()
+	}
+
+	targetsCount := 0
+	for _, seg := range param.Segments {
+		targetsCount += len(seg.Path)
+	}
+	log.Info().Int("segmentsCount", len(param.Segments)).Int("targetsCount", targetsCount).Msg("Starting navigation to targets")
+
+	m.resetMovement()
+
+	// For each segment
+	for si := range param.Segments {
+		seg := &param.Segments[si]
+		m.debug.beginSegment(si)
+		if si > 0 {
+			if !m.transit(&param.Segments[si-1], seg) {
+				return false
 			}
-			if closestIdx > 0 {
-				log.Info().Int("closest_index", closestIdx).Float64("closest_dist", minDist).Msg("Path trim enabled, skipping earlier targets")
-				param.Path = param.Path[closestIdx:]
+		}
+		if !m.followPath(seg) {
+			return false
+		}
+	}
+
+	// End of all targets reached, stop movement
+	m.mv.Stop(25)
+
+	// Show finished UI summary
+	if !param.NoPrint {
+		maafocus.NodeActionStarting(
+			m.ctx,
+			fmt.Sprintf(navigationFinishedHTML, targetsCount),
+		)
+	}
+
+	return true
+}
+
+// trimPath skips the segments and targets before the one closest to the current location
+func (m *mover) trimPath() {
+	mapNames := make([]string, 0, len(m.param.Segments))
+	for _, seg := range m.param.Segments {
+		mapNames = append(mapNames, seg.MapName)
+	}
+	initRes, err := doInfer(m.ctx, m.ctrl, m.param, mapNames...)
+	if err != nil || initRes == nil {
+		log.Warn().Err(err).Msg("Path trim enabled but failed to infer current location; using full path")
+		return
+	}
+
+	closestSeg, closestIdx := -1, 0
+	minDist := math.MaxFloat64
+	for si, seg := range m.param.Segments {
+		if seg.MapName != initRes.MapName {
+			continue
+		}
+		for i, p := range seg.Path {
+			dist := math.Hypot(float64(initRes.X-p.X), float64(initRes.Y-p.Y))
+			if dist < minDist {
+				minDist = dist
+				closestSeg, closestIdx = si, i
 			}
-		} else {
-			log.Warn().Err(err).Msg("Path trim enabled but failed to infer current location; using full path")
 		}
 	}
+	if closestSeg < 0 {
+		log.Warn().Str("map", initRes.MapName).Msg("Path trim enabled but current map is not in path; using full path")
+		return
+	}
+	if closestSeg > 0 || closestIdx > 0 {
+		log.Info().Int("closest_segment", closestSeg).Int("closest_index", closestIdx).Float64("closest_dist", minDist).Msg("Path trim enabled, skipping earlier targets")
+		segments := m.param.Segments[closestSeg:]
+		segments[0].Path = segments[0].Path[closestIdx:]
+		segments[0].Transition = nil
+		m.param.Segments = segments
+	}
+}
 
-	log.Info().Str("map", param.MapName).Int("targetsCount", len(param.Path)).Msg("Starting navigation to targets")
+// resetMovement resets player movement type to 'run' by sprint once
+func (m *mover) resetMovement() {
+	m.mv.ResetMode(200)
+	m.telemetry.mode(m.movement, &MovementRun, nil)
+	m.movement = &MovementRun
+}
 
-	// Reset player movement type by sprint once
-	aw.KeyDownSync(KEY_S, 50)
-	aw.KeyTypeSync(KEY_SHIFT, 50)
-	aw.KeyUpSync(KEY_S, 50)
-	aw.KeyTypeSync(KEY_W, 50)
-	movement := &MovementRun
+// followPath controls the player to move to each target point of a segment in order
+func (m *mover) followPath(seg *MapTrackerMoveSegment) bool {
+	ctx, ctrl, aw, mv, param := m.ctx, m.ctrl, m.aw, m.mv, m.param
+	loopInterval := time.Duration(INFER_INTERVAL_MS) * time.Millisecond
 
-	// Adaptive rotation sensitivity local state
-	rotationSpeed := ROTATION_DEFAULT_SPEED
-	var rotAdjState, rotAdjStateCache *PlayerRotationAdjustmentState
+	// Stuck recovery state, the chain restarts once stuck at a target beyond the last stuck one
+	stuckAttempts, stuckIndex := 0, -1
+	// Waypoint actions are not repeated when retracing earlier targets after re-planning
+	retraceUntil := -1
 
 	// For each target point
-	for i, target := range param.Path {
-		targetX, targetY := target[0], target[1]
-		log.Info().Int("index", i).Int("targetX", targetX).Int("targetY", targetY).Msg("Navigating to next target point")
+targetLoop:
+	for i := 0; i < len(seg.Path); i++ {
+		target := seg.Path[i]
+		targetX, targetY := target.X, target.Y
+		log.Info().Str("map", seg.MapName).Int("index", i).Int("targetX", targetX).Int("targetY", targetY).Msg("Navigating to next target point")
+		m.telemetry.target(seg.MapName, i)
 
 		// Show navigation UI
 		var initDist float64
-		var initRot int
-		if initResult, err := doInfer(ctx, ctrl, param); err == nil && initResult != nil {
+		var initRot float64
+		if initResult, err := doInfer(ctx, ctrl, param, seg.MapName); err == nil && initResult != nil {
 			initDist = math.Hypot(float64(initResult.X-targetX), float64(initResult.Y-targetY))
 			initRot = calcTargetRotation(initResult.X, initResult.Y, targetX, targetY)
 			if !param.NoPrint {
@@ -144,6 +283,7 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 		var (
 			lastLoopTime     = time.Time{}
 			lastArrivalTime  = time.Now()
+			lastRecoveryTime = time.Time{}
 			prevLocationTime = time.Time{}
 			prevLocation     *[2]int
 		)
@@ -160,181 +300,244 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 			// Check stopping signal
 			if ctx.GetTasker().Stopping() {
 				log.Warn().Msg("Task is stopping, exiting navigation loop")
-				aw.KeyUpSync(KEY_W, 25)
+				m.telemetry.fail("stopping")
+				mv.Stop(25)
 				return false
 			}
 
 			// Check arrival timeout
 			deltaArrivalMs := loopStartTime.Sub(lastArrivalTime).Milliseconds()
 			if deltaArrivalMs > param.ArrivalTimeout {
-				log.Error().Msg("Arrival timeout, stopping task")
-				doEmergencyStop(aw, param.NoPrint)
+				log.Error().Msg("Arrival timeout, stopping movement")
+				m.telemetry.fail("arrival_timeout")
+				doEmergencyStop(aw, mv, param.NoPrint)
 				return false
 			}
 
 			// Run inference to get current location and rotation
-			result, err := doInfer(ctx, ctrl, param)
+			result, err := doInfer(ctx, ctrl, param, seg.MapName)
 			if err != nil {
 				log.Error().Err(err).Msg("Inference failed during navigation")
-				aw.KeyUpSync(KEY_W, 25)
+				mv.Stop(25)
 				continue
 			}
 			curX, curY := result.X, result.Y
 			rot := result.Rot
+			m.debug.track(result)
 
 			// Calculate rotation difference
 			targetRot := calcTargetRotation(curX, curY, targetX, targetY)
-			rawDeltaRot := calcDeltaRotation(rot, targetRot)
+			dist := math.Hypot(float64(curX-targetX), float64(curY-targetY))
+			steerRot, steerDist, passed := targetRot, dist, false
+			if param.Steering == STEERING_PURSUIT {
+				var lookahead [2]int
+				lookahead, steerDist, passed = m.pursue(seg.Path, i, curX, curY)
+				steerRot = calcTargetRotation(curX, curY, lookahead[0], lookahead[1])
+			}
+			rawDeltaRot := calcDeltaRotation(rot, steerRot)
 
 			// Check arrival
-			dist := math.Hypot(float64(curX-targetX), float64(curY-targetY))
 			isArrived := func() bool {
+				if passed {
+					log.Info().Int("x", curX).Int("y", curY).Int("index", i).Msg("Target point reached (passed by pursuit)")
+					return true
+				}
 				if dist < param.ArrivalThreshold {
 					log.Info().Int("x", curX).Int("y", curY).Int("index", i).Msg("Target point reached")
 					return true
 				}
-				if math.Abs(float64(calcDeltaRotation(targetRot, initRot))) > 90.0 {
-					log.Info().Int("targetRot", targetRot).Int("initRot", initRot).Int("index", i).Msg("Target point reached (guessed by rotation)")
+				if math.Abs(calcDeltaRotation(targetRot, initRot)) > 90.0 {
+					log.Info().Float64("targetRot", targetRot).Float64("initRot", initRot).Int("index", i).Msg("Target point reached (guessed by rotation)")
 					return true
 				}
 				return false
 			}
 			if isArrived() {
+				// Run actions of the reached point
+				if i >= retraceUntil && !m.runWaypointActions(seg.MapName, i, &target) {
+					return false
+				}
 				// Peek next target's direction
-				if i < len(param.Path)-1 {
-					nextX, nextY := param.Path[i+1][0], param.Path[i+1][1]
+				if param.Steering == STEERING_WAYPOINT && i < len(seg.Path)-1 {
+					nextX, nextY := seg.Path[i+1].X, seg.Path[i+1].Y
 					nextTargetRot := calcTargetRotation(curX, curY, nextX, nextY)
 					nextDeltaRot := calcDeltaRotation(rot, nextTargetRot)
 					// Pause slightly if next target is in a very different direction
-					if math.Abs(float64(nextDeltaRot)) > param.RotationUpperThreshold {
-						aw.KeyUpSync(KEY_W, 25)
+					if math.Abs(nextDeltaRot) > param.RotationUpperThreshold {
+						mv.Stop(25)
 					}
 				}
 				// Finish current target
 				break
 			}
 
-			log.Debug().Int("curX", curX).Int("curY", curY).Int("curRot", rot).Float64("dist", dist).Int("targetRot", targetRot).Msg("Navigating to target")
+			log.Debug().Int("curX", curX).Int("curY", curY).Float64("curRot", rot).Float64("dist", dist).Float64("targetRot", targetRot).Float64("steerRot", steerRot).Msg("Navigating to target")
 
 			// Check Stuck
 			if prevLocation != nil && prevLocation[0] == curX && prevLocation[1] == curY {
 				deltaLocationMs := loopStartTime.Sub(prevLocationTime).Milliseconds()
 				if deltaLocationMs > param.StuckTimeout {
-					log.Error().Msg("Stuck for too long, stopping task")
-					doEmergencyStop(aw, param.NoPrint)
+					m.debug.markStuck(curX, curY)
+					m.telemetry.stuck(curX, curY, "", 0, "stuck_timeout")
+					m.telemetry.fail("stuck_timeout")
+					log.Error().Msg("Stuck for too long, stopping movement")
+					doEmergencyStop(aw, mv, param.NoPrint)
 					return false
 				}
-				if deltaLocationMs > param.StuckThreshold {
-					log.Info().Msg("Stuck detected, jumping...")
-					aw.KeyTypeSync(KEY_SPACE, 100)
+				deltaRecoveryMs := loopStartTime.Sub(lastRecoveryTime).Milliseconds()
+				if deltaLocationMs > param.StuckThreshold && deltaRecoveryMs > param.StuckThreshold {
+					m.debug.markStuck(curX, curY)
+					if i > stuckIndex {
+						stuckAttempts, stuckIndex = 0, i
+					}
+					if stuckAttempts >= len(param.StuckRecovery) {
+						log.Error().Int("attempts", stuckAttempts).Msg("Stuck recovery exhausted, stopping movement")
+						m.telemetry.stuck(curX, curY, "", stuckAttempts, "stuck_recovery_exhausted")
+						m.telemetry.fail("stuck_recovery_exhausted")
+						doEmergencyStop(aw, mv, param.NoPrint)
+						return false
+					}
+					strategy := param.StuckRecovery[stuckAttempts]
+					stuckAttempts++
+					log.Info().Str("strategy", string(strategy)).Int("attempt", stuckAttempts).Int("index", i).Msg("Stuck detected, trying recovery")
+					m.telemetry.stuck(curX, curY, strategy, stuckAttempts, "")
+					if strategy == STUCK_RECOVERY_REPLAN {
+						if j := nearestEarlierTarget(seg.Path, i, curX, curY); j >= 0 {
+							log.Info().Int("from", i).Int("to", j).Msg("Re-planning from earlier target point")
+							retraceUntil = max(retraceUntil, i)
+							i = j - 1
+							continue targetLoop
+						}
+						log.Info().Msg("No earlier target point to re-plan from, skipping")
+					} else {
+						m.recoverStuck(strategy)
+					}
+					lastRecoveryTime = time.Now()
 				}
 			} else {
 				prevLocation = &[2]int{curX, curY}
 				prevLocationTime = loopStartTime
 			}
 
-			// Update adaptive rotation speed
-			if rotAdjState != nil && (rotAdjStateCache == nil || rotAdjState.startTime.After(rotAdjStateCache.startTime)) {
-				// Check if last rotation adjustment is completed
-				if loopStartTime.Sub(rotAdjState.startTime) > rotAdjState.expectedElapsed {
-					// Check if player is moving and rotating sufficiently to trust rotation measurement
-					distTravel := math.Hypot(float64(curX-rotAdjState.fromPos[0]), float64(curY-rotAdjState.fromPos[1]))
-					if distTravel > rotAdjState.expectedElapsed.Seconds()*MovementWalk.Speed {
-						// Check if rotation difference is sufficient to consider adjusting rotation speed
-						actualDeltaRot := calcDeltaRotation(rotAdjState.fromRot, rot)
-						if math.Abs(float64(actualDeltaRot))+math.Abs(rotAdjState.deltaRot) > param.RotationLowerThreshold {
-							idealRotSpeed := rotAdjState.deltaRot / (float64(actualDeltaRot) + 1e-6)
-							if idealRotSpeed >= ROTATION_MIN_SPEED && idealRotSpeed <= ROTATION_MAX_SPEED {
-								rotationSpeed = rotationSpeed*0.618 + idealRotSpeed*0.382
-								rotAdjStateCache = rotAdjState
-								log.Debug().
-									Float64("idealRotSpeed", idealRotSpeed).
-									Float64("newRotSpeed", rotationSpeed).
-									Int("actualDeltaRot", actualDeltaRot).
-									Float64("lastDeltaRot", rotAdjState.deltaRot).
-									Msg("Adaptive rotation speed updated")
-							}
-						}
+			m.updateRotationSpeed(loopStartTime, curX, curY, rot)
+			m.steer(loopStartTime, curX, curY, rot, rawDeltaRot, steerDist)
+		}
+		// End of loop, one target reached
+	}
+
+	return true
+}
+
+// updateRotationSpeed updates the adaptive rotation speed from the last completed rotation adjustment
+func (m *mover) updateRotationSpeed(loopStartTime time.Time, curX, curY int, rot float64) {
+	rotAdjState := m.rotAdjState
+	if rotAdjState != nil && (m.rotAdjStateCache == nil || rotAdjState.startTime.After(m.rotAdjStateCache.startTime)) {
+		// Check if last rotation adjustment is completed
+		if loopStartTime.Sub(rotAdjState.startTime) > rotAdjState.expectedElapsed {
+			// Check if player is moving and rotating sufficiently to trust rotation measurement
+			distTravel := math.Hypot(float64(curX-rotAdjState.fromPos[0]), float64(curY-rotAdjState.fromPos[1]))
+			if distTravel > rotAdjState.expectedElapsed.Seconds()*MovementWalk.Speed {
+				// Check if rotation difference is sufficient to consider adjusting rotation speed
+				actualDeltaRot := calcDeltaRotation(rotAdjState.fromRot, rot)
+				if math.Abs(actualDeltaRot)+math.Abs(rotAdjState.deltaRot) > m.param.RotationLowerThreshold {
+					idealRotSpeed := rotAdjState.deltaRot / (actualDeltaRot + 1e-6)
+					if idealRotSpeed >= ROTATION_MIN_SPEED && idealRotSpeed <= ROTATION_MAX_SPEED {
+						m.rotationSpeed = m.rotationSpeed*0.618 + idealRotSpeed*0.382
+						m.rotAdjStateCache = rotAdjState
+						log.Debug().
+							Float64("idealRotSpeed", idealRotSpeed).
+							Float64("newRotSpeed", m.rotationSpeed).
+							Float64("actualDeltaRot", actualDeltaRot).
+							Float64("lastDeltaRot", rotAdjState.deltaRot).
+							Msg("Adaptive rotation speed updated")
+						m.telemetry.rotationSpeed(idealRotSpeed, m.rotationSpeed)
 					}
 				}
 			}
+		}
+	}
+}
 
-			// Check if no active rotation adjustment
-			if rotAdjState == nil || loopStartTime.Sub(rotAdjState.startTime) > rotAdjState.expectedElapsed {
-				// Check if rotation is not good enough to sprint
-				if math.Abs(float64(rawDeltaRot)) > param.RotationLowerThreshold {
-					// Ensure no sprinting: forcibly set to 'walk'
-					if movement.Speed > MovementRun.Speed {
-						aw.KeyTypeSync(KEY_CTRL, 25)
-						movement = &MovementWalk
-					}
-				} else {
-					// Rotation is good: at least set to 'run'
-					if movement.Speed < MovementRun.Speed {
-						aw.KeyTypeSync(KEY_CTRL, 25)
-						movement = &MovementRun
-					}
-					aw.KeyDownSync(KEY_W, 5)
+// rotateCamera rotates the camera by deltaRot degrees through the calibrated sensitivity,
+// scaled by the adaptive rotation speed relative to its default
+func (m *mover) rotateCamera(deltaRot float64, durationMillis, delayMillis int) {
+	m.mv.RotateCamera(m.sensitivity.Pixels(deltaRot*m.rotationSpeed/ROTATION_DEFAULT_SPEED), durationMillis, delayMillis)
+}
 
-					if dist > param.SprintThreshold {
-						// Target is far enough: enable 'sprint'
-						if movement.Speed < MovementSprint.Speed {
-							aw.KeyTypeSync(KEY_SHIFT, 100)
-							movement = &MovementSprint
-						}
-					}
-				}
+// steer adjusts movement mode and camera rotation towards the current target
+func (m *mover) steer(loopStartTime time.Time, curX, curY int, rot, rawDeltaRot, dist float64) {
+	mv, param := m.mv, m.param
+	prevMovement := m.movement
+	defer func() { m.telemetry.mode(prevMovement, m.movement, &[2]int{curX, curY}) }()
 
-				// Start a new rotation adjustment
-				if math.Abs(float64(rawDeltaRot)) > 1.0 {
-					finalDeltaRot := float64(rawDeltaRot)
+	// Pure pursuit corrects heading continuously, so only a very bad rotation slows down the player
+	sprintRotThreshold := param.RotationLowerThreshold
+	if param.Steering == STEERING_PURSUIT {
+		sprintRotThreshold = param.RotationUpperThreshold
+	}
 
-					// Select appropriate rotation method based on how bad the rotation is
-					if math.Abs(float64(rawDeltaRot)) > param.RotationUpperThreshold {
-						// Rotation is very bad: forcibly set to 'walk' for better control
-						if movement.Speed > MovementWalk.Speed {
-							aw.KeyTypeSync(KEY_CTRL, 25)
-							movement = &MovementWalk
-						}
-						aw.RotateCamera(int(finalDeltaRot*rotationSpeed), 75, 25)
-						aw.KeyDownSync(KEY_W, 25)
-					} else {
-						// Rotation is acceptable but can be improved: at least ensure 'run'
-						if movement.Speed < MovementRun.Speed {
-							aw.KeyTypeSync(KEY_CTRL, 25)
-							movement = &MovementRun
-						}
-						aw.KeyDownSync(KEY_W, 25)
-						aw.RotateCamera(int(finalDeltaRot*rotationSpeed), 75, 25)
-					}
+	// Check if no active rotation adjustment
+	if m.rotAdjState == nil || loopStartTime.Sub(m.rotAdjState.startTime) > m.rotAdjState.expectedElapsed {
+		// Check if rotation is not good enough to sprint
+		if math.Abs(rawDeltaRot) > sprintRotThreshold {
+			// Ensure no sprinting: forcibly set to 'walk'
+			if m.movement.Speed > MovementRun.Speed {
+				mv.ToggleWalk(25)
+				m.movement = &MovementWalk
+			}
+		} else {
+			// Rotation is good: at least set to 'run'
+			if m.movement.Speed < MovementRun.Speed {
+				mv.ToggleWalk(25)
+				m.movement = &MovementRun
+			}
+			mv.Forward(5)
 
-					// Update adaptive rotation state
-					rotAdjState = &PlayerRotationAdjustmentState{
-						fromPos:         [2]int{curX, curY},
-						fromRot:         rot,
-						deltaRot:        finalDeltaRot,
-						startTime:       time.Now(),
-						expectedElapsed: time.Duration(float64(time.Second) * math.Abs(finalDeltaRot) / movement.RotationSpeed),
-					}
-					aw.ResetCamera(25)
+			if dist > param.SprintThreshold {
+				// Target is far enough: enable 'sprint'
+				if m.movement.Speed < MovementSprint.Speed {
+					mv.Sprint(100)
+					m.movement = &MovementSprint
 				}
 			}
 		}
-		// End of loop, one target reached
-	}
 
-	// End of all targets reached, stop movement
-	aw.KeyUpSync(KEY_W, 25)
+		// Start a new rotation adjustment
+		if math.Abs(rawDeltaRot) > 1.0 {
+			finalDeltaRot := rawDeltaRot
 
-	// Show finished UI summary
-	if !param.NoPrint {
-		maafocus.NodeActionStarting(
-			aw.ctx,
-			fmt.Sprintf(navigationFinishedHTML, len(param.Path)),
-		)
-	}
+			// Select appropriate rotation method based on how bad the rotation is
+			if math.Abs(rawDeltaRot) > param.RotationUpperThreshold {
+				// Rotation is very bad: forcibly set to 'walk' for better control
+				if m.movement.Speed > MovementWalk.Speed {
+					mv.ToggleWalk(25)
+					m.movement = &MovementWalk
+				}
+				m.rotateCamera(finalDeltaRot, 75, 25)
+				mv.Forward(25)
+			} else {
+				// Rotation is acceptable but can be improved: at least ensure 'run'
+				if m.movement.Speed < MovementRun.Speed {
+					mv.ToggleWalk(25)
+					m.movement = &MovementRun
+				}
+				mv.Forward(25)
+				m.rotateCamera(finalDeltaRot, 75, 25)
+			}
 
-	return true
+			m.telemetry.rotate(curX, curY, finalDeltaRot, dist, m.rotationSpeed, m.movement)
+
+			// Update adaptive rotation state
+			m.rotAdjState = &PlayerRotationAdjustmentState{
+				fromPos:         [2]int{curX, curY},
+				fromRot:         rot,
+				deltaRot:        finalDeltaRot,
+				startTime:       time.Now(),
+				expectedElapsed: time.Duration(float64(time.Second) * math.Abs(finalDeltaRot) / m.movement.RotationSpeed),
+			}
+			mv.ResetCamera(25)
+		}
+	}
 }
 
 func (a *MapTrackerMove) parseParam(paramStr string) (*MapTrackerMoveParam, error) {
@@ -345,73 +548,142 @@ func (a *MapTrackerMove) parseParam(paramStr string) (*MapTrackerMoveParam, erro
 	if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
 		return nil, fmt.Errorf("failed to parse parameters: %w", err)
 	}
-	if len(param.MapName) == 0 {
-		return nil, fmt.Errorf("map_name is required in parameters, got empty")
+	if err := param.normalize(); err != nil {
+		return nil, err
 	}
-	if len(param.Path) == 0 {
-		return nil, fmt.Errorf("path is required in parameters, got empty")
+	return &param, nil
+}
+
+// normalize resolves the route, validates the parameters and sets defaults
+func (param *MapTrackerMoveParam) normalize() error {
+	if param.Route != "" {
+		if err := param.applyRoute(); err != nil {
+			return fmt.Errorf("failed to apply route: %w", err)
+		}
+	} else if param.RouteVersion != 0 || param.RouteReverse || len(param.RouteSlice) > 0 {
+		return fmt.Errorf("route_version, route_reverse and route_slice require route to be set")
+	}
+	if len(param.Segments) > 0 {
+		if len(param.MapName) > 0 || len(param.Path) > 0 {
+			return fmt.Errorf("map_name and path cannot be set together with segments")
+		}
+		if err := validateSegments(param.Segments); err != nil {
+			return err
+		}
+	} else {
+		if len(param.MapName) == 0 {
+			return fmt.Errorf("map_name is required in parameters, got empty")
+		}
+		if len(param.Path) == 0 {
+			return fmt.Errorf("path is required in parameters, got empty")
+		}
+		param.Segments = []MapTrackerMoveSegment{{MapName: param.MapName, Path: param.Path}}
 	}
 
 	// Validate parameters and set defaults
 	if param.ArrivalThreshold < 0 {
-		return nil, fmt.Errorf("arrival_threshold must be non-negative")
+		return fmt.Errorf("arrival_threshold must be non-negative")
 	} else if param.ArrivalThreshold == 0 {
 		param.ArrivalThreshold = DEFAULT_MOVING_PARAM.ArrivalThreshold
 	}
 
 	if param.ArrivalTimeout < 0 {
-		return nil, fmt.Errorf("arrival_timeout must be non-negative")
+		return fmt.Errorf("arrival_timeout must be non-negative")
 	} else if param.ArrivalTimeout == 0 {
 		param.ArrivalTimeout = DEFAULT_MOVING_PARAM.ArrivalTimeout
 	}
 
 	if param.RotationLowerThreshold < 0 {
-		return nil, fmt.Errorf("rotation_lower_threshold must be non-negative")
+		return fmt.Errorf("rotation_lower_threshold must be non-negative")
 	} else if param.RotationLowerThreshold > 180 {
-		return nil, fmt.Errorf("rotation_lower_threshold must be between 0 and 180 degrees")
+		return fmt.Errorf("rotation_lower_threshold must be between 0 and 180 degrees")
 	} else if param.RotationLowerThreshold == 0 {
 		param.RotationLowerThreshold = DEFAULT_MOVING_PARAM.RotationLowerThreshold
 	}
 
 	if param.RotationUpperThreshold < 0 {
-		return nil, fmt.Errorf("rotation_upper_threshold must be non-negative")
+		return fmt.Errorf("rotation_upper_threshold must be non-negative")
 	} else if param.RotationUpperThreshold > 180 {
-		return nil, fmt.Errorf("rotation_upper_threshold must be between 0 and 180 degrees")
+		return fmt.Errorf("rotation_upper_threshold must be between 0 and 180 degrees")
 	} else if param.RotationUpperThreshold == 0 {
 		param.RotationUpperThreshold = DEFAULT_MOVING_PARAM.RotationUpperThreshold
 	}
 
 	if param.SprintThreshold < 0 {
-		return nil, fmt.Errorf("sprint_threshold must be non-negative")
+		return fmt.Errorf("sprint_threshold must be non-negative")
 	} else if param.SprintThreshold == 0 {
 		param.SprintThreshold = DEFAULT_MOVING_PARAM.SprintThreshold
 	}
 
 	if param.StuckThreshold < 0 {
-		return nil, fmt.Errorf("stuck_threshold must be non-negative")
+		return fmt.Errorf("stuck_threshold must be non-negative")
 	} else if param.StuckThreshold == 0 {
 		param.StuckThreshold = DEFAULT_MOVING_PARAM.StuckThreshold
 	}
 
+	if param.StuckRecovery == nil {
+		param.StuckRecovery = DEFAULT_MOVING_PARAM.StuckRecovery
+	} else if err := validateStuckRecovery(param.StuckRecovery); err != nil {
+		return err
+	}
+
+	// The default timeout is long enough for the whole recovery chain to run
 	if param.StuckTimeout < 0 {
-		return nil, fmt.Errorf("stuck_timeout must be non-negative")
+		return fmt.Errorf("stuck_timeout must be non-negative")
 	} else if param.StuckTimeout == 0 {
-		param.StuckTimeout = DEFAULT_MOVING_PARAM.StuckTimeout
+		param.StuckTimeout = max(DEFAULT_MOVING_PARAM.StuckTimeout, stuckChainTimeout(param.StuckThreshold, param.StuckRecovery))
 	}
 
-	return &param, nil
+	switch param.Steering {
+	case "":
+		param.Steering = DEFAULT_MOVING_PARAM.Steering
+	case STEERING_WAYPOINT, STEERING_PURSUIT:
+	default:
+		return fmt.Errorf("invalid steering value: %s", param.Steering)
+	}
+
+	if param.Backend == "" {
+		param.Backend = DEFAULT_MOVING_PARAM.Backend
+	} else if err := validateMovementBackend(param.Backend); err != nil {
+		return err
+	}
+
+	switch param.InferFilter {
+	case "":
+		param.InferFilter = DEFAULT_INFERENCE_PARAM_FOR_MOVE.Filter
+	case FILTER_HEURISTIC, FILTER_KALMAN:
+	default:
+		return fmt.Errorf("invalid infer_filter value: %s", param.InferFilter)
+	}
+
+	switch param.InferSearchMode {
+	case "":
+		param.InferSearchMode = DEFAULT_INFERENCE_PARAM_FOR_MOVE.SearchMode
+	case SEARCH_MODE_EXHAUSTIVE, SEARCH_MODE_PYRAMID:
+	default:
+		return fmt.Errorf("invalid infer_search_mode value: %s", param.InferSearchMode)
+	}
+
+	return nil
 }
 
-func doEmergencyStop(aw *ActionWrapper, noPrint bool) {
+// doEmergencyStop stops the movement and notifies the failure,
+// the caller then returns false so that the pipeline can route via on_error
+func doEmergencyStop(aw *ActionWrapper, mv MovementBackend, noPrint bool) {
 	log.Warn().Msg("Emergency stop triggered")
 	if !noPrint {
 		maafocus.NodeActionStarting(aw.ctx, emergencyStopHTML)
 	}
-	aw.KeyUpSync(KEY_W, 100)
-	aw.ctx.GetTasker().PostStop()
+	mv.Stop(100)
+}
+
+// doInfer captures the screen and runs MapTrackerInfer restricted to the given maps
+func doInfer(ctx *maa.Context, ctrl *maa.Controller, param *MapTrackerMoveParam, mapNames ...string) (*MapTrackerInferResult, error) {
+	return doInferRegex(ctx, ctrl, param, buildMapNameRegex(mapNames))
 }
 
-func doInfer(ctx *maa.Context, ctrl *maa.Controller, param *MapTrackerMoveParam) (*MapTrackerInferResult, error) {
+// doInferRegex captures the screen and runs MapTrackerInfer restricted to the maps matching the regex
+func doInferRegex(ctx *maa.Context, ctrl *maa.Controller, param *MapTrackerMoveParam, mapNameRegex string) (*MapTrackerInferResult, error) {
 	// Capture screen
 	ctrl.PostScreencap().Wait()
 	img, err := ctrl.CacheImage()
@@ -426,9 +698,13 @@ func doInfer(ctx *maa.Context, ctrl *maa.Controller, param *MapTrackerMoveParam)
 
 	// Run recognition
 	inferConfig := map[string]any{
-		"map_name_regex": "^" + regexp.QuoteMeta(param.MapName) + "$",
+		"map_name_regex": mapNameRegex,
 		"precision":      DEFAULT_INFERENCE_PARAM_FOR_MOVE.Precision,
 		"threshold":      DEFAULT_INFERENCE_PARAM_FOR_MOVE.Threshold,
+		"search_mode":    param.InferSearchMode,
+		"filter":         param.InferFilter,
+		"session":        param.Session,
+		"telemetry":      param.Telemetry,
 	}
 
 	inferConfigBytes, err := json.Marshal(inferConfig)
@@ -475,23 +751,26 @@ func doInfer(ctx *maa.Context, ctrl *maa.Controller, param *MapTrackerMoveParam)
 	return &result, nil
 }
 
+// buildMapNameRegex builds a regex exactly matching any of the given map names
+func buildMapNameRegex(mapNames []string) string {
+	quoted := make([]string, 0, len(mapNames))
+	for _, name := range mapNames {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
 // calcTargetRotation calculates the angle from (fromX, fromY) to (toX, toY).
 // 0 degrees is North (negative Y), increasing clockwise.
-func calcTargetRotation(fromX, fromY, toX, toY int) int {
+func calcTargetRotation(fromX, fromY, toX, toY int) float64 {
 	dx := float64(toX - fromX)
 	dy := float64(toY - fromY)
 	angleRad := math.Atan2(dx, -dy)
-	angleDeg := angleRad * 180.0 / math.Pi
-
-	// Normalize to [0, 360)
-	if angleDeg < 0 {
-		angleDeg += 360
-	}
-	return int(math.Round(angleDeg)) % 360
+	return normalizeRotation(angleRad * 180.0 / math.Pi)
 }
 
 // calcDeltaRotation calculates min difference between two angles [-180, 180]
-func calcDeltaRotation(current, target int) int {
+func calcDeltaRotation(current, target float64) float64 {
 	diff := target - current
 	for diff > 180 {
 		diff -= 360
@@ -501,3 +780,15 @@ func calcDeltaRotation(current, target int) int {
 	}
 	return diff
 }
+
+// normalizeRotation normalizes an angle to [0, 360)
+func normalizeRotation(angle float64) float64 {
+	angle = math.Mod(angle, 360)
+	if angle < 0 {
+		angle += 360
+	}
+	if angle >= 360 {
+		angle = 0
+	}
+	return angle
+}
@@ -0,0 +1,336 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// MoveDirection is a direction to step towards, relative to the facing of the player
+type MoveDirection int
+
+const (
+	MOVE_FORWARD MoveDirection = iota
+	MOVE_LEFT
+	MOVE_RIGHT
+	MOVE_BACKWARD
+)
+
+// MovementBackend provides the movement primitives of the player on a kind of controller.
+// Every primitive waits for delayMillis after it is performed.
+type MovementBackend interface {
+	// Forward starts moving forward and keeps moving until Stop
+	Forward(delayMillis int)
+	// Stop stops moving forward
+	Stop(delayMillis int)
+	// Step moves towards the direction for the duration, while moving forward if it has started
+	Step(dir MoveDirection, durationMillis, delayMillis int)
+	// Sprint toggles sprinting
+	Sprint(delayMillis int)
+	// ToggleWalk toggles between walking and running
+	ToggleWalk(delayMillis int)
+	// ResetMode resets the movement mode to running
+	ResetMode(delayMillis int)
+	// Jump jumps once
+	Jump(delayMillis int)
+	// Interact interacts with the nearby object
+	Interact(delayMillis int)
+	// TapKey performs the primitive closest to tapping the waypoint key name, see waypointKeys
+	TapKey(name string, delayMillis int)
+	// RotateCamera rotates the camera horizontally by dx mouse pixels within durationMillis
+	RotateCamera(dx int, durationMillis, delayMillis int)
+	// ResetCamera restores the input state after camera rotations
+	ResetCamera(delayMillis int)
+}
+
+// MovementBackendType selects the movement backend
+type MovementBackendType string
+
+const (
+	// BACKEND_AUTO selects the backend from the controller type
+	BACKEND_AUTO MovementBackendType = "auto"
+	// BACKEND_KEYBOARD moves by keyboard and rotates the camera by mouse
+	BACKEND_KEYBOARD MovementBackendType = "keyboard"
+	// BACKEND_TOUCH moves by the on-screen joystick and buttons and rotates the camera by swipes
+	BACKEND_TOUCH MovementBackendType = "touch"
+)
+
+// validateMovementBackend checks the backend type, where empty means BACKEND_AUTO
+func validateMovementBackend(backend MovementBackendType) error {
+	switch backend {
+	case "", BACKEND_AUTO, BACKEND_KEYBOARD, BACKEND_TOUCH:
+		return nil
+	}
+	return fmt.Errorf("invalid backend value: %s", backend)
+}
+
+// newMovementBackend returns the movement backend of the given type. BACKEND_AUTO (or empty) selects
+// touch controls on ADB controllers and keyboard controls on the others. As the controller type is read from
+// the controller information, which does not document it, keyboard controls are used if it is missing.
+func newMovementBackend(aw *ActionWrapper, backend MovementBackendType) MovementBackend {
+	switch backend {
+	case BACKEND_TOUCH:
+		log.Info().Msg("Using touch movement backend")
+		return &touchMovement{aw: aw}
+	case BACKEND_KEYBOARD:
+		log.Info().Msg("Using keyboard movement backend")
+		return &keyboardMovement{aw: aw}
+	}
+
+	ctrlType := controllerType(aw.ctrl)
+	if ctrlType == "" {
+		log.Warn().Msg("Controller type unknown, using keyboard movement backend; set backend to override")
+		return &keyboardMovement{aw: aw}
+	}
+	if strings.EqualFold(ctrlType, "adb") {
+		log.Info().Str("controllerType", ctrlType).Msg("Using touch movement backend")
+		return &touchMovement{aw: aw}
+	}
+	log.Info().Str("controllerType", ctrlType).Msg("Using keyboard movement backend")
+	return &keyboardMovement{aw: aw}
+}
+
+// controllerType returns the type reported in the controller information, or empty if unknown
+func controllerType(ctrl *maa.Controller) string {
+	info, err := ctrl.GetInfo()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to get controller info")
+		return ""
+	}
+	var parsed struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal([]byte(info), &parsed); err != nil {
+		log.Warn().Err(err).Str("info", info).Msg("Failed to parse controller info")
+		return ""
+	}
+	return parsed.Type
+}
+
+/* ******** Keyboard ******** */
+
+// keyboardMovement moves the player by Win32 virtual keys and rotates the camera by mouse
+type keyboardMovement struct {
+	aw *ActionWrapper
+}
+
+// moveDirectionKeys maps the step directions to virtual-key codes
+var moveDirectionKeys = map[MoveDirection]int{
+	MOVE_FORWARD:  KEY_W,
+	MOVE_LEFT:     KEY_A,
+	MOVE_RIGHT:    KEY_D,
+	MOVE_BACKWARD: KEY_S,
+}
+
+func (k *keyboardMovement) Forward(delayMillis int) {
+	k.aw.KeyDownSync(KEY_W, delayMillis)
+}
+
+func (k *keyboardMovement) Stop(delayMillis int) {
+	k.aw.KeyUpSync(KEY_W, delayMillis)
+}
+
+func (k *keyboardMovement) Step(dir MoveDirection, durationMillis, delayMillis int) {
+	key := moveDirectionKeys[dir]
+	k.aw.KeyDownSync(key, durationMillis)
+	k.aw.KeyUpSync(key, delayMillis)
+}
+
+func (k *keyboardMovement) Sprint(delayMillis int) {
+	k.aw.KeyTypeSync(KEY_SHIFT, delayMillis)
+}
+
+func (k *keyboardMovement) ToggleWalk(delayMillis int) {
+	k.aw.KeyTypeSync(KEY_CTRL, delayMillis)
+}
+
+// ResetMode sprints once while moving backward, which leaves the player running
+func (k *keyboardMovement) ResetMode(delayMillis int) {
+	stepDelayMillis := delayMillis / 4
+	k.aw.KeyDownSync(KEY_S, stepDelayMillis)
+	k.aw.KeyTypeSync(KEY_SHIFT, stepDelayMillis)
+	k.aw.KeyUpSync(KEY_S, stepDelayMillis)
+	k.aw.KeyTypeSync(KEY_W, stepDelayMillis)
+}
+
+func (k *keyboardMovement) Jump(delayMillis int) {
+	k.aw.KeyTypeSync(KEY_SPACE, delayMillis)
+}
+
+func (k *keyboardMovement) Interact(delayMillis int) {
+	k.aw.KeyTypeSync(KEY_F, delayMillis)
+}
+
+func (k *keyboardMovement) TapKey(name string, delayMillis int) {
+	k.aw.KeyTypeSync(waypointKeys[name], delayMillis)
+}
+
+// RotateCamera rotates the camera by moving the mouse
+func (k *keyboardMovement) RotateCamera(dx int, durationMillis, delayMillis int) {
+	cx, cy := WORK_W/2, WORK_H/2
+	k.aw.SwipeSync(cx, cy, dx, 0, durationMillis, delayMillis)
+}
+
+// ResetCamera brings the cursor back to the screen center by Alt+click
+func (k *keyboardMovement) ResetCamera(delayMillis int) {
+	cx, cy := WORK_W/2, WORK_H/2
+	stepDelayMillis := delayMillis / 3
+	k.aw.KeyDownSync(KEY_ALT, stepDelayMillis)
+	k.aw.ClickSync(0, cx, cy, stepDelayMillis)
+	k.aw.KeyUpSync(KEY_ALT, stepDelayMillis)
+}
+
+/* ******** Touch ******** */
+
+// touchMovement moves the player by a held touch on the on-screen joystick,
+// taps the on-screen buttons and rotates the camera by swipes on the right half of the screen
+type touchMovement struct {
+	aw      *ActionWrapper
+	holding bool // Whether the joystick is held forward
+	walking bool // Whether the joystick is pushed halfway to walk
+}
+
+// joystickPoint returns the touch point pushing the joystick towards the direction
+func (t *touchMovement) joystickPoint(dir MoveDirection) (int32, int32) {
+	radius := float64(TOUCH_JOYSTICK_RADIUS)
+	if t.walking {
+		radius /= 2
+	}
+	angle := map[MoveDirection]float64{MOVE_FORWARD: 0, MOVE_LEFT: -90, MOVE_RIGHT: 90, MOVE_BACKWARD: 180}[dir]
+	if t.holding && (dir == MOVE_LEFT || dir == MOVE_RIGHT) {
+		angle /= 2 // Keep moving forward while stepping aside, as a held W key does
+	}
+	rad := angle * math.Pi / 180
+	return int32(TOUCH_JOYSTICK_X + radius*math.Sin(rad)), int32(TOUCH_JOYSTICK_Y - radius*math.Cos(rad))
+}
+
+// push moves the held joystick towards the direction, touching it down first if it is not held
+func (t *touchMovement) push(dir MoveDirection, down bool) {
+	ctrl := t.aw.ctrl
+	if down {
+		ctrl.PostTouchDown(TOUCH_CONTACT_JOYSTICK, TOUCH_JOYSTICK_X, TOUCH_JOYSTICK_Y, 1).Wait()
+	}
+	x, y := t.joystickPoint(dir)
+	ctrl.PostTouchMove(TOUCH_CONTACT_JOYSTICK, x, y, 1).Wait()
+}
+
+// tap taps an on-screen button
+func (t *touchMovement) tap(x, y int, delayMillis int) {
+	t.aw.ClickSync(TOUCH_CONTACT_BUTTON, x, y, 50)
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
+
+func (t *touchMovement) Forward(delayMillis int) {
+	if !t.holding {
+		t.push(MOVE_FORWARD, true)
+		t.holding = true
+	}
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
+
+func (t *touchMovement) Stop(delayMillis int) {
+	if t.holding {
+		t.aw.ctrl.PostTouchUp(TOUCH_CONTACT_JOYSTICK).Wait()
+		t.holding = false
+	}
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
+
+// Step holds the joystick for at least TOUCH_STEP_MIN_MS, as a shorter touch may not move the player
+func (t *touchMovement) Step(dir MoveDirection, durationMillis, delayMillis int) {
+	t.push(dir, !t.holding)
+	time.Sleep(time.Duration(max(durationMillis, TOUCH_STEP_MIN_MS)) * time.Millisecond)
+	if t.holding {
+		t.push(MOVE_FORWARD, false)
+	} else {
+		t.aw.ctrl.PostTouchUp(TOUCH_CONTACT_JOYSTICK).Wait()
+	}
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
+
+func (t *touchMovement) Sprint(delayMillis int) {
+	t.tap(TOUCH_SPRINT_X, TOUCH_SPRINT_Y, delayMillis)
+}
+
+// ToggleWalk switches the push distance of the joystick, as there is no walk button on touch screens
+func (t *touchMovement) ToggleWalk(delayMillis int) {
+	t.walking = !t.walking
+	if t.holding {
+		t.push(MOVE_FORWARD, false)
+	}
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
+
+// ResetMode releases the joystick and pushes it fully again on the next move, which runs
+func (t *touchMovement) ResetMode(delayMillis int) {
+	t.walking = false
+	t.Stop(delayMillis)
+}
+
+func (t *touchMovement) Jump(delayMillis int) {
+	t.tap(TOUCH_JUMP_X, TOUCH_JUMP_Y, delayMillis)
+}
+
+func (t *touchMovement) Interact(delayMillis int) {
+	t.tap(TOUCH_INTERACT_X, TOUCH_INTERACT_Y, delayMillis)
+}
+
+// TapKey maps the waypoint key to the touch control of the same function,
+// keys without such a control (ALT) are ignored
+func (t *touchMovement) TapKey(name string, delayMillis int) {
+	switch name {
+	case "W":
+		t.Step(MOVE_FORWARD, 0, delayMillis)
+	case "A":
+		t.Step(MOVE_LEFT, 0, delayMillis)
+	case "S":
+		t.Step(MOVE_BACKWARD, 0, delayMillis)
+	case "D":
+		t.Step(MOVE_RIGHT, 0, delayMillis)
+	case "SHIFT":
+		t.Sprint(delayMillis)
+	case "CTRL":
+		t.ToggleWalk(delayMillis)
+	case "SPACE":
+		t.Jump(delayMillis)
+	case "F":
+		t.Interact(delayMillis)
+	default:
+		log.Debug().Str("key", name).Msg("Key has no touch control, ignored")
+		time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+	}
+}
+
+// RotateCamera rotates the camera by horizontal swipes, split into strokes that fit on the screen.
+// Each stroke moves the touch gradually over its duration, as a single jump may be taken as a tap or ignored.
+func (t *touchMovement) RotateCamera(dx int, durationMillis, delayMillis int) {
+	ctrl := t.aw.ctrl
+	remaining := int(math.Round(float64(dx) * TOUCH_CAMERA_SCALE))
+	strokes := max(1, (abs(remaining)+TOUCH_CAMERA_MAX_SWIPE-1)/TOUCH_CAMERA_MAX_SWIPE)
+	strokeMillis := durationMillis / strokes
+	moves := max(1, strokeMillis/TOUCH_CAMERA_MOVE_INTERVAL_MS)
+	for range strokes {
+		stroke := max(-TOUCH_CAMERA_MAX_SWIPE, min(TOUCH_CAMERA_MAX_SWIPE, remaining))
+		remaining -= stroke
+
+		x0 := TOUCH_CAMERA_X - stroke/2
+		ctrl.PostTouchDown(TOUCH_CONTACT_CAMERA, int32(x0), TOUCH_CAMERA_Y, 1).Wait()
+		for i := 1; i <= moves; i++ {
+			time.Sleep(time.Duration(strokeMillis/moves) * time.Millisecond)
+			ctrl.PostTouchMove(TOUCH_CONTACT_CAMERA, int32(x0+stroke*i/moves), TOUCH_CAMERA_Y, 1).Wait()
+		}
+		ctrl.PostTouchUp(TOUCH_CONTACT_CAMERA).Wait()
+	}
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
+
+// ResetCamera does nothing but waits, as swipes leave no cursor to restore
+func (t *touchMovement) ResetCamera(delayMillis int) {
+	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type MapTrackerNavigate struct{}
+
+// MapTrackerNavigateParam represents the custom_action_param for MapTrackerNavigate
+type MapTrackerNavigateParam struct {
+	// Target is the [x, y] coordinate to reach (required).
+	Target *[2]int `json:"target"`
+	// MapTrackerMoveParam holds the map name and movement parameters (path and route are not allowed).
+	MapTrackerMoveParam
+}
+
+var _ maa.CustomActionRunner = &MapTrackerNavigate{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerNavigate) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	param, err := a.parseParam(arg.CustomActionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerNavigate")
+		return false
+	}
+	moveParam := &param.MapTrackerMoveParam
+
+	// Validate the movement parameters and set defaults before the first inference uses them,
+	// with the target standing in for the path until it is planned
+	moveParam.Path = makePath([][2]int{*param.Target})
+	if err := moveParam.normalize(); err != nil {
+		log.Error().Err(err).Msg("Invalid movement parameters for MapTrackerNavigate")
+		return false
+	}
+
+	// Load walkable mask
+	mask, err := getWalkableMask(moveParam.MapName)
+	if err != nil {
+		log.Error().Err(err).Str("map", moveParam.MapName).Msg("Failed to get walkable mask")
+		return false
+	}
+
+	// Get current location
+	ctrl := ctx.GetTasker().GetController()
+	cur, err := doInfer(ctx, ctrl, moveParam, moveParam.MapName)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to infer current location for navigation")
+		return false
+	}
+
+	// Plan path
+	path, err := planPath(mask, [2]int{cur.X, cur.Y}, *param.Target)
+	if err != nil {
+		log.Error().Err(err).
+			Int("fromX", cur.X).Int("fromY", cur.Y).
+			Int("toX", param.Target[0]).Int("toY", param.Target[1]).
+			Msg("Failed to plan navigation path")
+		return false
+	}
+	log.Info().Str("map", moveParam.MapName).
+		Int("fromX", cur.X).Int("fromY", cur.Y).
+		Int("toX", param.Target[0]).Int("toY", param.Target[1]).
+		Interface("path", path).
+		Msg("Navigation path planned")
+
+	// Drive the movement loop
+	moveParam.Path = makePath(path)
+	moveParam.Segments[0].Path = moveParam.Path
+	return runMove(ctx, arg.CurrentTaskName, moveParam)
+}
+
+func (a *MapTrackerNavigate) parseParam(paramStr string) (*MapTrackerNavigateParam, error) {
+	var param MapTrackerNavigateParam
+	if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters: %w", err)
+	}
+	if len(param.MapName) == 0 {
+		return nil, fmt.Errorf("map_name is required in parameters, got empty")
+	}
+	if param.Target == nil {
+		return nil, fmt.Errorf("target is required in parameters, got empty")
+	}
+	if len(param.Path) > 0 || param.Route != "" || len(param.Segments) > 0 {
+		return nil, fmt.Errorf("path, route and segments are not allowed, the path is planned automatically")
+	}
+	if param.PathTrim {
+		return nil, fmt.Errorf("path_trim is not allowed, the path always starts from the current location")
+	}
+	return &param, nil
+}
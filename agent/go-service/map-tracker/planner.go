@@ -0,0 +1,494 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"container/heap"
+	"fmt"
+	"image"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// WalkableMask represents the walkable area of a map, in the same coordinates as the map image
+type WalkableMask struct {
+	W, H int
+	// clearance is the chamfer distance (in 1/3 pixels) to the nearest obstacle, 0 means not walkable
+	clearance []uint16
+	// searches pools the *searchBuffers of path searches, which are as large as the mask
+	searches sync.Pool
+}
+
+// searchBuffers holds the per-cell state of a path search on a mask.
+// Buffers are reused across searches without being cleared, the cells not stamped with the current search are unvisited.
+type searchBuffers struct {
+	search   uint32   // Stamp of the current search
+	stamps   []uint32 // search if the cell is visited, search+1 if it is also closed
+	gScore   []float32
+	cameFrom []int32   // Only used by aStar
+	walked   []float32 // Only used by pathLengthsTo
+}
+
+// acquireSearch returns the buffers for a new search on the mask, which must be released after the search
+func (m *WalkableMask) acquireSearch() *searchBuffers {
+	b, _ := m.searches.Get().(*searchBuffers)
+	if b == nil {
+		b = &searchBuffers{stamps: make([]uint32, m.W*m.H), gScore: make([]float32, m.W*m.H)}
+	}
+	b.search += 2
+	if b.search < 2 {
+		// Stamps wrapped around, the cells stamped by old searches would look visited
+		clear(b.stamps)
+		b.search = 1
+	}
+	return b
+}
+
+// releaseSearch returns the buffers to the pool of the mask
+func (m *WalkableMask) releaseSearch(b *searchBuffers) {
+	m.searches.Put(b)
+}
+
+// score returns the best cost found to the cell in the current search
+func (b *searchBuffers) score(idx int32) float32 {
+	if b.stamps[idx] < b.search {
+		return math.MaxFloat32
+	}
+	return b.gScore[idx]
+}
+
+// visit records a better cost to the cell
+func (b *searchBuffers) visit(idx int32, g float32) {
+	b.stamps[idx] = b.search
+	b.gScore[idx] = g
+}
+
+// closed returns whether the cell has been settled in the current search
+func (b *searchBuffers) closed(idx int32) bool {
+	return b.stamps[idx] == b.search+1
+}
+
+// close marks the cell as settled
+func (b *searchBuffers) close(idx int32) {
+	b.stamps[idx] = b.search + 1
+}
+
+var (
+	walkableMasksMu sync.Mutex
+	walkableMasks   = make(map[string]*WalkableMask)
+)
+
+// getWalkableMask returns the cached walkable mask of the given map or loads it
+func getWalkableMask(mapName string) (*WalkableMask, error) {
+	walkableMasksMu.Lock()
+	defer walkableMasksMu.Unlock()
+
+	if mask, ok := walkableMasks[mapName]; ok {
+		return mask, nil
+	}
+
+	mask, err := loadWalkableMask(mapName)
+	if err != nil {
+		return nil, err
+	}
+	walkableMasks[mapName] = mask
+	log.Info().Str("map", mapName).Int("w", mask.W).Int("h", mask.H).Msg("Walkable mask loaded")
+	return mask, nil
+}
+
+// loadWalkableMask loads the walkable mask image of the given map.
+// Bright opaque pixels are walkable, while dark or transparent pixels are obstacles.
+func loadWalkableMask(mapName string) (*WalkableMask, error) {
+	walkableDir := findResource(WALKABLE_DIR)
+	if walkableDir == "" {
+		return nil, fmt.Errorf("walkable mask directory not found (searched in cache and standard locations)")
+	}
+
+	maskPath := filepath.Join(walkableDir, mapName+".png")
+	file, err := os.Open(maskPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open walkable mask of map %q: %w", mapName, err)
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode walkable mask of map %q: %w", mapName, err)
+	}
+	return newWalkableMask(img), nil
+}
+
+// newWalkableMask builds the walkable mask from the mask image, computing the clearance of each pixel
+func newWalkableMask(img image.Image) *WalkableMask {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	clearance := make([]uint16, w*h)
+	const inf = math.MaxUint16
+	for y := range h {
+		for x := range w {
+			r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			if a >= 0x8000 && (r+g+bl)/3 >= 0x8000 {
+				clearance[y*w+x] = inf
+			}
+		}
+	}
+
+	// Two-pass chamfer distance transform (3-4 weights)
+	relax := func(idx, nIdx int, cost uint16) {
+		if v := clearance[nIdx]; v < inf && v+cost < clearance[idx] {
+			clearance[idx] = v + cost
+		}
+	}
+	for y := range h {
+		for x := range w {
+			idx := y*w + x
+			if clearance[idx] == 0 {
+				continue
+			}
+			if x == 0 || y == 0 {
+				clearance[idx] = min(clearance[idx], 3)
+			}
+			if x > 0 {
+				relax(idx, idx-1, 3)
+			}
+			if y > 0 {
+				relax(idx, idx-w, 3)
+				if x > 0 {
+					relax(idx, idx-w-1, 4)
+				}
+				if x < w-1 {
+					relax(idx, idx-w+1, 4)
+				}
+			}
+		}
+	}
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			idx := y*w + x
+			if clearance[idx] == 0 {
+				continue
+			}
+			if x == w-1 || y == h-1 {
+				clearance[idx] = min(clearance[idx], 3)
+			}
+			if x < w-1 {
+				relax(idx, idx+1, 3)
+			}
+			if y < h-1 {
+				relax(idx, idx+w, 3)
+				if x < w-1 {
+					relax(idx, idx+w+1, 4)
+				}
+				if x > 0 {
+					relax(idx, idx+w-1, 4)
+				}
+			}
+		}
+	}
+
+	return &WalkableMask{W: w, H: h, clearance: clearance}
+}
+
+// Clearance returns the approximate distance in pixels from (x, y) to the nearest obstacle
+func (m *WalkableMask) Clearance(x, y int) float64 {
+	if x < 0 || y < 0 || x >= m.W || y >= m.H {
+		return 0
+	}
+	return float64(m.clearance[y*m.W+x]) / 3.0
+}
+
+// IsWalkable returns whether (x, y) is walkable
+func (m *WalkableMask) IsWalkable(x, y int) bool {
+	return m.Clearance(x, y) > 0
+}
+
+// snap finds the nearest walkable point to (x, y) within the given radius
+func (m *WalkableMask) snap(x, y, radius int) ([2]int, bool) {
+	if m.IsWalkable(x, y) {
+		return [2]int{x, y}, true
+	}
+	best, bestDist := [2]int{}, math.MaxFloat64
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			d := math.Hypot(float64(dx), float64(dy))
+			if d <= float64(radius) && d < bestDist && m.IsWalkable(x+dx, y+dy) {
+				best, bestDist = [2]int{x + dx, y + dy}, d
+			}
+		}
+	}
+	return best, bestDist < math.MaxFloat64
+}
+
+// planPath plans a walkable path from start to goal using A* search,
+// then simplifies it into waypoints that can be followed in straight lines
+func planPath(mask *WalkableMask, start, goal [2]int) ([][2]int, error) {
+	s, ok := mask.snap(start[0], start[1], NAV_SNAP_RADIUS)
+	if !ok {
+		return nil, fmt.Errorf("start point %v is not walkable", start)
+	}
+	g, ok := mask.snap(goal[0], goal[1], NAV_SNAP_RADIUS)
+	if !ok {
+		return nil, fmt.Errorf("goal point %v is not walkable", goal)
+	}
+
+	raw, err := mask.aStar(s, g)
+	if err != nil {
+		return nil, err
+	}
+	path := mask.simplifyPath(raw)
+
+	// Keep the exact goal if it was snapped, so the mover still aims at the requested point
+	if g != goal && math.Hypot(float64(g[0]-goal[0]), float64(g[1]-goal[1])) > 1 {
+		path = append(path, goal)
+	}
+	return path, nil
+}
+
+type aStarNode struct {
+	idx int32
+	f   float32
+}
+
+type aStarQueue []aStarNode
+
+func (q aStarQueue) Len() int           { return len(q) }
+func (q aStarQueue) Less(i, j int) bool { return q[i].f < q[j].f }
+func (q aStarQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *aStarQueue) Push(x any)        { *q = append(*q, x.(aStarNode)) }
+func (q *aStarQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// aStar runs an 8-connected A* search, penalizing cells close to obstacles
+func (m *WalkableMask) aStar(start, goal [2]int) ([][2]int, error) {
+	w, h := m.W, m.H
+	startIdx := int32(start[1]*w + start[0])
+	goalIdx := int32(goal[1]*w + goal[0])
+
+	b := m.acquireSearch()
+	defer m.releaseSearch(b)
+	if b.cameFrom == nil {
+		b.cameFrom = make([]int32, w*h)
+	}
+
+	heuristic := func(idx int32) float32 {
+		dx := math.Abs(float64(int(idx)%w - goal[0]))
+		dy := math.Abs(float64(int(idx)/w - goal[1]))
+		return float32(max(dx, dy) + (math.Sqrt2-1)*min(dx, dy))
+	}
+
+	dirs := [8][3]float64{
+		{1, 0, 1}, {-1, 0, 1}, {0, 1, 1}, {0, -1, 1},
+		{1, 1, math.Sqrt2}, {1, -1, math.Sqrt2}, {-1, 1, math.Sqrt2}, {-1, -1, math.Sqrt2},
+	}
+
+	q := &aStarQueue{{startIdx, heuristic(startIdx)}}
+	b.visit(startIdx, 0)
+	b.cameFrom[startIdx] = -1
+
+	for q.Len() > 0 {
+		cur := heap.Pop(q).(aStarNode)
+		if b.closed(cur.idx) {
+			continue
+		}
+		if cur.idx == goalIdx {
+			break
+		}
+		b.close(cur.idx)
+
+		cx, cy := int(cur.idx)%w, int(cur.idx)/w
+		for _, d := range dirs {
+			nx, ny := cx+int(d[0]), cy+int(d[1])
+			if nx < 0 || ny < 0 || nx >= w || ny >= h {
+				continue
+			}
+			nIdx := int32(ny*w + nx)
+			if b.closed(nIdx) || m.clearance[nIdx] == 0 {
+				continue
+			}
+			// Forbid cutting corners between two obstacles
+			if d[2] > 1 && (m.clearance[cy*w+nx] == 0 || m.clearance[ny*w+cx] == 0) {
+				continue
+			}
+
+			cost := d[2]
+			if c := m.Clearance(nx, ny); c < NAV_PREFERRED_CLEARANCE {
+				cost *= 1 + NAV_CLEARANCE_PENALTY*(NAV_PREFERRED_CLEARANCE-c)/NAV_PREFERRED_CLEARANCE
+			}
+			ng := b.score(cur.idx) + float32(cost)
+			if ng < b.score(nIdx) {
+				b.visit(nIdx, ng)
+				b.cameFrom[nIdx] = cur.idx
+				heap.Push(q, aStarNode{nIdx, ng + heuristic(nIdx)})
+			}
+		}
+	}
+
+	if b.score(goalIdx) == math.MaxFloat32 {
+		return nil, fmt.Errorf("no walkable path from %v to %v", start, goal)
+	}
+
+	path := make([][2]int, 0)
+	for idx := goalIdx; idx != -1; idx = b.cameFrom[idx] {
+		path = append(path, [2]int{int(idx) % w, int(idx) / w})
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, nil
+}
+
+// pathLengthsTo measures the walking distances from each point to goal along the paths aStar would find,
+// by one Dijkstra search from goal that stops once every point is settled.
+// The search runs backwards, so each step costs as entering the cell it comes from. Returns +Inf for unreachable points.
+func (m *WalkableMask) pathLengthsTo(goal [2]int, froms [][2]int) []float64 {
+	lengths := make([]float64, len(froms))
+	for i := range lengths {
+		lengths[i] = math.Inf(1)
+	}
+	g, ok := m.snap(goal[0], goal[1], NAV_SNAP_RADIUS)
+	if !ok {
+		return lengths
+	}
+
+	// Points are snapped like planPath does, the snapping distance is walked in a straight line
+	w, h := m.W, m.H
+	pending := make(map[int32][]int)
+	for i, from := range froms {
+		s, ok := m.snap(from[0], from[1], NAV_SNAP_RADIUS)
+		if !ok {
+			continue
+		}
+		idx := int32(s[1]*w + s[0])
+		pending[idx] = append(pending[idx], i)
+		lengths[i] = math.Hypot(float64(s[0]-from[0]), float64(s[1]-from[1])) +
+			math.Hypot(float64(goal[0]-g[0]), float64(goal[1]-g[1]))
+	}
+
+	b := m.acquireSearch()
+	defer m.releaseSearch(b)
+	if b.walked == nil {
+		b.walked = make([]float32, w*h) // Unpenalized length of the best path found to each cell
+	}
+
+	dirs := [8][3]float64{
+		{1, 0, 1}, {-1, 0, 1}, {0, 1, 1}, {0, -1, 1},
+		{1, 1, math.Sqrt2}, {1, -1, math.Sqrt2}, {-1, 1, math.Sqrt2}, {-1, -1, math.Sqrt2},
+	}
+
+	goalIdx := int32(g[1]*w + g[0])
+	q := &aStarQueue{{goalIdx, 0}}
+	b.visit(goalIdx, 0)
+	b.walked[goalIdx] = 0
+
+	for q.Len() > 0 && len(pending) > 0 {
+		cur := heap.Pop(q).(aStarNode)
+		if b.closed(cur.idx) {
+			continue
+		}
+		b.close(cur.idx)
+		if idxs, ok := pending[cur.idx]; ok {
+			for _, i := range idxs {
+				lengths[i] += float64(b.walked[cur.idx])
+			}
+			delete(pending, cur.idx)
+		}
+
+		cx, cy := int(cur.idx)%w, int(cur.idx)/w
+		cost := 1.0
+		if c := m.Clearance(cx, cy); c < NAV_PREFERRED_CLEARANCE {
+			cost = 1 + NAV_CLEARANCE_PENALTY*(NAV_PREFERRED_CLEARANCE-c)/NAV_PREFERRED_CLEARANCE
+		}
+		for _, d := range dirs {
+			nx, ny := cx+int(d[0]), cy+int(d[1])
+			if nx < 0 || ny < 0 || nx >= w || ny >= h {
+				continue
+			}
+			nIdx := int32(ny*w + nx)
+			if b.closed(nIdx) || m.clearance[nIdx] == 0 {
+				continue
+			}
+			// Forbid cutting corners between two obstacles
+			if d[2] > 1 && (m.clearance[cy*w+nx] == 0 || m.clearance[ny*w+cx] == 0) {
+				continue
+			}
+
+			ng := b.score(cur.idx) + float32(d[2]*cost)
+			if ng < b.score(nIdx) {
+				b.visit(nIdx, ng)
+				b.walked[nIdx] = b.walked[cur.idx] + float32(d[2])
+				heap.Push(q, aStarNode{nIdx, ng})
+			}
+		}
+	}
+
+	// Points never settled are unreachable
+	for _, idxs := range pending {
+		for _, i := range idxs {
+			lengths[i] = math.Inf(1)
+		}
+	}
+	return lengths
+}
+
+// simplifyPath greedily merges raw path cells into straight segments.
+// A segment is accepted only if every cell on it keeps at least the clearance
+// of the raw path it replaces, and it is not longer than NAV_MAX_SEGMENT_LENGTH.
+func (m *WalkableMask) simplifyPath(raw [][2]int) [][2]int {
+	if len(raw) <= 2 {
+		return raw[len(raw)-1:]
+	}
+
+	// The first point is the current location, which is omitted from the waypoints
+	result := make([][2]int, 0)
+	anchor := 0
+	for anchor < len(raw)-1 {
+		next := anchor + 1
+		minClearance := math.MaxFloat64
+		for j := anchor + 1; j < len(raw); j++ {
+			minClearance = min(minClearance, m.Clearance(raw[j][0], raw[j][1]))
+			a, b := raw[anchor], raw[j]
+			if math.Hypot(float64(b[0]-a[0]), float64(b[1]-a[1])) > NAV_MAX_SEGMENT_LENGTH {
+				break
+			}
+			if m.lineClear(a, b, min(minClearance, NAV_PREFERRED_CLEARANCE)) {
+				next = j
+			}
+		}
+		result = append(result, raw[next])
+		anchor = next
+	}
+	return result
+}
+
+// lineClear checks whether every cell on the line from a to b has at least the given clearance
+func (m *WalkableMask) lineClear(a, b [2]int, minClearance float64) bool {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	steps := max(abs(dx), abs(dy))
+	for i := 0; i <= steps; i++ {
+		t := 0.0
+		if steps > 0 {
+			t = float64(i) / float64(steps)
+		}
+		x := a[0] + int(math.Round(float64(dx)*t))
+		y := a[1] + int(math.Round(float64(dy)*t))
+		if c := m.Clearance(x, y); c == 0 || c < minClearance {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
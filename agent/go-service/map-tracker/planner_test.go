@@ -0,0 +1,181 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// testMask builds a w x h walkable mask, where the pixels accepted by blocked are obstacles
+func testMask(w, h int, blocked func(x, y int) bool) *WalkableMask {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			if !blocked(x, y) {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return newWalkableMask(img)
+}
+
+func TestPlanPath(t *testing.T) {
+	tests := []struct {
+		name        string
+		blocked     func(x, y int) bool
+		start, goal [2]int
+		wantErr     bool
+	}{
+		{
+			name:    "open field",
+			blocked: func(x, y int) bool { return false },
+			start:   [2]int{10, 30}, goal: [2]int{150, 30},
+		},
+		{
+			name:    "wall with a gap",
+			blocked: func(x, y int) bool { return x >= 78 && x <= 82 && y < 45 },
+			start:   [2]int{20, 10}, goal: [2]int{140, 10},
+		},
+		{
+			name: "walled room with a door",
+			blocked: func(x, y int) bool {
+				inRing := x >= 60 && x <= 100 && y >= 10 && y <= 50 && !(x > 63 && x < 97 && y > 13 && y < 47)
+				isDoor := x >= 60 && x <= 63 && y >= 26 && y <= 34
+				return inRing && !isDoor
+			},
+			start: [2]int{10, 30}, goal: [2]int{90, 30},
+		},
+		{
+			name: "walled room without a door",
+			blocked: func(x, y int) bool {
+				return x >= 60 && x <= 100 && y >= 10 && y <= 50 && !(x > 63 && x < 97 && y > 13 && y < 47)
+			},
+			start: [2]int{10, 30}, goal: [2]int{80, 30},
+			wantErr: true,
+		},
+		{
+			name:    "goal snapped out of an obstacle",
+			blocked: func(x, y int) bool { return x >= 100 && x <= 130 },
+			start:   [2]int{10, 30}, goal: [2]int{105, 30},
+		},
+		{
+			name:    "start too far from walkable area",
+			blocked: func(x, y int) bool { return x < 60 },
+			start:   [2]int{10, 30}, goal: [2]int{120, 30},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mask := testMask(160, 60, tt.blocked)
+			path, err := planPath(mask, tt.start, tt.goal)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("planPath(%v, %v) = %v, want error", tt.start, tt.goal, path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planPath(%v, %v) failed: %v", tt.start, tt.goal, err)
+			}
+			if len(path) == 0 || path[len(path)-1] != tt.goal {
+				t.Fatalf("planPath(%v, %v) = %v, want it to end at the goal", tt.start, tt.goal, path)
+			}
+
+			// Every segment must be walkable and short enough, except the last one if the goal was snapped
+			prev := tt.start
+			for i, p := range path {
+				if i == len(path)-1 && !mask.IsWalkable(p[0], p[1]) {
+					break
+				}
+				if !mask.lineClear(prev, p, 0) {
+					t.Errorf("segment %v -> %v crosses an obstacle in %v", prev, p, path)
+				}
+				if d := math.Hypot(float64(p[0]-prev[0]), float64(p[1]-prev[1])); d > NAV_MAX_SEGMENT_LENGTH+1 {
+					t.Errorf("segment %v -> %v is %.1f long, want at most %v", prev, p, d, NAV_MAX_SEGMENT_LENGTH)
+				}
+				prev = p
+			}
+		})
+	}
+}
+
+func TestAStarIsShortest(t *testing.T) {
+	// Without obstacles nearby, the 8-connected path length is the octile distance
+	mask := testMask(100, 100, func(x, y int) bool { return false })
+	tests := []struct {
+		start, goal [2]int
+	}{
+		{[2]int{50, 50}, [2]int{50, 50}},
+		{[2]int{20, 50}, [2]int{80, 50}},
+		{[2]int{20, 20}, [2]int{80, 80}},
+		{[2]int{20, 30}, [2]int{70, 80}},
+		{[2]int{80, 20}, [2]int{30, 60}},
+	}
+	for _, tt := range tests {
+		raw, err := mask.aStar(tt.start, tt.goal)
+		if err != nil {
+			t.Fatalf("aStar(%v, %v) failed: %v", tt.start, tt.goal, err)
+		}
+		if raw[0] != tt.start || raw[len(raw)-1] != tt.goal {
+			t.Fatalf("aStar(%v, %v) = %v, want it from start to goal", tt.start, tt.goal, raw)
+		}
+		length := 0.0
+		for i := 1; i < len(raw); i++ {
+			length += math.Hypot(float64(raw[i][0]-raw[i-1][0]), float64(raw[i][1]-raw[i-1][1]))
+		}
+		dx := math.Abs(float64(tt.goal[0] - tt.start[0]))
+		dy := math.Abs(float64(tt.goal[1] - tt.start[1]))
+		want := max(dx, dy) + (math.Sqrt2-1)*min(dx, dy)
+		if math.Abs(length-want) > 1e-6 {
+			t.Errorf("aStar(%v, %v) length = %.3f, want %.3f", tt.start, tt.goal, length, want)
+		}
+	}
+}
+
+func TestSearchBuffersReuse(t *testing.T) {
+	mask := testMask(160, 60, func(x, y int) bool { return x >= 78 && x <= 82 && y < 45 })
+	start, goal := [2]int{20, 10}, [2]int{140, 10}
+	want, err := mask.aStar(start, goal)
+	if err != nil {
+		t.Fatalf("aStar(%v, %v) failed: %v", start, goal, err)
+	}
+	wantLengths := mask.pathLengthsTo(goal, [][2]int{start, {150, 50}})
+
+	tests := []struct {
+		name   string
+		search uint32 // Stamp of the last search on the pooled buffers, 0 to keep it
+	}{
+		{"reused", 0},
+		{"stamps wrapped around", math.MaxUint32 - 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Leave the buffers of a search to another goal in the pool
+			if _, err := mask.aStar(start, [2]int{150, 50}); err != nil {
+				t.Fatalf("aStar() failed: %v", err)
+			}
+			if tt.search != 0 {
+				b := mask.acquireSearch()
+				b.search = tt.search
+				mask.releaseSearch(b)
+			}
+
+			got, err := mask.aStar(start, goal)
+			if err != nil {
+				t.Fatalf("aStar(%v, %v) failed: %v", start, goal, err)
+			}
+			if len(got) != len(want) || got[len(got)-1] != goal {
+				t.Errorf("aStar(%v, %v) = %v, want %v", start, goal, got, want)
+			}
+			gotLengths := mask.pathLengthsTo(goal, [][2]int{start, {150, 50}})
+			for i := range wantLengths {
+				if math.Abs(gotLengths[i]-wantLengths[i]) > 1e-3 {
+					t.Errorf("pathLengthsTo() = %v, want %v", gotLengths, wantLengths)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"math"
+)
+
+// SteeringMode is the way MapTrackerMove steers the player along the path
+type SteeringMode string
+
+const (
+	// STEERING_WAYPOINT steers straight to one target point at a time
+	STEERING_WAYPOINT SteeringMode = "waypoint"
+	// STEERING_PURSUIT steers to a lookahead point on the path (pure pursuit)
+	STEERING_PURSUIT SteeringMode = "pursuit"
+)
+
+// pursue computes the pure-pursuit steering while heading to the target point at index i.
+// Returns the lookahead point, the length of the straight path ahead (used for sprinting),
+// and whether the target point has already been passed.
+// Target points with actions and the last point are never cut, the lookahead stops at them.
+func (m *mover) pursue(path []MapTrackerPoint, i, curX, curY int) ([2]int, float64, bool) {
+	px, py := float64(curX), float64(curY)
+	if i == 0 {
+		// No previous point to pursue from, head to the first point directly
+		return [2]int{path[0].X, path[0].Y}, math.Hypot(float64(path[0].X)-px, float64(path[0].Y)-py), false
+	}
+
+	// Find the next point that must be reached exactly
+	stop := i
+	for stop < len(path)-1 && len(path[stop].Actions) == 0 {
+		stop++
+	}
+
+	// Project the current location onto the path around the target point
+	bestK, bestT, bestD := i-1, 0.0, math.MaxFloat64
+	for k := i - 1; k <= min(i, stop-1); k++ {
+		t, d := projectOnSegment(path[k], path[k+1], px, py)
+		if d < bestD {
+			bestK, bestT, bestD = k, t, d
+		}
+	}
+	passed := bestK >= i
+	ax, ay := float64(path[bestK].X), float64(path[bestK].Y)
+	bx, by := float64(path[bestK+1].X), float64(path[bestK+1].Y)
+	projX, projY := ax+(bx-ax)*bestT, ay+(by-ay)*bestT
+
+	// Walk along the path by the lookahead distance, interpolating between points
+	remaining := PURSUIT_LOOKAHEAD_MIN + m.movement.Speed*PURSUIT_LOOKAHEAD_TIME
+	lookX, lookY := projX, projY
+	for k := bestK; k < stop; k++ {
+		nx, ny := float64(path[k+1].X), float64(path[k+1].Y)
+		segLen := math.Hypot(nx-lookX, ny-lookY)
+		if segLen >= remaining {
+			lookX += (nx - lookX) * remaining / segLen
+			lookY += (ny - lookY) * remaining / segLen
+			break
+		}
+		remaining -= segLen
+		lookX, lookY = nx, ny
+	}
+
+	// Measure the straight section ahead, ending at a sharp turn or a point to reach exactly
+	straight := math.Hypot(bx-projX, by-projY)
+	for j := bestK + 1; j < stop; j++ {
+		inRot := calcTargetRotation(path[j-1].X, path[j-1].Y, path[j].X, path[j].Y)
+		outRot := calcTargetRotation(path[j].X, path[j].Y, path[j+1].X, path[j+1].Y)
+		if math.Abs(calcDeltaRotation(inRot, outRot)) > m.param.RotationUpperThreshold {
+			break
+		}
+		straight += math.Hypot(float64(path[j+1].X-path[j].X), float64(path[j+1].Y-path[j].Y))
+	}
+
+	return [2]int{int(math.Round(lookX)), int(math.Round(lookY))}, straight, passed
+}
+
+// projectOnSegment projects a point onto the segment from a to b,
+// returns the interpolation ratio in [0, 1] and the distance to the projection
+func projectOnSegment(a, b MapTrackerPoint, px, py float64) (float64, float64) {
+	ax, ay := float64(a.X), float64(a.Y)
+	dx, dy := float64(b.X)-ax, float64(b.Y)-ay
+	lenSq := dx*dx + dy*dy
+	t := 0.0
+	if lenSq > 1e-9 {
+		t = max(0.0, min(1.0, ((px-ax)*dx+(py-ay)*dy)/lenSq))
+	}
+	return t, math.Hypot(ax+dx*t-px, ay+dy*t-py)
+}
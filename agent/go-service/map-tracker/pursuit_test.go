@@ -0,0 +1,73 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPursue(t *testing.T) {
+	straight := makePath([][2]int{{0, 0}, {20, 0}, {40, 0}, {60, 0}})
+	withAction := makePath([][2]int{{0, 0}, {20, 0}, {40, 0}})
+	withAction[1].Actions = []MapTrackerWaypointAction{{Key: "F"}}
+	corner := makePath([][2]int{{0, 0}, {20, 0}, {20, 20}, {20, 40}})
+	gentle := makePath([][2]int{{0, 0}, {20, 0}, {40, 10}})
+
+	// Running, the lookahead is PURSUIT_LOOKAHEAD_MIN + 8 * PURSUIT_LOOKAHEAD_TIME = 12 px
+	m := &mover{movement: &MovementRun, param: &MapTrackerMoveParam{RotationUpperThreshold: 60}}
+
+	tests := []struct {
+		name         string
+		path         []MapTrackerPoint
+		i            int
+		cur          [2]int
+		wantLook     [2]int
+		wantStraight float64
+		wantPassed   bool
+	}{
+		{"first point", straight, 0, [2]int{-10, 0}, [2]int{0, 0}, 10, false},
+		{"on a straight path", straight, 1, [2]int{5, 1}, [2]int{17, 0}, 55, false},
+		{"cuts through the target point", straight, 1, [2]int{18, 0}, [2]int{30, 0}, 42, false},
+		{"target point passed", straight, 1, [2]int{25, 1}, [2]int{37, 0}, 35, true},
+		{"stops at a point with actions", withAction, 1, [2]int{18, 0}, [2]int{20, 0}, 2, false},
+		{"stops at the last point", straight, 3, [2]int{55, 0}, [2]int{60, 0}, 5, false},
+		{"sharp turn ends the straight", corner, 1, [2]int{5, 0}, [2]int{17, 0}, 15, false},
+		{"lookahead rounds the corner", corner, 1, [2]int{15, 0}, [2]int{20, 7}, 5, false},
+		{"gentle turn keeps the straight", gentle, 1, [2]int{5, 0}, [2]int{17, 0}, 15 + math.Hypot(20, 10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			look, straight, passed := m.pursue(tt.path, tt.i, tt.cur[0], tt.cur[1])
+			if look != tt.wantLook || math.Abs(straight-tt.wantStraight) > 1e-6 || passed != tt.wantPassed {
+				t.Errorf("pursue(%d, %v) = %v, %.2f, %v, want %v, %.2f, %v",
+					tt.i, tt.cur, look, straight, passed, tt.wantLook, tt.wantStraight, tt.wantPassed)
+			}
+		})
+	}
+}
+
+func TestProjectOnSegment(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     [2]int
+		p        [2]float64
+		wantT    float64
+		wantDist float64
+	}{
+		{"middle", [2]int{0, 0}, [2]int{10, 0}, [2]float64{4, 3}, 0.4, 3},
+		{"before the start", [2]int{0, 0}, [2]int{10, 0}, [2]float64{-3, 4}, 0, 5},
+		{"after the end", [2]int{0, 0}, [2]int{10, 0}, [2]float64{13, -4}, 1, 5},
+		{"diagonal", [2]int{0, 0}, [2]int{10, 10}, [2]float64{10, 0}, 0.5, math.Sqrt(50)},
+		{"degenerate segment", [2]int{2, 2}, [2]int{2, 2}, [2]float64{5, 6}, 0, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := MapTrackerPoint{X: tt.a[0], Y: tt.a[1]}
+			b := MapTrackerPoint{X: tt.b[0], Y: tt.b[1]}
+			gotT, gotDist := projectOnSegment(a, b, tt.p[0], tt.p[1])
+			if math.Abs(gotT-tt.wantT) > 1e-9 || math.Abs(gotDist-tt.wantDist) > 1e-9 {
+				t.Errorf("projectOnSegment(%v, %v, %v) = %v, %v, want %v, %v", tt.a, tt.b, tt.p, gotT, gotDist, tt.wantT, tt.wantDist)
+			}
+		})
+	}
+}
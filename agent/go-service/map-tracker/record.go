@@ -0,0 +1,328 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type MapTrackerRecord struct{}
+
+// MapTrackerRecordParam represents the custom_action_param for MapTrackerRecord
+type MapTrackerRecordParam struct {
+	// Name is the name of the recorded route, defaults to "record_<datetime>".
+	Name string `json:"name,omitempty"`
+	// MapNameRegex is a regex pattern to filter which maps to consider during recording.
+	MapNameRegex string `json:"map_name_regex,omitempty"`
+	// SaveToStore saves the route as the next version in the route store instead of as a plain recording.
+	SaveToStore bool `json:"save_to_store,omitempty"`
+	// SampleDistance is the minimum distance between two sampled points.
+	SampleDistance float64 `json:"sample_distance,omitempty"`
+	// Tolerance is the maximum deviation allowed when simplifying the sampled path.
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// MaxDuration is the maximum recording time in milliseconds, 0 means unlimited.
+	MaxDuration int64 `json:"max_duration,omitempty"`
+	// StopKey is the virtual-key code of the host hotkey that stops recording, -1 to disable.
+	StopKey int `json:"stop_key,omitempty"`
+	// NoPrint controls whether to suppress printing recording status to the GUI.
+	NoPrint bool `json:"no_print,omitempty"`
+}
+
+//go:embed messages/recording_started.html
+var recordingStartedHTML string
+
+//go:embed messages/recording_finished.html
+var recordingFinishedHTML string
+
+var _ maa.CustomActionRunner = &MapTrackerRecord{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerRecord) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	param, err := a.parseParam(arg.CustomActionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerRecord")
+		return false
+	}
+
+	ctrl := ctx.GetTasker().GetController()
+	inferParam := &MapTrackerMoveParam{
+		InferFilter:     DEFAULT_INFERENCE_PARAM_FOR_MOVE.Filter,
+		InferSearchMode: DEFAULT_INFERENCE_PARAM_FOR_MOVE.SearchMode,
+	}
+
+	if !param.NoPrint {
+		hint := "停止任务以结束录制。"
+		if param.StopKey > 0 {
+			hint = fmt.Sprintf("按下热键（虚拟键码 0x%02X）或停止任务以结束录制。", param.StopKey)
+		}
+		maafocus.NodeActionStarting(ctx, fmt.Sprintf(recordingStartedHTML, hint))
+	}
+	log.Info().Str("name", param.Name).Int("stopKey", param.StopKey).Msg("Starting route recording")
+
+	// Sample positions until stopped
+	var segments []MapTrackerMoveSegment
+	samplesCount := 0
+	loopInterval := time.Duration(INFER_INTERVAL_MS) * time.Millisecond
+	startTime := time.Now()
+	for {
+		time.Sleep(loopInterval)
+
+		if ctx.GetTasker().Stopping() {
+			log.Info().Msg("Task is stopping, finishing route recording")
+			break
+		}
+		if isHotkeyPressed(param.StopKey) {
+			log.Info().Msg("Stop hotkey pressed, finishing route recording")
+			break
+		}
+		if param.MaxDuration > 0 && time.Since(startTime).Milliseconds() > param.MaxDuration {
+			log.Info().Msg("Max duration reached, finishing route recording")
+			break
+		}
+
+		result, err := doInferRegex(ctx, ctrl, inferParam, param.MapNameRegex)
+		if err != nil || result.InferMode == string(VIRTUAL_HIT) {
+			continue
+		}
+
+		point := MapTrackerPoint{X: result.X, Y: result.Y}
+		if len(segments) == 0 || segments[len(segments)-1].MapName != result.MapName {
+			log.Info().Str("map", result.MapName).Int("x", result.X).Int("y", result.Y).Msg("Recording on new map")
+			segments = append(segments, MapTrackerMoveSegment{MapName: result.MapName, Path: []MapTrackerPoint{point}})
+			samplesCount++
+			continue
+		}
+		seg := &segments[len(segments)-1]
+		last := seg.Path[len(seg.Path)-1]
+		if math.Hypot(float64(point.X-last.X), float64(point.Y-last.Y)) >= param.SampleDistance {
+			seg.Path = append(seg.Path, point)
+			samplesCount++
+		}
+	}
+
+	if len(segments) == 0 {
+		log.Error().Msg("No position recorded")
+		return false
+	}
+
+	// Simplify and save
+	route := MapTrackerRoute{Name: param.Name, Version: 1}
+	pointsCount := 0
+	for i := range segments {
+		segments[i].Path = simplifyPathDP(segments[i].Path, param.Tolerance)
+		pointsCount += len(segments[i].Path)
+	}
+	if len(segments) == 1 {
+		route.MapName = segments[0].MapName
+		route.Path = segments[0].Path
+	} else {
+		if err := validateSegments(segments); err != nil {
+			log.Error().Err(err).Msg("Invalid recorded segments")
+			return false
+		}
+		route.Segments = segments
+	}
+
+	path, err := saveRecordedRoute(&route, param.SaveToStore)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to save recorded route")
+		return false
+	}
+
+	moveParam, _ := json.Marshal(MapTrackerRoute{MapName: route.MapName, Path: route.Path, Segments: route.Segments})
+	log.Info().Str("name", route.Name).Int("version", route.Version).
+		Int("samplesCount", samplesCount).Int("pointsCount", pointsCount).
+		Str("path", path).RawJSON("param", moveParam).
+		Msg("Route recording finished")
+
+	if !param.NoPrint {
+		maafocus.NodeActionStarting(ctx, fmt.Sprintf(recordingFinishedHTML, samplesCount, pointsCount, path))
+	}
+	return true
+}
+
+var recordSaveMu sync.Mutex
+
+// saveRecordedRoute writes the route as JSON without overwriting any file, returns the file path.
+// When saving to the route store, the route is written into the user route directory with the version
+// next to the latest one in the store and on disk, and added to the store to take effect immediately.
+func saveRecordedRoute(route *MapTrackerRoute, toStore bool) (string, error) {
+	recordSaveMu.Lock()
+	defer recordSaveMu.Unlock()
+
+	if !toStore {
+		data, err := json.MarshalIndent(route, "", "    ")
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(RECORD_OUTPUT_DIR, 0755); err != nil {
+			return "", err
+		}
+		// Take the first free file name, as recordings may share a name
+		for n := 1; ; n++ {
+			fileName := route.Name + ".json"
+			if n > 1 {
+				fileName = fmt.Sprintf("%s_%d.json", route.Name, n)
+			}
+			path := filepath.Join(RECORD_OUTPUT_DIR, fileName)
+			if err := writeFileExclusive(path, data); err == nil {
+				return path, nil
+			} else if !os.IsExist(err) {
+				return "", err
+			}
+		}
+	}
+
+	store, err := getRouteStore()
+	if err != nil {
+		return "", err
+	}
+	version := 0
+	if latest, err := store.Get(route.Name, 0); err == nil {
+		version = latest.Version
+	}
+	if onDisk := latestRecordedVersion(route.Name); onDisk > version {
+		version = onDisk
+	}
+	route.Version = version + 1
+
+	data, err := json.MarshalIndent(route, "", "    ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(USER_ROUTE_DIR, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(USER_ROUTE_DIR, fmt.Sprintf("%s_v%d.json", route.Name, route.Version))
+	if err := writeFileExclusive(path, data); err != nil {
+		return "", err
+	}
+	store.add(*route)
+	return path, nil
+}
+
+// latestRecordedVersion returns the highest version of the route name among the files in the user route directory
+func latestRecordedVersion(name string) int {
+	entries, err := os.ReadDir(USER_ROUTE_DIR)
+	if err != nil {
+		return 0
+	}
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(name) + `_v(\d+)\.json$`)
+	latest := 0
+	for _, entry := range entries {
+		if m := pattern.FindStringSubmatch(entry.Name()); m != nil {
+			if v, err := strconv.Atoi(m[1]); err == nil {
+				latest = max(latest, v)
+			}
+		}
+	}
+	return latest
+}
+
+// writeFileExclusive writes data to a new file, failing with an error satisfying os.IsExist if it exists
+func writeFileExclusive(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// simplifyPathDP removes redundant points with the Douglas-Peucker algorithm,
+// keeping every point that deviates from the simplified polyline by more than tolerance
+func simplifyPathDP(path []MapTrackerPoint, tolerance float64) []MapTrackerPoint {
+	if len(path) <= 2 {
+		return path
+	}
+
+	keep := make([]bool, len(path))
+	keep[0], keep[len(path)-1] = true, true
+
+	var simplify func(lo, hi int)
+	simplify = func(lo, hi int) {
+		if hi-lo < 2 {
+			return
+		}
+		// Distances are measured to the segment rather than its line, so that turnarounds are kept
+		maxDist, maxIdx := -1.0, lo
+		for i := lo + 1; i < hi; i++ {
+			_, d := projectOnSegment(path[lo], path[hi], float64(path[i].X), float64(path[i].Y))
+			if d > maxDist {
+				maxDist, maxIdx = d, i
+			}
+		}
+		if maxDist > tolerance {
+			keep[maxIdx] = true
+			simplify(lo, maxIdx)
+			simplify(maxIdx, hi)
+		}
+	}
+	simplify(0, len(path)-1)
+
+	result := make([]MapTrackerPoint, 0, len(path))
+	for i, p := range path {
+		if keep[i] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func (a *MapTrackerRecord) parseParam(paramStr string) (*MapTrackerRecordParam, error) {
+	var param MapTrackerRecordParam
+	if paramStr != "" {
+		if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+			return nil, fmt.Errorf("failed to parse parameters: %w", err)
+		}
+	}
+
+	if param.Name == "" {
+		param.Name = "record_" + time.Now().Format("20060102_150405")
+	} else if filepath.Base(param.Name) != param.Name {
+		return nil, fmt.Errorf("name must not contain path separators")
+	}
+
+	if param.MapNameRegex == "" {
+		param.MapNameRegex = DEFAULT_RECORD_PARAM.MapNameRegex
+	} else if _, err := regexp.Compile(param.MapNameRegex); err != nil {
+		return nil, fmt.Errorf("invalid map_name_regex: %w", err)
+	}
+
+	if param.SampleDistance < 0 {
+		return nil, fmt.Errorf("sample_distance must be non-negative")
+	} else if param.SampleDistance == 0 {
+		param.SampleDistance = DEFAULT_RECORD_PARAM.SampleDistance
+	}
+
+	if param.Tolerance < 0 {
+		return nil, fmt.Errorf("tolerance must be non-negative")
+	} else if param.Tolerance == 0 {
+		param.Tolerance = DEFAULT_RECORD_PARAM.Tolerance
+	}
+
+	if param.MaxDuration < 0 {
+		return nil, fmt.Errorf("max_duration must be non-negative")
+	}
+
+	if param.StopKey == 0 {
+		param.StopKey = DEFAULT_RECORD_PARAM.StopKey
+	}
+
+	return &param, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSimplifyPathDP(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      [][2]int
+		tolerance float64
+		want      [][2]int
+	}{
+		{"empty", [][2]int{}, 2, [][2]int{}},
+		{"single point", [][2]int{{3, 4}}, 2, [][2]int{{3, 4}}},
+		{"two points", [][2]int{{0, 0}, {10, 0}}, 2, [][2]int{{0, 0}, {10, 0}}},
+		{"collinear", [][2]int{{0, 0}, {5, 0}, {10, 0}, {15, 0}, {20, 0}}, 2, [][2]int{{0, 0}, {20, 0}}},
+		{"jitter within tolerance", [][2]int{{0, 0}, {5, 1}, {10, -1}, {15, 1}, {20, 0}}, 2, [][2]int{{0, 0}, {20, 0}}},
+		{"deviation on tolerance", [][2]int{{0, 0}, {5, 2}, {10, 0}}, 2, [][2]int{{0, 0}, {10, 0}}},
+		{"corner", [][2]int{{0, 0}, {5, 0}, {10, 0}, {10, 5}, {10, 10}}, 2, [][2]int{{0, 0}, {10, 0}, {10, 10}}},
+		{"zigzag over tolerance", [][2]int{{0, 0}, {5, 5}, {10, 0}, {15, 5}, {20, 0}}, 2, [][2]int{{0, 0}, {5, 5}, {10, 0}, {15, 5}, {20, 0}}},
+		{"zero tolerance keeps turns", [][2]int{{0, 0}, {1, 0}, {2, 0}, {2, 1}}, 0, [][2]int{{0, 0}, {2, 0}, {2, 1}}},
+		{"closed loop", [][2]int{{0, 0}, {10, 0}, {10, 10}, {0, 0}}, 2, [][2]int{{0, 0}, {10, 0}, {10, 10}, {0, 0}}},
+		{"back and forth", [][2]int{{0, 0}, {20, 0}, {1, 0}}, 2, [][2]int{{0, 0}, {20, 0}, {1, 0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := makePath(tt.path)
+			got := simplifyPathDP(path, tt.tolerance)
+			if !slices.Equal(pathCoords(got), tt.want) {
+				t.Fatalf("simplifyPathDP(%v, %v) = %v, want %v", tt.path, tt.tolerance, pathCoords(got), tt.want)
+			}
+
+			// Every dropped point must lie within tolerance of the simplified polyline between its kept neighbours
+			j := 0
+			for i, p := range path {
+				if j < len(got) && p.X == got[j].X && p.Y == got[j].Y {
+					j++
+					continue
+				}
+				a, b := got[j-1], got[j]
+				if _, d := projectOnSegment(a, b, float64(p.X), float64(p.Y)); d > tt.tolerance {
+					t.Errorf("point %d %v is %.2f away from segment %v -> %v", i, p, d, a, b)
+				}
+			}
+		})
+	}
+}
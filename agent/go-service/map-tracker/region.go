@@ -0,0 +1,247 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// LocationCondition represents a condition on the player's location.
+// Exactly one kind of condition should be set: a shape (Target, Circle or Polygon, with MapName),
+// a named region (Region), or a composition (All, Any or Not).
+type LocationCondition struct {
+	// MapName is the name of the map the shape lies on.
+	MapName string `json:"map_name,omitempty"`
+	// Target is a rectangle [x, y, w, h].
+	Target *[4]int `json:"target,omitempty"`
+	// Circle is a circle [cx, cy, r].
+	Circle *[3]int `json:"circle,omitempty"`
+	// Polygon is a sequence of [x, y] vertices of a simple polygon.
+	Polygon [][2]int `json:"polygon,omitempty"`
+	// Region is the name of a region in the region file.
+	Region string `json:"region,omitempty"`
+	// All is satisfied if all of the conditions are satisfied.
+	All []LocationCondition `json:"all,omitempty"`
+	// Any is satisfied if any of the conditions is satisfied.
+	Any []LocationCondition `json:"any,omitempty"`
+	// Not is satisfied if the condition is not satisfied.
+	Not *LocationCondition `json:"not,omitempty"`
+}
+
+var (
+	regionStoreMu  sync.Mutex
+	regionStoreGen uint64 // Resource generation the store is loaded from
+	regionStore    map[string]*LocationCondition
+	regionStoreErr error
+)
+
+// getRegionStore returns the named regions loaded from the region file (thread-safe, reloads after the resource is reloaded)
+func getRegionStore() (map[string]*LocationCondition, error) {
+	regionStoreMu.Lock()
+	defer regionStoreMu.Unlock()
+
+	gen := resourceGeneration.Load()
+	if regionStoreGen == gen && (regionStore != nil || regionStoreErr != nil) {
+		return regionStore, regionStoreErr
+	}
+	regionStoreGen = gen
+	regionStore, regionStoreErr = loadRegions()
+	if regionStoreErr != nil {
+		log.Error().Err(regionStoreErr).Msg("Failed to load regions")
+	} else {
+		log.Info().Int("regionsCount", len(regionStore)).Msg("Regions loaded")
+	}
+	return regionStore, regionStoreErr
+}
+
+// loadRegions loads the region file, which maps region names to location conditions
+func loadRegions() (map[string]*LocationCondition, error) {
+	regions := make(map[string]*LocationCondition)
+
+	path := findResource(REGION_FILE)
+	if path == "" {
+		log.Debug().Msg("Region file not found, region store is empty")
+		return regions, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &regions); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal region file: %w", err)
+	}
+	for name, cond := range regions {
+		if cond == nil {
+			return nil, fmt.Errorf("region %q is empty", name)
+		}
+		if err := cond.validate(nil); err != nil {
+			return nil, fmt.Errorf("invalid region %q: %w", name, err)
+		}
+	}
+	return regions, nil
+}
+
+// validate checks the condition recursively.
+// Region references are resolved against regions, and are not allowed if regions is nil.
+func (c *LocationCondition) validate(regions map[string]*LocationCondition) error {
+	kinds := 0
+	for _, set := range []bool{c.Target != nil, c.Circle != nil, c.Polygon != nil, c.Region != "", c.All != nil, c.Any != nil, c.Not != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("exactly one of target, circle, polygon, region, all, any and not must be set")
+	}
+
+	isShape := c.Target != nil || c.Circle != nil || c.Polygon != nil
+	if isShape && c.MapName == "" {
+		return fmt.Errorf("map_name must be provided for shape conditions")
+	} else if !isShape && c.MapName != "" {
+		return fmt.Errorf("map_name can only be set for shape conditions")
+	}
+
+	switch {
+	case c.Target != nil:
+		if c.Target[2] <= 0 || c.Target[3] <= 0 {
+			return fmt.Errorf("width and height in target must be positive")
+		}
+	case c.Circle != nil:
+		if c.Circle[2] <= 0 {
+			return fmt.Errorf("radius in circle must be positive")
+		}
+	case c.Polygon != nil:
+		if len(c.Polygon) < 3 {
+			return fmt.Errorf("polygon must have at least 3 vertices")
+		}
+	case c.Region != "":
+		if regions == nil {
+			return fmt.Errorf("region references are not allowed here")
+		}
+		if _, ok := regions[c.Region]; !ok {
+			return fmt.Errorf("region %q is not found in the region file", c.Region)
+		}
+	case c.All != nil || c.Any != nil:
+		children := c.All
+		if c.Any != nil {
+			children = c.Any
+		}
+		if len(children) == 0 {
+			return fmt.Errorf("all and any must not be empty")
+		}
+		for i := range children {
+			if err := children[i].validate(regions); err != nil {
+				return fmt.Errorf("at index %d: %w", i, err)
+			}
+		}
+	case c.Not != nil:
+		if err := c.Not.validate(regions); err != nil {
+			return fmt.Errorf("in not: %w", err)
+		}
+	}
+	return nil
+}
+
+// match checks whether the location satisfies the condition.
+// Returns whether it is satisfied, and the name of the region that made it satisfied if any.
+func (c *LocationCondition) match(mapName string, x, y int, regions map[string]*LocationCondition) (bool, string) {
+	switch {
+	case c.Target != nil, c.Circle != nil, c.Polygon != nil:
+		return mapName == c.MapName && c.containsPoint(x, y), ""
+	case c.Region != "":
+		if ok, _ := regions[c.Region].match(mapName, x, y, nil); ok {
+			return true, c.Region
+		}
+		return false, ""
+	case c.All != nil:
+		region := ""
+		for i := range c.All {
+			ok, r := c.All[i].match(mapName, x, y, regions)
+			if !ok {
+				return false, ""
+			}
+			if region == "" {
+				region = r
+			}
+		}
+		return true, region
+	case c.Any != nil:
+		for i := range c.Any {
+			if ok, r := c.Any[i].match(mapName, x, y, regions); ok {
+				return true, r
+			}
+		}
+		return false, ""
+	case c.Not != nil:
+		ok, _ := c.Not.match(mapName, x, y, regions)
+		return !ok, ""
+	}
+	return false, ""
+}
+
+// containsPoint checks whether the shape of the condition contains the point
+func (c *LocationCondition) containsPoint(x, y int) bool {
+	switch {
+	case c.Target != nil:
+		t := c.Target
+		return x >= t[0] && x < t[0]+t[2] && y >= t[1] && y < t[1]+t[3]
+	case c.Circle != nil:
+		dx, dy := x-c.Circle[0], y-c.Circle[1]
+		return dx*dx+dy*dy <= c.Circle[2]*c.Circle[2]
+	case c.Polygon != nil:
+		// Ray casting with the ray towards +x through the pixel center
+		px, py := float64(x)+0.5, float64(y)+0.5
+		inside := false
+		for i, j := 0, len(c.Polygon)-1; i < len(c.Polygon); j, i = i, i+1 {
+			ax, ay := float64(c.Polygon[i][0]), float64(c.Polygon[i][1])
+			bx, by := float64(c.Polygon[j][0]), float64(c.Polygon[j][1])
+			if (ay > py) != (by > py) && px < ax+(bx-ax)*(py-ay)/(by-ay) {
+				inside = !inside
+			}
+		}
+		return inside
+	}
+	return false
+}
+
+// collectMapNames collects the names of the maps the condition can be satisfied on,
+// returns false if the condition may be satisfied on any map (i.e. it contains not)
+func (c *LocationCondition) collectMapNames(regions map[string]*LocationCondition, names map[string]struct{}) bool {
+	switch {
+	case c.MapName != "":
+		names[c.MapName] = struct{}{}
+	case c.Region != "":
+		return regions[c.Region].collectMapNames(nil, names)
+	case c.All != nil || c.Any != nil:
+		for i := range c.All {
+			if !c.All[i].collectMapNames(regions, names) {
+				return false
+			}
+		}
+		for i := range c.Any {
+			if !c.Any[i].collectMapNames(regions, names) {
+				return false
+			}
+		}
+	case c.Not != nil:
+		return false
+	}
+	return true
+}
+
+// regionsContaining returns the sorted names of all regions that contain the location
+func regionsContaining(mapName string, x, y int, regions map[string]*LocationCondition) []string {
+	names := make([]string, 0)
+	for name, cond := range regions {
+		if ok, _ := cond.match(mapName, x, y, nil); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// mapRegionHint represents the region the player is believed to be in,
+// which narrows the map candidates of full search
+type mapRegionHint struct {
+	region string // Map name prefix of the region, e.g. "map01"
+	source string // What the hint is observed from
+	timeMs int64  // When the hint is observed
+}
+
+var (
+	regionHints   = make(map[maa.Tasker]mapRegionHint)
+	regionHintsMu sync.Mutex
+)
+
+// mapRegion returns the region of a map, which is the map name prefix before the first underscore
+func mapRegion(mapName string) string {
+	region, _, _ := strings.Cut(mapName, "_")
+	return region
+}
+
+// getRegionHint returns the latest region hint of the tasker, or nil if there is none
+func getRegionHint(tasker *maa.Tasker) *mapRegionHint {
+	regionHintsMu.Lock()
+	defer regionHintsMu.Unlock()
+
+	if hint, ok := regionHints[*tasker]; ok {
+		return &hint
+	}
+	return nil
+}
+
+// setRegionHint records the region the player of the tasker is believed to be in
+func setRegionHint(tasker *maa.Tasker, region, source string) {
+	if region == "" {
+		return
+	}
+
+	regionHintsMu.Lock()
+	defer regionHintsMu.Unlock()
+
+	if prev, ok := regionHints[*tasker]; !ok || prev.region != region {
+		log.Debug().Str("region", region).Str("source", source).Msg("Map region hint updated")
+	}
+	regionHints[*tasker] = mapRegionHint{region, source, time.Now().UnixMilli()}
+}
+
+// clearRegionHint discards the region hint of the tasker. Returns whether there was one.
+func clearRegionHint(tasker *maa.Tasker) bool {
+	regionHintsMu.Lock()
+	defer regionHintsMu.Unlock()
+
+	_, ok := regionHints[*tasker]
+	delete(regionHints, *tasker)
+	return ok
+}
+
+// candidateRegion returns the region whose maps are searched first, and what it is observed from.
+// It is the more recent one of the last map tracked by the filter in the session and the region hint of the tasker.
+func candidateRegion(state *InferState, filter InferFilterType, hint *mapRegionHint) (string, string) {
+	state.mu.Lock()
+	convincedMapName, _, _, convincedTimeMs, _ := state.trackedLocation(filter, 0)
+	state.mu.Unlock()
+
+	if hint != nil && (convincedMapName == "" || hint.timeMs > convincedTimeMs) {
+		return hint.region, hint.source
+	}
+	if convincedMapName != "" {
+		return mapRegion(convincedMapName), "convinced"
+	}
+	return "", ""
+}
+
+// sceneRegionSink records the region hint of a tasker from the SceneManager nodes it passes,
+// including the nodes that recognize the region name on the map screen by OCR
+type sceneRegionSink struct{}
+
+var _ maa.ContextEventSink = &sceneRegionSink{}
+
+// observe records the region hint if the node name indicates a region
+func (s *sceneRegionSink) observe(ctx *maa.Context, name string) {
+	m := SCENE_REGION_NODE_REGEX.FindStringSubmatch(name)
+	if m == nil {
+		return
+	}
+	setRegionHint(ctx.GetTasker(), SCENE_REGION_MAP_PREFIXES[m[1]], name)
+}
+
+// OnNodePipelineNode handles pipeline node events
+func (s *sceneRegionSink) OnNodePipelineNode(ctx *maa.Context, event maa.EventStatus, detail maa.NodePipelineNodeDetail) {
+	if event == maa.EventStatusSucceeded {
+		s.observe(ctx, detail.Name)
+	}
+}
+
+// OnNodeRecognitionNode handles recognition node events
+func (s *sceneRegionSink) OnNodeRecognitionNode(ctx *maa.Context, event maa.EventStatus, detail maa.NodeRecognitionNodeDetail) {
+	if event == maa.EventStatusSucceeded {
+		s.observe(ctx, detail.Name)
+	}
+}
+
+// OnNodeRecognition handles recognition events, including the sub-recognitions of And nodes
+func (s *sceneRegionSink) OnNodeRecognition(ctx *maa.Context, event maa.EventStatus, detail maa.NodeRecognitionDetail) {
+	if event == maa.EventStatusSucceeded {
+		s.observe(ctx, detail.Name)
+	}
+}
+
+// OnNodeActionNode ignores action node events
+func (s *sceneRegionSink) OnNodeActionNode(ctx *maa.Context, event maa.EventStatus, detail maa.NodeActionNodeDetail) {
+}
+
+// OnNodeNextList ignores next list events
+func (s *sceneRegionSink) OnNodeNextList(ctx *maa.Context, event maa.EventStatus, detail maa.NodeNextListDetail) {
+}
+
+// OnNodeAction ignores action events
+func (s *sceneRegionSink) OnNodeAction(ctx *maa.Context, event maa.EventStatus, detail maa.NodeActionDetail) {
+}
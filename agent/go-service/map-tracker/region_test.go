@@ -0,0 +1,146 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// testRegions are the named regions used by region tests
+var testRegions = map[string]*LocationCondition{
+	"town":   {MapName: "map01", Target: &[4]int{0, 0, 100, 100}},
+	"plaza":  {MapName: "map01", Circle: &[3]int{50, 50, 10}},
+	"harbor": {MapName: "map02", Polygon: [][2]int{{0, 0}, {40, 0}, {40, 40}, {20, 20}, {0, 40}}},
+}
+
+// parseCondition parses a location condition from JSON
+func parseCondition(t *testing.T, s string) *LocationCondition {
+	t.Helper()
+	var c LocationCondition
+	if err := json.Unmarshal([]byte(s), &c); err != nil {
+		t.Fatalf("failed to parse condition %s: %v", s, err)
+	}
+	return &c
+}
+
+func TestLocationConditionMatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		cond       string
+		mapName    string
+		x, y       int
+		want       bool
+		wantRegion string
+	}{
+		{"target inside", `{"map_name": "map01", "target": [10, 20, 30, 40]}`, "map01", 10, 59, true, ""},
+		{"target right edge excluded", `{"map_name": "map01", "target": [10, 20, 30, 40]}`, "map01", 40, 30, false, ""},
+		{"target on another map", `{"map_name": "map01", "target": [10, 20, 30, 40]}`, "map02", 20, 30, false, ""},
+		{"circle on the border", `{"map_name": "map01", "circle": [50, 50, 5]}`, "map01", 53, 54, true, ""},
+		{"circle outside", `{"map_name": "map01", "circle": [50, 50, 5]}`, "map01", 54, 54, false, ""},
+		{"polygon inside", `{"map_name": "map02", "polygon": [[0, 0], [40, 0], [40, 40], [20, 20], [0, 40]]}`, "map02", 5, 30, true, ""},
+		{"polygon in the notch", `{"map_name": "map02", "polygon": [[0, 0], [40, 0], [40, 40], [20, 20], [0, 40]]}`, "map02", 20, 35, false, ""},
+		{"region", `{"region": "plaza"}`, "map01", 50, 45, true, "plaza"},
+		{"region outside", `{"region": "plaza"}`, "map01", 70, 70, false, ""},
+		{"all", `{"all": [{"region": "town"}, {"not": {"region": "plaza"}}]}`, "map01", 70, 70, true, "town"},
+		{"all failing", `{"all": [{"region": "town"}, {"not": {"region": "plaza"}}]}`, "map01", 50, 50, false, ""},
+		{"any reports the first matched region", `{"any": [{"region": "harbor"}, {"region": "plaza"}, {"region": "town"}]}`, "map01", 50, 50, true, "plaza"},
+		{"any failing", `{"any": [{"region": "harbor"}, {"region": "plaza"}]}`, "map01", 90, 90, false, ""},
+		{"not", `{"not": {"region": "town"}}`, "map02", 50, 50, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := parseCondition(t, tt.cond)
+			if err := c.validate(testRegions); err != nil {
+				t.Fatalf("validate(%s) failed: %v", tt.cond, err)
+			}
+			got, region := c.match(tt.mapName, tt.x, tt.y, testRegions)
+			if got != tt.want || region != tt.wantRegion {
+				t.Errorf("match(%s, %d, %d) = %v, %q, want %v, %q", tt.mapName, tt.x, tt.y, got, region, tt.want, tt.wantRegion)
+			}
+		})
+	}
+}
+
+func TestLocationConditionValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    string
+		regions map[string]*LocationCondition
+		wantErr bool
+	}{
+		{"target", `{"map_name": "map01", "target": [0, 0, 1, 1]}`, nil, false},
+		{"nothing set", `{}`, nil, true},
+		{"two shapes", `{"map_name": "map01", "target": [0, 0, 1, 1], "circle": [0, 0, 1]}`, nil, true},
+		{"shape without map", `{"circle": [0, 0, 1]}`, nil, true},
+		{"map on composition", `{"map_name": "map01", "not": {"map_name": "map01", "circle": [0, 0, 1]}}`, nil, true},
+		{"empty target", `{"map_name": "map01", "target": [0, 0, 0, 1]}`, nil, true},
+		{"zero radius", `{"map_name": "map01", "circle": [0, 0, 0]}`, nil, true},
+		{"degenerate polygon", `{"map_name": "map01", "polygon": [[0, 0], [1, 1]]}`, nil, true},
+		{"empty any", `{"any": []}`, nil, true},
+		{"invalid nested", `{"all": [{"region": "town"}, {"not": {"circle": [0, 0, 1]}}]}`, testRegions, true},
+		{"known region", `{"region": "town"}`, testRegions, false},
+		{"unknown region", `{"region": "castle"}`, testRegions, true},
+		{"region in the region file", `{"region": "town"}`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseCondition(t, tt.cond).validate(tt.regions)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate(%s) = %v, want error %v", tt.cond, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLocationConditionCollectMapNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		cond    string
+		wantOk  bool
+		wantMap []string
+	}{
+		{"shape", `{"map_name": "map01", "circle": [0, 0, 1]}`, true, []string{"map01"}},
+		{"regions", `{"any": [{"region": "town"}, {"region": "harbor"}]}`, true, []string{"map01", "map02"}},
+		{"not", `{"all": [{"region": "town"}, {"not": {"region": "plaza"}}]}`, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make(map[string]struct{})
+			ok := parseCondition(t, tt.cond).collectMapNames(testRegions, names)
+			if ok != tt.wantOk {
+				t.Fatalf("collectMapNames(%s) = %v, want %v", tt.cond, ok, tt.wantOk)
+			}
+			if !ok {
+				return
+			}
+			got := make([]string, 0, len(names))
+			for name := range names {
+				got = append(got, name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantMap) {
+				t.Errorf("collectMapNames(%s) = %v, want %v", tt.cond, got, tt.wantMap)
+			}
+		})
+	}
+}
+
+func TestRegionsContaining(t *testing.T) {
+	tests := []struct {
+		mapName string
+		x, y    int
+		want    []string
+	}{
+		{"map01", 50, 50, []string{"plaza", "town"}},
+		{"map01", 90, 10, []string{"town"}},
+		{"map01", 150, 150, []string{}},
+		{"map02", 30, 10, []string{"harbor"}},
+	}
+	for _, tt := range tests {
+		got := regionsContaining(tt.mapName, tt.x, tt.y, testRegions)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("regionsContaining(%s, %d, %d) = %v, want %v", tt.mapName, tt.x, tt.y, got, tt.want)
+		}
+	}
+}
@@ -6,8 +6,18 @@ import "github.com/MaaXYZ/maa-framework-go/v4"
 // Register registers all custom recognition components for map-tracker package
 func Register() {
 	ensureResourcePathSink()
+	maa.AgentServerAddTaskerSink(&inferSessionSink{})
+	maa.AgentServerAddTaskerSink(&geofenceSink{})
+	maa.AgentServerAddContextSink(&sceneRegionSink{})
 
 	maa.AgentServerRegisterCustomRecognition("MapTrackerInfer", &MapTrackerInfer{})
 	maa.AgentServerRegisterCustomRecognition("MapTrackerAssertLocation", &MapTrackerAssertLocation{})
 	maa.AgentServerRegisterCustomAction("MapTrackerMove", &MapTrackerMove{})
+	maa.AgentServerRegisterCustomAction("MapTrackerNavigate", &MapTrackerNavigate{})
+	maa.AgentServerRegisterCustomAction("MapTrackerGoTo", &MapTrackerGoTo{})
+	maa.AgentServerRegisterCustomAction("MapTrackerReset", &MapTrackerReset{})
+	maa.AgentServerRegisterCustomAction("MapTrackerRecord", &MapTrackerRecord{})
+	maa.AgentServerRegisterCustomAction("MapTrackerGeofence", &MapTrackerGeofence{})
+	maa.AgentServerRegisterCustomAction("MapTrackerCalibrate", &MapTrackerCalibrate{})
+	maa.AgentServerRegisterCustomAction("MapTrackerCalibrateRotation", &MapTrackerCalibrateRotation{})
 }
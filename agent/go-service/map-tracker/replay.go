@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+	"regexp"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+)
+
+// Replayer runs MapTrackerInfer on recorded frames offline, without MaaFramework or a live game.
+// It owns its own tracking state, so that time-series optimization behaves as in a live session.
+type Replayer struct {
+	infer        *MapTrackerInfer
+	param        *MapTrackerInferParam
+	mapNameRegex *regexp.Regexp
+	state        InferState
+}
+
+// NewReplayer creates a Replayer loading resources from resourceDir,
+// using paramStr as the custom_recognition_param of MapTrackerInfer
+func NewReplayer(resourceDir string, paramStr string) (*Replayer, error) {
+	abs, err := filepath.Abs(resourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource directory: %w", err)
+	}
+	resourcePath.Store(abs)
+	resourceGeneration.Add(1)
+
+	infer := &MapTrackerInfer{}
+	parsed, err := infer.parseParam(paramStr)
+	if err != nil {
+		return nil, err
+	}
+	param := *parsed
+	param.Print = false
+
+	mapNameRegex, err := regexp.Compile(param.MapNameRegex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid map_name_regex: %w", err)
+	}
+
+	if err := infer.initMaps(nil); err != nil {
+		return nil, err
+	}
+	if err := infer.initPointer(nil); err != nil {
+		return nil, err
+	}
+
+	return &Replayer{
+		infer:        infer,
+		param:        &param,
+		mapNameRegex: mapNameRegex,
+	}, nil
+}
+
+// Infer runs inference on one frame captured at timestampMs (a 1280x720 screen image at the default HUD scale).
+// Returns nil if not hit.
+func (r *Replayer) Infer(img image.Image, timestampMs int64) *MapTrackerInferResult {
+	return r.infer.infer(minicv.ImageConvertRGBA(img), &DEFAULT_MINIMAP_GEOMETRY, r.mapNameRegex, r.param, &r.state, nil, timestampMs)
+}
@@ -36,6 +36,7 @@ func (c *resourcePathSink) OnResourceLoading(resource *maa.Resource, status maa.
 		abs = p
 	}
 	resourcePath.Store(abs)
+	resourceGeneration.Add(1) // Maps, the pointer template, routes, regions and anchors are reloaded on next use
 	log.Debug().Str("resource_path", abs).Msg("Resource loaded; cached path for map-tracker")
 }
 
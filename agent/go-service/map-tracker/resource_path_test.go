@@ -0,0 +1,97 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoresReloadWithResource(t *testing.T) {
+	prev, _ := resourcePath.Load().(string)
+	t.Cleanup(func() {
+		resourcePath.Store(prev)
+		resourceGeneration.Add(1)
+	})
+	base := t.TempDir()
+	resourcePath.Store(base)
+	resourceGeneration.Add(1)
+
+	tests := []struct {
+		name  string
+		file  string
+		v1    string
+		v2    string
+		count func(t *testing.T) int // Number of entries in the loaded store
+	}{
+		{
+			name: "routes",
+			file: filepath.Join(ROUTE_DIR, "test.json"),
+			v1:   `{"name": "a", "map_name": "map01", "path": [[10, 10], [50, 50]]}`,
+			v2:   `{"name": "b", "map_name": "map01", "path": [[10, 10], [50, 50]]}`,
+			count: func(t *testing.T) int {
+				store, err := getRouteStore()
+				if err != nil {
+					t.Fatalf("getRouteStore() failed: %v", err)
+				}
+				if _, err := store.Get("b", 0); err != nil {
+					return 1
+				}
+				return 2
+			},
+		},
+		{
+			name: "regions",
+			file: REGION_FILE,
+			v1:   `{"a": {"map_name": "map01", "circle": [50, 50, 5]}}`,
+			v2:   `{"a": {"map_name": "map01", "circle": [50, 50, 5]}, "b": {"map_name": "map02", "circle": [50, 50, 5]}}`,
+			count: func(t *testing.T) int {
+				regions, err := getRegionStore()
+				if err != nil {
+					t.Fatalf("getRegionStore() failed: %v", err)
+				}
+				return len(regions)
+			},
+		},
+		{
+			name: "anchors",
+			file: ANCHOR_FILE,
+			v1:   `{"map01": [{"task": "A", "pos": [1, 2]}]}`,
+			v2:   `{"map01": [{"task": "A", "pos": [1, 2]}], "map02": [{"task": "B", "pos": [3, 4]}]}`,
+			count: func(t *testing.T) int {
+				anchors, err := getAnchorStore()
+				if err != nil {
+					t.Fatalf("getAnchorStore() failed: %v", err)
+				}
+				return len(anchors)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(base, tt.file)
+			write := func(content string) {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			write(tt.v1)
+			resourceGeneration.Add(1)
+			if got := tt.count(t); got != 1 {
+				t.Fatalf("%s loaded %d entries, want 1", tt.name, got)
+			}
+			write(tt.v2)
+			if got := tt.count(t); got != 1 {
+				t.Errorf("%s reloaded to %d entries before the resource is reloaded, want 1", tt.name, got)
+			}
+			resourceGeneration.Add(1)
+			if got := tt.count(t); got != 2 {
+				t.Errorf("%s loaded %d entries after the resource is reloaded, want 2", tt.name, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/camerasens"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// MapTrackerCalibrateRotation is the custom action component that calibrates the camera rotation sensitivity,
+// by performing known swipes and measuring the camera heading changes on the mini-map
+type MapTrackerCalibrateRotation struct{}
+
+// MapTrackerCalibrateRotationParam represents the custom_action_param for MapTrackerCalibrateRotation
+type MapTrackerCalibrateRotationParam struct {
+	// Swipes is the list of swipe distances in pixels to perform, each to the right and then to the left.
+	Swipes []int `json:"swipes,omitempty"`
+	// Backend selects the controls to swipe with ("auto", "keyboard" or "touch"), see MapTrackerMoveParam.
+	Backend MovementBackendType `json:"backend,omitempty"`
+	// Reset discards the calibration of the current controller instead of calibrating.
+	Reset bool `json:"reset,omitempty"`
+	// NoPrint controls whether to suppress printing the calibration result to the GUI.
+	NoPrint bool `json:"no_print,omitempty"`
+}
+
+//go:embed messages/rotation_calibration_finished.html
+var rotationCalibrationFinishedHTML string
+
+var _ maa.CustomActionRunner = &MapTrackerCalibrateRotation{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerCalibrateRotation) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	var param MapTrackerCalibrateRotationParam
+	if arg.CustomActionParam != "" {
+		if err := json.Unmarshal([]byte(arg.CustomActionParam), &param); err != nil {
+			log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerCalibrateRotation")
+			return false
+		}
+	}
+	if len(param.Swipes) == 0 {
+		param.Swipes = ROTATION_CALIBRATION_SWIPES
+	}
+	if err := validateMovementBackend(param.Backend); err != nil {
+		log.Error().Err(err).Msg("Invalid parameters for MapTrackerCalibrateRotation")
+		return false
+	}
+	for i, px := range param.Swipes {
+		if px <= 0 {
+			log.Error().Int("index", i).Int("swipe", px).Msg("Swipe distances must be positive")
+			return false
+		}
+	}
+
+	ctrl := ctx.GetTasker().GetController()
+	key, err := camerasens.Key(ctrl)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get camera sensitivity key")
+		return false
+	}
+
+	if param.Reset {
+		if err := camerasens.Set(key, nil); err != nil {
+			log.Error().Err(err).Msg("Failed to save camera sensitivity file")
+			return false
+		}
+		log.Info().Str("key", key).Msg("Camera rotation calibration reset")
+		return true
+	}
+
+	infer := mapTrackerInferRunner.(*MapTrackerInfer)
+	if err := infer.initPointer(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to initialize pointer")
+		return false
+	}
+
+	sens, samples, err := calibrateRotation(ctx, ctrl, infer, param.Swipes, param.Backend)
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Interface("samples", samples).Msg("Camera rotation calibration failed")
+		return false
+	}
+	if err := camerasens.Set(key, sens); err != nil {
+		log.Error().Err(err).Msg("Failed to save camera sensitivity file")
+		return false
+	}
+
+	log.Info().Str("key", key).Interface("sensitivity", sens).Interface("samples", samples).Msg("Camera rotation calibrated")
+	if !param.NoPrint {
+		maafocus.NodeActionStarting(ctx, fmt.Sprintf(rotationCalibrationFinishedHTML, sens.Pixels(90), sens.DeadZone, sens.Exponent, sens.RMS))
+	}
+	return true
+}
+
+// calibrateRotation swipes by each distance to the right and back to the left,
+// measures the camera heading changes and fits the sensitivity model to them
+func calibrateRotation(ctx *maa.Context, ctrl *maa.Controller, infer *MapTrackerInfer, swipes []int, backend MovementBackendType) (*camerasens.Sensitivity, []camerasens.Sample, error) {
+	mv := newMovementBackend(NewActionWrapper(ctx, ctrl), backend)
+	mv.Stop(25)
+	mv.ResetCamera(25)
+
+	heading, err := measureCameraHeading(ctrl, infer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	samples := make([]camerasens.Sample, 0, 2*len(swipes))
+	fit := camerasens.Default
+	for _, px := range swipes {
+		for _, dx := range []int{px, -px} {
+			if ctx.GetTasker().Stopping() {
+				return nil, samples, fmt.Errorf("task is stopping")
+			}
+			mv.RotateCamera(dx, ROTATION_CALIBRATION_SWIPE_MS, ROTATION_CALIBRATION_SETTLE_MS)
+			mv.ResetCamera(25)
+
+			next, err := measureCameraHeading(ctrl, infer)
+			if err != nil {
+				return nil, samples, err
+			}
+
+			// Headings wrap around, so take the turn closest to the prediction of the samples so far
+			delta := calcDeltaRotation(heading, next)
+			pred := fit.Yaw(float64(dx))
+			delta += 360 * math.Round((pred-delta)/360)
+			heading = next
+
+			samples = append(samples, camerasens.Sample{Pixels: float64(dx), Degrees: delta})
+			log.Debug().Int("dx", dx).Float64("delta", delta).Float64("pred", pred).Msg("Camera rotation sample measured")
+			if len(samples) >= 2 {
+				if s, err := camerasens.Fit(samples); err == nil {
+					fit = *s
+				}
+			}
+		}
+	}
+
+	sens, err := camerasens.Fit(samples)
+	if err != nil {
+		return nil, samples, err
+	}
+	if sens.RMS > ROTATION_CALIBRATION_MAX_RMS {
+		return nil, samples, fmt.Errorf("fitting error %.1f degrees exceeds %.1f, keep still and retry", sens.RMS, ROTATION_CALIBRATION_MAX_RMS)
+	}
+	return sens, samples, nil
+}
+
+// measureCameraHeading returns the camera heading averaged over a few screenshots
+func measureCameraHeading(ctrl *maa.Controller, infer *MapTrackerInfer) (float64, error) {
+	sx, sy, weight := 0.0, 0.0, 0.0
+	for i := range ROTATION_CALIBRATION_FRAMES {
+		if i > 0 {
+			time.Sleep(time.Duration(INFER_INTERVAL_MS) * time.Millisecond)
+		}
+		ctrl.PostScreencap().Wait()
+		img, err := ctrl.CacheImage()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get cached image: %w", err)
+		}
+		if img == nil {
+			return 0, fmt.Errorf("cached image is nil")
+		}
+		screenImg := minicv.ImageConvertRGBA(img)
+		cam := infer.inferCameraRotation(screenImg, infer.getMinimapGeometry(ctrl, screenImg))
+		if cam == nil || cam.conf < ROTATION_CALIBRATION_MIN_CONF {
+			continue
+		}
+		rad := cam.rot * math.Pi / 180
+		sx += cam.conf * math.Sin(rad)
+		sy += cam.conf * math.Cos(rad)
+		weight += cam.conf
+	}
+	if weight == 0 {
+		return 0, fmt.Errorf("camera heading not recognized")
+	}
+	return normalizeRotation(math.Atan2(sx, sy) * 180 / math.Pi), nil
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MapTrackerRoute represents a named route stored in the route directory
+type MapTrackerRoute struct {
+	// Name is the unique name of the route (defaults to the file name without ".json").
+	Name string `json:"name"`
+	// Version is the revision of the route; the highest version is used unless pinned.
+	Version int `json:"version"`
+	// MapName is the name of the map this route belongs to (single-map routes only).
+	MapName string `json:"map_name,omitempty"`
+	// Path is a sequence of points to follow, each as [x, y] or an object with actions.
+	Path []MapTrackerPoint `json:"path,omitempty"`
+	// Segments is a sequence of paths on different maps or tiers, used in place of MapName and Path.
+	Segments []MapTrackerMoveSegment `json:"segments,omitempty"`
+	// Defaults holds per-route movement defaults, overridable by the action parameters.
+	Defaults MapTrackerRouteDefaults `json:"defaults,omitzero"`
+}
+
+// MapTrackerRouteDefaults represents the movement parameters a route may provide as defaults
+type MapTrackerRouteDefaults struct {
+	ArrivalThreshold       float64             `json:"arrival_threshold,omitempty"`
+	ArrivalTimeout         int64               `json:"arrival_timeout,omitempty"`
+	RotationLowerThreshold float64             `json:"rotation_lower_threshold,omitempty"`
+	RotationUpperThreshold float64             `json:"rotation_upper_threshold,omitempty"`
+	SprintThreshold        float64             `json:"sprint_threshold,omitempty"`
+	StuckThreshold         int64               `json:"stuck_threshold,omitempty"`
+	StuckTimeout           int64               `json:"stuck_timeout,omitempty"`
+	StuckRecovery          []StuckRecoveryType `json:"stuck_recovery,omitempty"`
+	Steering               SteeringMode        `json:"steering,omitempty"`
+}
+
+// RouteStore holds all loaded routes, indexed by name and sorted by version
+type RouteStore struct {
+	mu     sync.RWMutex
+	routes map[string][]MapTrackerRoute
+}
+
+var (
+	routeStoreMu  sync.Mutex
+	routeStoreGen uint64 // Resource generation the store is loaded from
+	routeStore    *RouteStore
+	routeStoreErr error
+)
+
+// getRouteStore returns the global route store (thread-safe, reloads after the resource is reloaded)
+func getRouteStore() (*RouteStore, error) {
+	routeStoreMu.Lock()
+	defer routeStoreMu.Unlock()
+
+	gen := resourceGeneration.Load()
+	if routeStoreGen == gen && (routeStore != nil || routeStoreErr != nil) {
+		return routeStore, routeStoreErr
+	}
+	routeStoreGen = gen
+	routeStore, routeStoreErr = loadRoutes()
+	if routeStoreErr != nil {
+		log.Error().Err(routeStoreErr).Msg("Failed to load routes")
+	} else {
+		log.Info().Int("routesCount", len(routeStore.routes)).Msg("Routes loaded")
+	}
+	return routeStore, routeStoreErr
+}
+
+// loadRoutes loads all route JSON files from the route directory of the resource
+// and the user route directory (recursively)
+func loadRoutes() (*RouteStore, error) {
+	store := &RouteStore{routes: make(map[string][]MapTrackerRoute)}
+
+	routeDir := findResource(ROUTE_DIR)
+	if routeDir == "" {
+		log.Debug().Msg("Route directory not found")
+	} else if err := store.loadDir(routeDir); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(USER_ROUTE_DIR); err == nil {
+		if err := store.loadDir(USER_ROUTE_DIR); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// loadDir loads all route JSON files in a directory (recursively)
+func (s *RouteStore) loadDir(routeDir string) error {
+	err := filepath.WalkDir(routeDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to read route file")
+			return nil
+		}
+
+		var route MapTrackerRoute
+		if err := json.Unmarshal(data, &route); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Failed to unmarshal route file")
+			return nil
+		}
+		if route.Name == "" {
+			route.Name = strings.TrimSuffix(d.Name(), ".json")
+		}
+		if err := route.validate(); err != nil {
+			log.Warn().Err(err).Str("path", path).Msg("Invalid route file")
+			return nil
+		}
+
+		s.add(route)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk route directory: %w", err)
+	}
+	return nil
+}
+
+// add inserts a route, keeping versions of the same name sorted in ascending order
+func (s *RouteStore) add(route MapTrackerRoute) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := s.routes[route.Name]
+	idx := len(versions)
+	for i, r := range versions {
+		if r.Version == route.Version {
+			log.Warn().Str("name", route.Name).Int("version", route.Version).Msg("Duplicated route version, overriding")
+			versions[i] = route
+			return
+		}
+		if r.Version > route.Version {
+			idx = i
+			break
+		}
+	}
+	versions = append(versions, MapTrackerRoute{})
+	copy(versions[idx+1:], versions[idx:])
+	versions[idx] = route
+	s.routes[route.Name] = versions
+}
+
+// Get returns the route of the given name, using the latest version if version is 0
+func (s *RouteStore) Get(name string, version int) (*MapTrackerRoute, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions, ok := s.routes[name]
+	if !ok || len(versions) == 0 {
+		return nil, fmt.Errorf("route %q not found", name)
+	}
+	if version == 0 {
+		route := versions[len(versions)-1]
+		return &route, nil
+	}
+	for _, r := range versions {
+		if r.Version == version {
+			route := r
+			return &route, nil
+		}
+	}
+	return nil, fmt.Errorf("route %q has no version %d", name, version)
+}
+
+func (r *MapTrackerRoute) validate() error {
+	if r.Version < 0 {
+		return fmt.Errorf("version must be non-negative")
+	}
+	if len(r.Segments) > 0 {
+		if r.MapName != "" || len(r.Path) > 0 {
+			return fmt.Errorf("map_name and path cannot be set together with segments")
+		}
+		return validateSegments(r.Segments)
+	}
+	if r.MapName == "" {
+		return fmt.Errorf("map_name is required")
+	}
+	if len(r.Path) == 0 {
+		return fmt.Errorf("path is required")
+	}
+	return nil
+}
+
+// slicePath returns a copy of the route path sliced by [start, end) and optionally reversed.
+// Negative indices count from the end of the path, like Python slices.
+func (r *MapTrackerRoute) slicePath(slice []int, reverse bool) ([]MapTrackerPoint, error) {
+	n := len(r.Path)
+	start, end := 0, n
+	switch len(slice) {
+	case 0:
+	case 2:
+		end = slice[1]
+		if end < 0 {
+			end += n
+		}
+		fallthrough
+	case 1:
+		start = slice[0]
+		if start < 0 {
+			start += n
+		}
+	default:
+		return nil, fmt.Errorf("route_slice must be [start] or [start, end]")
+	}
+	if start < 0 || end > n || start >= end {
+		return nil, fmt.Errorf("route_slice %v is out of range for route %q with %d points", slice, r.Name, n)
+	}
+
+	path := make([]MapTrackerPoint, 0, end-start)
+	path = append(path, r.Path[start:end]...)
+	if reverse {
+		for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+			path[i], path[j] = path[j], path[i]
+		}
+	}
+	return path, nil
+}
+
+// applyRoute resolves the route referenced by the parameters,
+// filling map name, path and movement parameters that are not explicitly set
+func (param *MapTrackerMoveParam) applyRoute() error {
+	store, err := getRouteStore()
+	if err != nil {
+		return err
+	}
+	route, err := store.Get(param.Route, param.RouteVersion)
+	if err != nil {
+		return err
+	}
+
+	if len(param.Path) > 0 || len(param.Segments) > 0 {
+		return fmt.Errorf("path or segments and route cannot be set at the same time")
+	}
+
+	pointsCount := 0
+	if len(route.Segments) > 0 {
+		if param.MapName != "" {
+			return fmt.Errorf("map_name cannot be set for multi-segment route %q", route.Name)
+		}
+		if param.RouteReverse || len(param.RouteSlice) > 0 {
+			return fmt.Errorf("route_reverse and route_slice are not supported for multi-segment route %q", route.Name)
+		}
+		param.Segments = make([]MapTrackerMoveSegment, len(route.Segments))
+		for i, seg := range route.Segments {
+			seg.Path = append([]MapTrackerPoint(nil), seg.Path...)
+			if seg.Transition != nil {
+				tr := *seg.Transition
+				seg.Transition = &tr
+			}
+			param.Segments[i] = seg
+			pointsCount += len(seg.Path)
+		}
+	} else {
+		if param.MapName != "" && param.MapName != route.MapName {
+			return fmt.Errorf("map_name %q conflicts with map_name %q of route %q", param.MapName, route.MapName, route.Name)
+		}
+		path, err := route.slicePath(param.RouteSlice, param.RouteReverse)
+		if err != nil {
+			return err
+		}
+		param.MapName = route.MapName
+		param.Path = path
+		pointsCount = len(path)
+	}
+
+	d := route.Defaults
+	if param.ArrivalThreshold == 0 {
+		param.ArrivalThreshold = d.ArrivalThreshold
+	}
+	if param.ArrivalTimeout == 0 {
+		param.ArrivalTimeout = d.ArrivalTimeout
+	}
+	if param.RotationLowerThreshold == 0 {
+		param.RotationLowerThreshold = d.RotationLowerThreshold
+	}
+	if param.RotationUpperThreshold == 0 {
+		param.RotationUpperThreshold = d.RotationUpperThreshold
+	}
+	if param.SprintThreshold == 0 {
+		param.SprintThreshold = d.SprintThreshold
+	}
+	if param.StuckThreshold == 0 {
+		param.StuckThreshold = d.StuckThreshold
+	}
+	if param.StuckTimeout == 0 {
+		param.StuckTimeout = d.StuckTimeout
+	}
+	if param.StuckRecovery == nil {
+		param.StuckRecovery = d.StuckRecovery
+	}
+	if param.Steering == "" {
+		param.Steering = d.Steering
+	}
+
+	log.Info().Str("route", route.Name).Int("version", route.Version).
+		Int("pointsCount", pointsCount).Bool("reverse", param.RouteReverse).
+		Msg("Route resolved from route store")
+	return nil
+}
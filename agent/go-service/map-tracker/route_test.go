@@ -0,0 +1,63 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSlicePath(t *testing.T) {
+	route := &MapTrackerRoute{Name: "test", Path: makePath([][2]int{{0, 0}, {1, 1}, {2, 2}, {3, 3}, {4, 4}})}
+
+	tests := []struct {
+		name    string
+		slice   []int
+		reverse bool
+		want    [][2]int // nil if an error is expected
+	}{
+		{"whole", nil, false, [][2]int{{0, 0}, {1, 1}, {2, 2}, {3, 3}, {4, 4}}},
+		{"whole reversed", nil, true, [][2]int{{4, 4}, {3, 3}, {2, 2}, {1, 1}, {0, 0}}},
+		{"start", []int{2}, false, [][2]int{{2, 2}, {3, 3}, {4, 4}}},
+		{"start and end", []int{1, 3}, false, [][2]int{{1, 1}, {2, 2}}},
+		{"negative start", []int{-2}, false, [][2]int{{3, 3}, {4, 4}}},
+		{"negative end", []int{0, -1}, false, [][2]int{{0, 0}, {1, 1}, {2, 2}, {3, 3}}},
+		{"negative both reversed", []int{-4, -1}, true, [][2]int{{3, 3}, {2, 2}, {1, 1}}},
+		{"single point", []int{4, 5}, false, [][2]int{{4, 4}}},
+		{"empty", []int{2, 2}, false, nil},
+		{"inverted", []int{3, 1}, false, nil},
+		{"end out of range", []int{0, 6}, false, nil},
+		{"start out of range", []int{-6}, false, nil},
+		{"too many indices", []int{0, 1, 2}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := route.slicePath(tt.slice, tt.reverse)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("slicePath(%v, %v) = %v, want error", tt.slice, tt.reverse, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("slicePath(%v, %v) failed: %v", tt.slice, tt.reverse, err)
+			}
+			if !slices.Equal(pathCoords(got), tt.want) {
+				t.Errorf("slicePath(%v, %v) = %v, want %v", tt.slice, tt.reverse, got, tt.want)
+			}
+		})
+	}
+
+	// The route itself must be left untouched
+	if !slices.Equal(pathCoords(route.Path), [][2]int{{0, 0}, {1, 1}, {2, 2}, {3, 3}, {4, 4}}) {
+		t.Errorf("slicePath modified the route path: %v", route.Path)
+	}
+}
+
+// pathCoords returns the coordinates of the path points
+func pathCoords(path []MapTrackerPoint) [][2]int {
+	coords := make([][2]int, len(path))
+	for i, p := range path {
+		coords[i] = [2]int{p.X, p.Y}
+	}
+	return coords
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// inferSessionKey identifies a tracking session, scoped to a tasker and an optional session name
+type inferSessionKey struct {
+	tasker maa.Tasker
+	name   string
+}
+
+var (
+	inferSessions   = make(map[inferSessionKey]*InferState)
+	inferSessionsMu sync.Mutex
+)
+
+// getInferState returns the tracking state of the given session, creating it if absent
+func getInferState(tasker *maa.Tasker, name string) *InferState {
+	key := inferSessionKey{*tasker, name}
+
+	inferSessionsMu.Lock()
+	defer inferSessionsMu.Unlock()
+
+	state, ok := inferSessions[key]
+	if !ok {
+		state = &InferState{}
+		inferSessions[key] = state
+		log.Debug().Str("session", name).Msg("Map tracking session created")
+	}
+	return state
+}
+
+// resetInferStates discards the tracking state of the given session,
+// or of all sessions of the tasker if name is nil. Returns the number of sessions discarded.
+func resetInferStates(tasker *maa.Tasker, name *string) int {
+	inferSessionsMu.Lock()
+	defer inferSessionsMu.Unlock()
+
+	count := 0
+	for key := range inferSessions {
+		if key.tasker == *tasker && (name == nil || key.name == *name) {
+			delete(inferSessions, key)
+			count++
+		}
+	}
+	return count
+}
+
+// inferSessionSink resets the tracking sessions and the region hint of a tasker when it starts a new task
+type inferSessionSink struct{}
+
+var _ maa.TaskerEventSink = &inferSessionSink{}
+
+// OnTaskerTask handles tasker task events
+func (s *inferSessionSink) OnTaskerTask(tasker *maa.Tasker, event maa.EventStatus, detail maa.TaskerTaskDetail) {
+	if event != maa.EventStatusStarting || detail.Entry == "MaaTaskerPostStop" {
+		return
+	}
+	if count := resetInferStates(tasker, nil); count > 0 {
+		log.Debug().Uint64("task_id", detail.TaskID).Str("entry", detail.Entry).
+			Int("sessionsCount", count).Msg("Map tracking sessions reset on task starting")
+	}
+	if clearRegionHint(tasker) {
+		log.Debug().Uint64("task_id", detail.TaskID).Str("entry", detail.Entry).Msg("Map region hint cleared on task starting")
+	}
+}
+
+// MapTrackerReset is the custom action component that resets map tracking sessions
+type MapTrackerReset struct{}
+
+// MapTrackerResetParam represents the custom_action_param for MapTrackerReset
+type MapTrackerResetParam struct {
+	// Session is the name of the session to reset, where "" is the default session;
+	// all sessions of the tasker are reset if omitted.
+	Session *string `json:"session,omitempty"`
+}
+
+var _ maa.CustomActionRunner = &MapTrackerReset{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerReset) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	var param MapTrackerResetParam
+	if arg.CustomActionParam != "" {
+		if err := json.Unmarshal([]byte(arg.CustomActionParam), &param); err != nil {
+			log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerReset")
+			return false
+		}
+	}
+
+	count := resetInferStates(ctx.GetTasker(), param.Session)
+	if param.Session == nil {
+		log.Info().Int("sessionsCount", count).Msg("All map tracking sessions reset")
+	} else {
+		log.Info().Str("session", *param.Session).Int("sessionsCount", count).Msg("Map tracking session reset")
+	}
+	return true
+}
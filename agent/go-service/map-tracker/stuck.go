@@ -0,0 +1,97 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"math"
+)
+
+// StuckRecoveryType is a strategy to get the player out of a stuck condition
+type StuckRecoveryType string
+
+const (
+	// STUCK_RECOVERY_JUMP jumps once while keeping moving forward
+	STUCK_RECOVERY_JUMP StuckRecoveryType = "jump"
+	// STUCK_RECOVERY_STRAFE_LEFT moves left for a while while keeping moving forward
+	STUCK_RECOVERY_STRAFE_LEFT StuckRecoveryType = "strafe_left"
+	// STUCK_RECOVERY_STRAFE_RIGHT moves right for a while while keeping moving forward
+	STUCK_RECOVERY_STRAFE_RIGHT StuckRecoveryType = "strafe_right"
+	// STUCK_RECOVERY_BACK_OFF stops, moves backward for a while, then approaches the target again
+	STUCK_RECOVERY_BACK_OFF StuckRecoveryType = "back_off"
+	// STUCK_RECOVERY_REPLAN goes back to the nearest earlier target point, then follows the path again
+	STUCK_RECOVERY_REPLAN StuckRecoveryType = "replan"
+	// STUCK_RECOVERY_RESET_CAMERA resets the camera and the adaptive rotation state
+	STUCK_RECOVERY_RESET_CAMERA StuckRecoveryType = "reset_camera"
+)
+
+// validateStuckRecovery validates the stuck recovery chain
+func validateStuckRecovery(chain []StuckRecoveryType) error {
+	for i, s := range chain {
+		switch s {
+		case STUCK_RECOVERY_JUMP, STUCK_RECOVERY_STRAFE_LEFT, STUCK_RECOVERY_STRAFE_RIGHT,
+			STUCK_RECOVERY_BACK_OFF, STUCK_RECOVERY_REPLAN, STUCK_RECOVERY_RESET_CAMERA:
+		default:
+			return fmt.Errorf("unknown stuck_recovery strategy %q at index %d", s, i)
+		}
+	}
+	return nil
+}
+
+// stuckRecoveryDuration returns the time in milliseconds that recoverStuck takes to perform the strategy
+func stuckRecoveryDuration(strategy StuckRecoveryType) int64 {
+	switch strategy {
+	case STUCK_RECOVERY_JUMP:
+		return 100
+	case STUCK_RECOVERY_STRAFE_LEFT, STUCK_RECOVERY_STRAFE_RIGHT:
+		return STUCK_STRAFE_DURATION_MS + 25
+	case STUCK_RECOVERY_BACK_OFF:
+		return 25 + STUCK_BACK_OFF_DURATION_MS + 25 + 200
+	case STUCK_RECOVERY_RESET_CAMERA:
+		return 25
+	}
+	return 0
+}
+
+// stuckChainTimeout returns the shortest stuck timeout in milliseconds for the whole recovery chain to run.
+// Each strategy is tried after being stuck for the threshold since the previous one has finished,
+// and the chain is exhausted after the threshold once more.
+func stuckChainTimeout(threshold int64, chain []StuckRecoveryType) int64 {
+	timeout := int64(len(chain)+1) * threshold
+	for _, s := range chain {
+		timeout += stuckRecoveryDuration(s)
+	}
+	return timeout
+}
+
+// recoverStuck performs a stuck recovery strategy that does not change the current target
+func (m *mover) recoverStuck(strategy StuckRecoveryType) {
+	mv := m.mv
+	switch strategy {
+	case STUCK_RECOVERY_JUMP:
+		mv.Jump(100)
+	case STUCK_RECOVERY_STRAFE_LEFT:
+		mv.Step(MOVE_LEFT, STUCK_STRAFE_DURATION_MS, 25)
+	case STUCK_RECOVERY_STRAFE_RIGHT:
+		mv.Step(MOVE_RIGHT, STUCK_STRAFE_DURATION_MS, 25)
+	case STUCK_RECOVERY_BACK_OFF:
+		mv.Stop(25)
+		mv.Step(MOVE_BACKWARD, STUCK_BACK_OFF_DURATION_MS, 25)
+		m.resetMovement()
+	case STUCK_RECOVERY_RESET_CAMERA:
+		mv.ResetCamera(25)
+		m.rotationSpeed = ROTATION_DEFAULT_SPEED
+		m.rotAdjState, m.rotAdjStateCache = nil, nil
+	}
+}
+
+// nearestEarlierTarget returns the index of the target point before the given index
+// that is closest to the current location, or -1 if there is none
+func nearestEarlierTarget(path []MapTrackerPoint, index, curX, curY int) int {
+	best, bestDist := -1, math.MaxFloat64
+	for j := range index {
+		if d := math.Hypot(float64(curX-path[j].X), float64(curY-path[j].Y)); d < bestDist {
+			best, bestDist = j, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"image"
+	"testing"
+)
+
+func TestStuckTimeoutDefault(t *testing.T) {
+	tests := []struct {
+		name  string
+		param string // extra fields of the parameters
+		want  int64
+	}{
+		{"default chain", ``, 7*2000 + 100 + 625 + 625 + 1050 + 0 + 25},
+		{"short chain", `, "stuck_recovery": ["jump"]`, 10000},
+		{"empty chain", `, "stuck_recovery": []`, 10000},
+		{"long threshold", `, "stuck_threshold": 5000, "stuck_recovery": ["jump", "replan"]`, 3*5000 + 100},
+		{"explicit timeout", `, "stuck_timeout": 3000`, 3000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			param, err := (&MapTrackerMove{}).parseParam(`{"map_name": "map01", "path": [[10, 10], [50, 50]]` + tt.param + `}`)
+			if err != nil {
+				t.Fatalf("parseParam(%s) failed: %v", tt.param, err)
+			}
+			if param.StuckTimeout != tt.want {
+				t.Errorf("stuck_timeout = %d, want %d", param.StuckTimeout, tt.want)
+			}
+			// Recoveries are tried every threshold after the previous one has finished, the timeout must not come first
+			if tt.name != "explicit timeout" {
+				elapsed := int64(0)
+				for _, s := range param.StuckRecovery {
+					elapsed += param.StuckThreshold
+					if elapsed > param.StuckTimeout {
+						t.Errorf("strategy %q starts at %d, after the timeout %d", s, elapsed, param.StuckTimeout)
+					}
+					elapsed += stuckRecoveryDuration(s)
+				}
+			}
+		})
+	}
+}
+
+func TestLintStuckTimeout(t *testing.T) {
+	l := &Linter{mapSizes: map[string]image.Point{"map01": image.Pt(100, 100)}, bboxes: map[string][]int{}}
+	tests := []struct {
+		name    string
+		param   string // extra fields of the parameters
+		wantErr bool
+	}{
+		{"default", ``, false},
+		{"enough for the default chain", `, "stuck_timeout": 16425`, false},
+		{"too short for the default chain", `, "stuck_timeout": 10000`, true},
+		{"enough for a short chain", `, "stuck_timeout": 4200, "stuck_recovery": ["jump"]`, false},
+		{"too short for a short chain", `, "stuck_timeout": 4000, "stuck_recovery": ["jump"]`, true},
+		{"threshold above timeout", `, "stuck_threshold": 3000, "stuck_timeout": 2500, "stuck_recovery": []`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := l.LintMove(`{"map_name": "map01", "path": [[10, 10], [50, 50]]` + tt.param + `}`)
+			found := false
+			for _, issue := range issues {
+				if issue.Code == "invalid_param" {
+					t.Fatalf("LintMove(%s) failed: %s", tt.param, issue.Message)
+				}
+				if issue.Code == "stuck_timeout_too_short" {
+					found = true
+					if issue.Severity != LINT_ERROR {
+						t.Errorf("issue %+v is not an error", issue)
+					}
+				}
+			}
+			if found != tt.wantErr {
+				t.Errorf("LintMove(%s) reported stuck_timeout_too_short %v, want %v: %+v", tt.param, found, tt.wantErr, issues)
+			}
+		})
+	}
+}
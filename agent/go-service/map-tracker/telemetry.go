@@ -0,0 +1,232 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// TelemetryEvent is the kind of a telemetry record
+type TelemetryEvent string
+
+const (
+	// TELEMETRY_INFER is one MapTrackerInfer inference, hit or not
+	TELEMETRY_INFER TelemetryEvent = "infer"
+	// TELEMETRY_MOVE_START is the start of a MapTrackerMove run
+	TELEMETRY_MOVE_START TelemetryEvent = "move_start"
+	// TELEMETRY_MOVE_END is the end of a MapTrackerMove run, with its outcome
+	TELEMETRY_MOVE_END TelemetryEvent = "move_end"
+	// TELEMETRY_MODE is a movement mode change ("walk", "run" or "sprint")
+	TELEMETRY_MODE TelemetryEvent = "mode"
+	// TELEMETRY_ROTATE is the start of a rotation adjustment
+	TELEMETRY_ROTATE TelemetryEvent = "rotate"
+	// TELEMETRY_ROTATION_SPEED is an update of the adaptive rotation speed
+	TELEMETRY_ROTATION_SPEED TelemetryEvent = "rotation_speed"
+	// TELEMETRY_STUCK is a stuck condition, with the recovery strategy tried or the reason to give up
+	TELEMETRY_STUCK TelemetryEvent = "stuck"
+)
+
+// TelemetryRecord represents one line of the telemetry stream.
+// Fields not related to the event are omitted, except the target index, where 0 is meaningful.
+// The coordinates are pointers for the same reason, and are only present if the event has a location.
+type TelemetryRecord struct {
+	TimeMs  int64          `json:"t"`                 // Unix time in ms
+	Event   TelemetryEvent `json:"event"`             // Event kind
+	Entry   string         `json:"entry,omitempty"`   // Entry of the running task
+	Node    string         `json:"node,omitempty"`    // Node running the recognition or action
+	Session string         `json:"session,omitempty"` // Tracking session name
+	Move    int64          `json:"move,omitempty"`    // Start time in ms of the MapTrackerMove run, identifies the run
+
+	Infer *MapTrackerInferResult `json:"infer,omitempty"` // Inference result, nil if not hit
+
+	MapName string `json:"mapName,omitempty"` // Map of the current segment
+	Index   int    `json:"index"`             // Index of the current target point
+	X       *int   `json:"x,omitempty"`       // Current X coordinate, nil if the event has no location
+	Y       *int   `json:"y,omitempty"`       // Current Y coordinate, nil if the event has no location
+
+	Mode          string  `json:"mode,omitempty"`          // New movement mode
+	PrevMode      string  `json:"prevMode,omitempty"`      // Previous movement mode
+	DeltaRot      float64 `json:"deltaRot,omitempty"`      // Rotation difference to correct in degrees
+	Dist          float64 `json:"dist,omitempty"`          // Distance to the steering target
+	RotationSpeed float64 `json:"rotationSpeed,omitempty"` // Adaptive rotation speed after the event
+	IdealSpeed    float64 `json:"idealSpeed,omitempty"`    // Rotation speed measured from the last adjustment
+	Strategy      string  `json:"strategy,omitempty"`      // Stuck recovery strategy
+	Attempt       int     `json:"attempt,omitempty"`       // Stuck recovery attempt, starting from 1
+
+	Ok     *bool  `json:"ok,omitempty"`     // Outcome of the run
+	Reason string `json:"reason,omitempty"` // Reason of giving up
+}
+
+// telemetryWriter appends telemetry records as JSON lines to one file per process
+type telemetryWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+	err  error
+}
+
+var telemetry telemetryWriter
+
+// record writes a record, opening the telemetry file on first use.
+// Failures are logged once and the following records are dropped.
+func (w *telemetryWriter) record(rec *TelemetryRecord) {
+	if rec.TimeMs == 0 {
+		rec.TimeMs = time.Now().UnixMilli()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.err != nil {
+		return
+	}
+	if w.file == nil {
+		if w.err = w.open(); w.err != nil {
+			log.Warn().Err(w.err).Msg("Failed to open telemetry file, telemetry disabled")
+			return
+		}
+	}
+	if err := w.enc.Encode(rec); err != nil {
+		log.Warn().Err(err).Msg("Failed to write telemetry record")
+	}
+}
+
+func (w *telemetryWriter) open() error {
+	if err := os.MkdirAll(TELEMETRY_DIR, 0755); err != nil {
+		return err
+	}
+	path := filepath.Join(TELEMETRY_DIR, fmt.Sprintf("%s.jsonl", time.Now().Format("20060102-150405.000")))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.enc = f, json.NewEncoder(f)
+	log.Info().Str("path", path).Msg("Telemetry file opened")
+	return nil
+}
+
+// taskEntry returns the entry of the task running in ctx, or an empty string if unavailable
+func taskEntry(ctx *maa.Context) string {
+	if detail, err := ctx.GetTaskJob().GetDetail(); err == nil {
+		return detail.Entry
+	}
+	return ""
+}
+
+// moveTelemetry records the control decisions of one MapTrackerMove run
+type moveTelemetry struct {
+	entry   string
+	node    string
+	session string
+	startMs int64
+	mapName string
+	index   int
+	reason  string
+}
+
+// newMoveTelemetry creates the telemetry of a run and records its start
+func newMoveTelemetry(ctx *maa.Context, node string, param *MapTrackerMoveParam) *moveTelemetry {
+	t := &moveTelemetry{entry: taskEntry(ctx), node: node, session: param.Session, startMs: time.Now().UnixMilli()}
+	t.record(&TelemetryRecord{Event: TELEMETRY_MOVE_START})
+	return t
+}
+
+// record fills the run fields of a record and writes it, no-op if t is nil
+func (t *moveTelemetry) record(rec *TelemetryRecord) {
+	if t == nil {
+		return
+	}
+	rec.Entry, rec.Node, rec.Session, rec.Move = t.entry, t.node, t.session, t.startMs
+	if rec.MapName == "" {
+		rec.MapName = t.mapName
+	}
+	telemetry.record(rec)
+}
+
+// target sets the current target point the following records belong to, no-op if t is nil
+func (t *moveTelemetry) target(mapName string, index int) {
+	if t == nil {
+		return
+	}
+	t.mapName, t.index = mapName, index
+}
+
+// mode records a movement mode change at loc, or without a location if loc is nil.
+// No-op if t is nil or the mode is unchanged.
+func (t *moveTelemetry) mode(prev, cur *PlayerMovement, loc *[2]int) {
+	if t == nil || prev == cur {
+		return
+	}
+	rec := &TelemetryRecord{Event: TELEMETRY_MODE, Index: t.index, Mode: movementName(cur), PrevMode: movementName(prev)}
+	if loc != nil {
+		rec.X, rec.Y = &loc[0], &loc[1]
+	}
+	t.record(rec)
+}
+
+// rotate records the start of a rotation adjustment, no-op if t is nil
+func (t *moveTelemetry) rotate(x, y int, deltaRot, dist, rotationSpeed float64, movement *PlayerMovement) {
+	if t == nil {
+		return
+	}
+	t.record(&TelemetryRecord{Event: TELEMETRY_ROTATE, Index: t.index, X: &x, Y: &y,
+		DeltaRot: deltaRot, Dist: dist, RotationSpeed: rotationSpeed, Mode: movementName(movement)})
+}
+
+// rotationSpeed records an update of the adaptive rotation speed, no-op if t is nil
+func (t *moveTelemetry) rotationSpeed(idealSpeed, newSpeed float64) {
+	if t == nil {
+		return
+	}
+	t.record(&TelemetryRecord{Event: TELEMETRY_ROTATION_SPEED, Index: t.index,
+		IdealSpeed: idealSpeed, RotationSpeed: newSpeed})
+}
+
+// stuck records a stuck condition with the recovery strategy tried, or the reason to give up, no-op if t is nil
+func (t *moveTelemetry) stuck(x, y int, strategy StuckRecoveryType, attempt int, reason string) {
+	if t == nil {
+		return
+	}
+	t.record(&TelemetryRecord{Event: TELEMETRY_STUCK, Index: t.index, X: &x, Y: &y,
+		Strategy: string(strategy), Attempt: attempt, Reason: reason})
+}
+
+// fail sets the reason recorded if the run fails, no-op if t is nil
+func (t *moveTelemetry) fail(reason string) {
+	if t == nil {
+		return
+	}
+	t.reason = reason
+}
+
+// end records the outcome of the run, no-op if t is nil
+func (t *moveTelemetry) end(ok bool) {
+	if t == nil {
+		return
+	}
+	rec := &TelemetryRecord{Event: TELEMETRY_MOVE_END, Index: t.index, Ok: &ok}
+	if !ok {
+		rec.Reason = t.reason
+	}
+	t.record(rec)
+}
+
+// movementName returns the name of a movement mode
+func movementName(movement *PlayerMovement) string {
+	switch movement {
+	case &MovementWalk:
+		return "walk"
+	case &MovementRun:
+		return "run"
+	case &MovementSprint:
+		return "sprint"
+	}
+	return ""
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MapTrackerMoveSegment represents a part of a path that lies on a single map or tier
+type MapTrackerMoveSegment struct {
+	// MapName is the name of the map or tier of this segment (required).
+	MapName string `json:"map_name"`
+	// Path is a sequence of points to follow on this map, each as [x, y] or an object with actions (required).
+	Path []MapTrackerPoint `json:"path"`
+	// Transition describes how to enter this segment from the previous one (ignored for the first segment).
+	Transition *MapTrackerTransition `json:"transition,omitempty"`
+}
+
+// TransitionType is the way the player moves from one segment to the next
+type TransitionType string
+
+const (
+	// TRANSITION_STAIRS keeps walking towards the first point of the next segment (stairs, slopes, doors)
+	TRANSITION_STAIRS TransitionType = "stairs"
+	// TRANSITION_ELEVATOR stops, interacts and waits for the elevator to arrive
+	TRANSITION_ELEVATOR TransitionType = "elevator"
+	// TRANSITION_TELEPORT stops and runs a pipeline task that performs the teleport (e.g. via SceneMapTeleport)
+	TRANSITION_TELEPORT TransitionType = "teleport"
+	// TRANSITION_ZIPLINE stops, interacts and waits for the zipline ride to finish
+	TRANSITION_ZIPLINE TransitionType = "zipline"
+)
+
+// MapTrackerTransition represents a typed transition between two segments
+type MapTrackerTransition struct {
+	// Type is the transition type, defaults to "stairs".
+	Type TransitionType `json:"type,omitempty"`
+	// Task is a pipeline node to run for the transition, required by "teleport".
+	// For "elevator" and "zipline" it replaces the default interaction key press.
+	Task string `json:"task,omitempty"`
+	// Timeout is the maximum time in milliseconds to wait for the next map to be reported.
+	Timeout int64 `json:"timeout,omitempty"`
+}
+
+// validateSegments validates the segments and sets transition defaults
+func validateSegments(segments []MapTrackerMoveSegment) error {
+	for i := range segments {
+		seg := &segments[i]
+		if seg.MapName == "" {
+			return fmt.Errorf("map_name is required for segment at index %d", i)
+		}
+		if len(seg.Path) == 0 {
+			return fmt.Errorf("path is required for segment at index %d", i)
+		}
+		if i == 0 {
+			seg.Transition = nil
+			continue
+		}
+
+		if seg.Transition == nil {
+			seg.Transition = &MapTrackerTransition{}
+		}
+		tr := seg.Transition
+		switch tr.Type {
+		case "":
+			tr.Type = TRANSITION_STAIRS
+		case TRANSITION_STAIRS, TRANSITION_ELEVATOR, TRANSITION_ZIPLINE:
+		case TRANSITION_TELEPORT:
+			if tr.Task == "" {
+				return fmt.Errorf("task is required for teleport transition of segment at index %d", i)
+			}
+		default:
+			return fmt.Errorf("unknown transition type %q for segment at index %d", tr.Type, i)
+		}
+		if tr.Timeout < 0 {
+			return fmt.Errorf("transition timeout must be non-negative for segment at index %d", i)
+		} else if tr.Timeout == 0 {
+			tr.Timeout = TRANSITION_DEFAULT_TIMEOUT_MS
+		}
+	}
+	return nil
+}
+
+// transit performs the transition from one segment to the next,
+// and waits until MapTrackerInfer reports the map of the next segment
+func (m *mover) transit(from, to *MapTrackerMoveSegment) bool {
+	ctx, ctrl, mv := m.ctx, m.ctrl, m.mv
+	tr := to.Transition
+	log.Info().Str("type", string(tr.Type)).Str("from", from.MapName).Str("to", to.MapName).Msg("Starting segment transition")
+
+	// Trigger the transition
+	switch tr.Type {
+	case TRANSITION_STAIRS:
+		mv.Forward(25)
+	case TRANSITION_ELEVATOR, TRANSITION_ZIPLINE, TRANSITION_TELEPORT:
+		mv.Stop(100)
+		if tr.Task != "" {
+			if err := runTask(ctx, tr.Task); err != nil {
+				log.Error().Err(err).Str("task", tr.Task).Msg("Failed to run transition task")
+				return false
+			}
+		} else {
+			mv.Interact(100)
+		}
+	}
+
+	// Wait for the next map to be reported
+	loopInterval := time.Duration(INFER_INTERVAL_MS) * time.Millisecond
+	startTime := time.Now()
+	target := to.Path[0]
+	for {
+		time.Sleep(loopInterval)
+
+		if ctx.GetTasker().Stopping() {
+			log.Warn().Msg("Task is stopping, exiting segment transition")
+			mv.Stop(25)
+			return false
+		}
+		if time.Since(startTime).Milliseconds() > tr.Timeout {
+			log.Error().Str("to", to.MapName).Msg("Segment transition timeout")
+			mv.Stop(25)
+			return false
+		}
+
+		result, err := doInfer(ctx, ctrl, m.param, from.MapName, to.MapName)
+		if err != nil {
+			continue
+		}
+		if result.MapName == to.MapName {
+			log.Info().Str("map", to.MapName).Int("x", result.X).Int("y", result.Y).Msg("Segment transition completed")
+			break
+		}
+
+		// Keep heading to the next segment while walking across the layers
+		if tr.Type == TRANSITION_STAIRS {
+			deltaRot := calcDeltaRotation(result.Rot, calcTargetRotation(result.X, result.Y, target.X, target.Y))
+			if math.Abs(deltaRot) > m.param.RotationLowerThreshold {
+				m.rotateCamera(deltaRot, 75, 25)
+				mv.ResetCamera(25)
+			}
+		}
+	}
+
+	// Non-walking transitions leave the player standing still
+	if tr.Type != TRANSITION_STAIRS {
+		m.resetMovement()
+	}
+	return true
+}
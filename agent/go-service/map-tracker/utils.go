@@ -2,6 +2,7 @@
 package maptracker
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/MaaXYZ/maa-framework-go/v4"
@@ -53,16 +54,21 @@ func (aw *ActionWrapper) KeyTypeSync(keyCode int, delayMillis int) {
 	time.Sleep(time.Duration(delayMillis) * time.Millisecond)
 }
 
-// RotateCamera performs a camera rotation via series of mouse-keyboard operations
-func (aw *ActionWrapper) RotateCamera(dx int, durationMillis, delayMillis int) {
-	cx, cy := WORK_W/2, WORK_H/2
-	aw.SwipeSync(cx, cy, dx, 0, durationMillis, delayMillis)
-}
+/* ******** Tasks ******** */
 
-func (aw *ActionWrapper) ResetCamera(delayMillis int) {
-	cx, cy := WORK_W/2, WORK_H/2
-	stepDelayMillis := delayMillis / 3
-	aw.KeyDownSync(KEY_ALT, stepDelayMillis)
-	aw.ClickSync(0, cx, cy, stepDelayMillis)
-	aw.KeyUpSync(KEY_ALT, stepDelayMillis)
+// runTask runs the pipeline node and waits for it,
+// returns an error if it could not be run or did not succeed
+func runTask(ctx *maa.Context, entry string) error {
+	detail, err := ctx.RunTask(entry)
+	if err != nil {
+		return err
+	}
+	if detail == nil || !detail.Status.Success() {
+		status := "unknown"
+		if detail != nil {
+			status = detail.Status.String()
+		}
+		return fmt.Errorf("task %s finished with status %s", entry, status)
+	}
+	return nil
 }
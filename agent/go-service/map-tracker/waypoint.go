@@ -0,0 +1,215 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MapTrackerPoint represents a point of a path, written as [x, y] or as {"pos": [x, y], "actions": [...]}
+type MapTrackerPoint struct {
+	X, Y int
+	// Actions is a sequence of actions to run when arriving at this point.
+	Actions []MapTrackerWaypointAction
+}
+
+// MapTrackerWaypointAction represents an action to run at a path point, exactly one field should be set
+type MapTrackerWaypointAction struct {
+	// Key is the name of a key to tap without stopping, e.g. "F" or "SPACE".
+	Key string `json:"key,omitempty"`
+	// WaitMs stops the player and waits for the given duration in milliseconds.
+	WaitMs int64 `json:"wait_ms,omitempty"`
+	// RunTask stops the player and runs the given pipeline node.
+	RunTask string `json:"run_task,omitempty"`
+	// StopAndFace stops the player and turns to face the given rotation in degrees.
+	StopAndFace *float64 `json:"stop_and_face,omitempty"`
+}
+
+// waypointKeys maps the key names usable in waypoint actions to virtual-key codes
+var waypointKeys = map[string]int{
+	"W":     KEY_W,
+	"A":     KEY_A,
+	"S":     KEY_S,
+	"D":     KEY_D,
+	"SHIFT": KEY_SHIFT,
+	"CTRL":  KEY_CTRL,
+	"ALT":   KEY_ALT,
+	"SPACE": KEY_SPACE,
+	"F":     KEY_F,
+}
+
+type mapTrackerPointObject struct {
+	Pos     []int                      `json:"pos"`
+	Actions []MapTrackerWaypointAction `json:"actions,omitempty"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (p *MapTrackerPoint) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var pos []int
+		if err := json.Unmarshal(data, &pos); err != nil {
+			return err
+		}
+		if len(pos) != 2 {
+			return fmt.Errorf("path point must be [x, y], got %s", data)
+		}
+		*p = MapTrackerPoint{X: pos[0], Y: pos[1]}
+		return nil
+	}
+
+	// Unknown keys are rejected, so that a misspelled "actions" is not silently ignored
+	var obj mapTrackerPointObject
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&obj); err != nil {
+		return fmt.Errorf("invalid path point %s: %w", data, err)
+	}
+	if obj.Pos == nil {
+		return fmt.Errorf("pos is required for path point with actions")
+	}
+	if len(obj.Pos) != 2 {
+		return fmt.Errorf("pos must be [x, y], got %v", obj.Pos)
+	}
+	for i := range obj.Actions {
+		if err := obj.Actions[i].validate(); err != nil {
+			return fmt.Errorf("invalid action at index %d of point %v: %w", i, obj.Pos, err)
+		}
+	}
+	*p = MapTrackerPoint{X: obj.Pos[0], Y: obj.Pos[1], Actions: obj.Actions}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (p MapTrackerPoint) MarshalJSON() ([]byte, error) {
+	if len(p.Actions) == 0 {
+		return json.Marshal([2]int{p.X, p.Y})
+	}
+	return json.Marshal(mapTrackerPointObject{Pos: []int{p.X, p.Y}, Actions: p.Actions})
+}
+
+func (a *MapTrackerWaypointAction) validate() error {
+	count := 0
+	if a.Key != "" {
+		a.Key = strings.ToUpper(a.Key)
+		if _, ok := waypointKeys[a.Key]; !ok {
+			return fmt.Errorf("unknown key %q", a.Key)
+		}
+		count++
+	}
+	if a.WaitMs < 0 {
+		return fmt.Errorf("wait_ms must be non-negative")
+	} else if a.WaitMs > 0 {
+		count++
+	}
+	if a.RunTask != "" {
+		count++
+	}
+	if a.StopAndFace != nil {
+		if *a.StopAndFace < 0 || *a.StopAndFace >= 360 {
+			return fmt.Errorf("stop_and_face must be in range [0, 360)")
+		}
+		count++
+	}
+	if count != 1 {
+		return fmt.Errorf("exactly one of key, wait_ms, run_task and stop_and_face must be set")
+	}
+	return nil
+}
+
+// makePath converts coordinate pairs to path points without actions
+func makePath(coords [][2]int) []MapTrackerPoint {
+	path := make([]MapTrackerPoint, len(coords))
+	for i, c := range coords {
+		path[i] = MapTrackerPoint{X: c[0], Y: c[1]}
+	}
+	return path
+}
+
+// runWaypointActions runs the actions of a reached path point in order.
+// The player is left standing still if any action requires stopping.
+func (m *mover) runWaypointActions(mapName string, index int, point *MapTrackerPoint) bool {
+	if len(point.Actions) == 0 {
+		return true
+	}
+	ctx, mv := m.ctx, m.mv
+	log.Info().Str("map", mapName).Int("index", index).Int("actionsCount", len(point.Actions)).Msg("Running waypoint actions")
+
+	stopped := false
+	stop := func() {
+		if !stopped {
+			mv.Stop(100)
+			stopped = true
+		}
+	}
+
+	for _, action := range point.Actions {
+		if ctx.GetTasker().Stopping() {
+			log.Warn().Msg("Task is stopping, exiting waypoint actions")
+			mv.Stop(25)
+			return false
+		}
+
+		switch {
+		case action.Key != "":
+			log.Debug().Str("key", action.Key).Msg("Waypoint action: key")
+			mv.TapKey(action.Key, 100)
+		case action.WaitMs > 0:
+			log.Debug().Int64("waitMs", action.WaitMs).Msg("Waypoint action: wait")
+			stop()
+			deadline := time.Now().Add(time.Duration(action.WaitMs) * time.Millisecond)
+			for time.Now().Before(deadline) {
+				if ctx.GetTasker().Stopping() {
+					log.Warn().Msg("Task is stopping, exiting waypoint actions")
+					return false
+				}
+				time.Sleep(min(time.Until(deadline), time.Duration(INFER_INTERVAL_MS)*time.Millisecond))
+			}
+		case action.RunTask != "":
+			log.Debug().Str("task", action.RunTask).Msg("Waypoint action: run task")
+			stop()
+			if err := runTask(ctx, action.RunTask); err != nil {
+				log.Error().Err(err).Str("task", action.RunTask).Msg("Failed to run waypoint task")
+				return false
+			}
+		case action.StopAndFace != nil:
+			log.Debug().Float64("rot", *action.StopAndFace).Msg("Waypoint action: stop and face")
+			stop()
+			m.faceRotation(mapName, *action.StopAndFace)
+		}
+	}
+
+	if stopped {
+		m.resetMovement()
+	}
+	return true
+}
+
+// faceRotation turns the standing player towards the target rotation,
+// by rotating the camera and stepping forward briefly until the rotation is close enough
+func (m *mover) faceRotation(mapName string, targetRot float64) {
+	ctx, ctrl, mv := m.ctx, m.ctrl, m.mv
+	for attempt := range WAYPOINT_FACE_MAX_ATTEMPTS {
+		result, err := doInfer(ctx, ctrl, m.param, mapName)
+		if err != nil {
+			log.Debug().Err(err).Msg("Infer failed while facing rotation")
+			time.Sleep(time.Duration(INFER_INTERVAL_MS) * time.Millisecond)
+			continue
+		}
+		deltaRot := calcDeltaRotation(result.Rot, targetRot)
+		if math.Abs(deltaRot) <= m.param.RotationLowerThreshold {
+			log.Info().Float64("rot", result.Rot).Float64("targetRot", targetRot).Int("attempts", attempt).Msg("Target rotation faced")
+			return
+		}
+		m.rotateCamera(deltaRot, 75, 25)
+		mv.ResetCamera(25)
+		mv.Step(MOVE_FORWARD, 0, 100)
+	}
+	log.Warn().Float64("targetRot", targetRot).Msg("Failed to face target rotation, continuing")
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMapTrackerPointJSON(t *testing.T) {
+	face := 90.0
+	tests := []struct {
+		name    string
+		json    string
+		want    *MapTrackerPoint // nil if an error is expected
+		wantOut string           // marshaled form, empty if the same as json
+	}{
+		{"coordinate", `[12,34]`, &MapTrackerPoint{X: 12, Y: 34}, ""},
+		{"coordinate with spaces", ` [ 12 , 34 ] `, &MapTrackerPoint{X: 12, Y: 34}, `[12,34]`},
+		{"object without actions", `{"pos": [12, 34]}`, &MapTrackerPoint{X: 12, Y: 34}, `[12,34]`},
+		{
+			"object with actions",
+			`{"pos":[12,34],"actions":[{"stop_and_face":90},{"key":"F"},{"wait_ms":1500},{"run_task":"Pick"}]}`,
+			&MapTrackerPoint{X: 12, Y: 34, Actions: []MapTrackerWaypointAction{{StopAndFace: &face}, {Key: "F"}, {WaitMs: 1500}, {RunTask: "Pick"}}},
+			"",
+		},
+		{
+			"lowercase key",
+			`{"pos": [12, 34], "actions": [{"key": "space"}]}`,
+			&MapTrackerPoint{X: 12, Y: 34, Actions: []MapTrackerWaypointAction{{Key: "SPACE"}}},
+			`{"pos":[12,34],"actions":[{"key":"SPACE"}]}`,
+		},
+		{"missing pos", `{"actions": [{"key": "F"}]}`, nil, ""},
+		{"several fields in an action", `{"pos": [12, 34], "actions": [{"key": "F", "wait_ms": 500}]}`, nil, ""},
+		{"no field in an action", `{"pos": [12, 34], "actions": [{}]}`, nil, ""},
+		{"unknown key name", `{"pos": [12, 34], "actions": [{"key": "Q"}]}`, nil, ""},
+		{"rotation out of range", `{"pos": [12, 34], "actions": [{"stop_and_face": 360}]}`, nil, ""},
+		{"negative wait", `{"pos": [12, 34], "actions": [{"wait_ms": -1}]}`, nil, ""},
+		{"unknown field", `{"pos": [12, 34], "action": [{"key": "F"}]}`, nil, ""},
+		{"unknown action field", `{"pos": [12, 34], "actions": [{"key": "F", "delay": 100}]}`, nil, ""},
+		{"too many coordinates", `[12, 34, 56]`, nil, ""},
+		{"too few coordinates in pos", `{"pos": [12], "actions": [{"key": "F"}]}`, nil, ""},
+		{"not a point", `"12,34"`, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got MapTrackerPoint
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %+v, want error", tt.json, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unmarshal(%s) failed: %v", tt.json, err)
+			}
+			if !reflect.DeepEqual(got, *tt.want) {
+				t.Fatalf("Unmarshal(%s) = %+v, want %+v", tt.json, got, *tt.want)
+			}
+
+			out, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("Marshal(%+v) failed: %v", got, err)
+			}
+			wantOut := tt.wantOut
+			if wantOut == "" {
+				wantOut = tt.json
+			}
+			if string(out) != wantOut {
+				t.Errorf("Marshal(%+v) = %s, want %s", got, out, wantOut)
+			}
+		})
+	}
+}
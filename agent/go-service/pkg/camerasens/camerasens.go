@@ -0,0 +1,196 @@
+// Copyright (c) 2026 Harry Huang
+
+// Package camerasens stores the camera rotation sensitivity of each controller,
+// i.e. how many degrees of yaw a horizontal swipe of some pixels produces,
+// so that all components rotating the camera agree on the in-game sensitivity setting.
+package camerasens
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// File is where the sensitivities are stored, relative to the working directory
+const File = "debug/camera_sensitivity.json"
+
+const (
+	defaultPixelsPerDegree = 2.0  // Swipe pixels per degree of yaw assumed without calibration
+	fitMinExponent         = 0.5  // Min exponent to search for when fitting
+	fitMaxExponent         = 1.5  // Max exponent to search for when fitting
+	fitExponentStep        = 0.01 // Step of the exponent search
+	fitDeadZoneStep        = 0.5  // Step of the dead zone search (px)
+)
+
+// Sensitivity models the yaw produced by a horizontal swipe of px pixels as
+// sign(px) * DegPerPx * max(0, |px| - DeadZone) ^ Exponent
+type Sensitivity struct {
+	DegPerPx float64 `json:"deg_per_px"` // Gain of the model
+	DeadZone float64 `json:"dead_zone"`  // Swipe pixels that produce no rotation
+	Exponent float64 `json:"exponent"`   // Non-linearity, 1 for a linear response
+	RMS      float64 `json:"rms"`        // RMS fitting error in degrees, 0 if not fitted
+}
+
+// Default is the sensitivity assumed for controllers without calibration
+var Default = Sensitivity{DegPerPx: 1 / defaultPixelsPerDegree, Exponent: 1}
+
+// Sample represents one measured swipe
+type Sample struct {
+	Pixels  float64 `json:"pixels"`  // Signed swipe distance, positive to the right
+	Degrees float64 `json:"degrees"` // Signed yaw change, positive clockwise
+}
+
+// Yaw returns the yaw in degrees produced by a swipe of px pixels
+func (s *Sensitivity) Yaw(px float64) float64 {
+	mag := math.Abs(px) - s.DeadZone
+	if mag <= 0 {
+		return 0
+	}
+	return math.Copysign(s.DegPerPx*math.Pow(mag, s.Exponent), px)
+}
+
+// Pixels returns the swipe pixels producing a yaw of deg degrees
+func (s *Sensitivity) Pixels(deg float64) int {
+	if deg == 0 || s.DegPerPx <= 0 || s.Exponent <= 0 {
+		return 0
+	}
+	mag := math.Pow(math.Abs(deg)/s.DegPerPx, 1/s.Exponent) + s.DeadZone
+	return int(math.Round(math.Copysign(mag, deg)))
+}
+
+// Fit fits the sensitivity model to the samples by least squares,
+// searching the dead zone below the smallest swipe and the exponent within a plausible range
+func Fit(samples []Sample) (*Sensitivity, error) {
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("at least 2 samples are required, got %d", len(samples))
+	}
+	minPx := math.Inf(1)
+	for _, s := range samples {
+		minPx = min(minPx, math.Abs(s.Pixels))
+	}
+
+	var best *Sensitivity
+	bestSSE := math.Inf(1)
+	for d := 0.0; d < minPx; d += fitDeadZoneStep {
+		for p := fitMinExponent; p <= fitMaxExponent+1e-9; p += fitExponentStep {
+			// The gain minimizing the squared error has a closed form for a fixed dead zone and exponent
+			sxy, sxx := 0.0, 0.0
+			for _, s := range samples {
+				x := math.Pow(math.Abs(s.Pixels)-d, p)
+				y := s.Degrees
+				if s.Pixels < 0 {
+					y = -y
+				}
+				sxy += x * y
+				sxx += x * x
+			}
+			if sxx == 0 || sxy <= 0 {
+				continue
+			}
+			cand := Sensitivity{DegPerPx: sxy / sxx, DeadZone: d, Exponent: p}
+			sse := 0.0
+			for _, s := range samples {
+				e := cand.Yaw(s.Pixels) - s.Degrees
+				sse += e * e
+			}
+			if sse < bestSSE {
+				bestSSE, best = sse, &cand
+			}
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no rotation in the direction of the swipes")
+	}
+	best.RMS = math.Sqrt(bestSSE / float64(len(samples)))
+	return best, nil
+}
+
+var (
+	storeOnce sync.Once
+	store     map[string]Sensitivity
+	storeMu   sync.Mutex
+)
+
+// load loads the sensitivity file, the caller must hold storeMu
+func load() {
+	storeOnce.Do(func() {
+		store = make(map[string]Sensitivity)
+		data, err := os.ReadFile(File)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Warn().Err(err).Msg("Failed to read camera sensitivity file")
+			}
+			return
+		}
+		if err := json.Unmarshal(data, &store); err != nil {
+			log.Warn().Err(err).Msg("Failed to unmarshal camera sensitivity file, ignoring")
+			store = make(map[string]Sensitivity)
+			return
+		}
+		log.Info().Int("sensitivitiesCount", len(store)).Msg("Camera sensitivities loaded")
+	})
+}
+
+// Key identifies a controller at its current resolution, as swipes are given in 1280x720 coordinates
+// and scaled to the device. It also keys the mini-map calibrations of map-tracker.
+func Key(ctrl *maa.Controller) (string, error) {
+	uuid, err := ctrl.GetUUID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get controller uuid: %w", err)
+	}
+	w, h, err := ctrl.GetResolution()
+	if err != nil {
+		return "", fmt.Errorf("failed to get controller resolution: %w", err)
+	}
+	return fmt.Sprintf("%s@%dx%d", uuid, w, h), nil
+}
+
+// Get returns the calibrated sensitivity of the controller, or Default if not calibrated
+func Get(ctrl *maa.Controller) Sensitivity {
+	key, err := Key(ctrl)
+	if err != nil {
+		log.Debug().Err(err).Msg("Camera sensitivity unavailable, using default")
+		return Default
+	}
+
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	load()
+	if s, ok := store[key]; ok {
+		return s
+	}
+	return Default
+}
+
+// Set stores the sensitivity of the key, or discards it if s is nil
+func Set(key string, s *Sensitivity) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	load()
+
+	if s == nil {
+		delete(store, key)
+	} else {
+		store[key] = *s
+	}
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(File), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(File, data, 0644)
+}
+
+// SwipePixels returns the swipe pixels rotating the camera of the controller by deg degrees
+func SwipePixels(ctrl *maa.Controller, deg float64) int {
+	s := Get(ctrl)
+	return s.Pixels(deg)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2026 Harry Huang
+package camerasens
+
+import (
+	"math"
+	"testing"
+)
+
+// testSwipes are the swipe distances measured by rotation calibration, in both directions
+var testSwipes = []float64{15, 30, 60, 120, 240, -15, -30, -60, -120, -240}
+
+// testSamples returns samples of the sensitivity at testSwipes, with noise[i] added to the i-th yaw if given
+func testSamples(s Sensitivity, noise ...float64) []Sample {
+	samples := make([]Sample, len(testSwipes))
+	for i, px := range testSwipes {
+		samples[i] = Sample{Pixels: px, Degrees: s.Yaw(px)}
+		if i < len(noise) {
+			samples[i].Degrees += noise[i]
+		}
+	}
+	return samples
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []Sample
+		want    Sensitivity
+		exact   bool // whether the parameters must be recovered, otherwise only the predicted yaws are checked
+	}{
+		{"default", testSamples(Default), Default, true},
+		{"linear", testSamples(Sensitivity{DegPerPx: 0.8, Exponent: 1}), Sensitivity{DegPerPx: 0.8, Exponent: 1}, true},
+		{"dead zone", testSamples(Sensitivity{DegPerPx: 0.5, DeadZone: 4, Exponent: 1}), Sensitivity{DegPerPx: 0.5, DeadZone: 4, Exponent: 1}, true},
+		{"concave", testSamples(Sensitivity{DegPerPx: 1.2, DeadZone: 2, Exponent: 0.8}), Sensitivity{DegPerPx: 1.2, DeadZone: 2, Exponent: 0.8}, true},
+		{"convex", testSamples(Sensitivity{DegPerPx: 0.1, DeadZone: 1.5, Exponent: 1.3}), Sensitivity{DegPerPx: 0.1, DeadZone: 1.5, Exponent: 1.3}, true},
+		{
+			"noisy",
+			testSamples(Sensitivity{DegPerPx: 0.5, DeadZone: 3, Exponent: 1}, 0.4, -0.3, 0.5, -0.6, 0.8, -0.2, 0.3, -0.5, 0.7, -0.4),
+			Sensitivity{DegPerPx: 0.5, DeadZone: 3, Exponent: 1},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fit(tt.samples)
+			if err != nil {
+				t.Fatalf("Fit() failed: %v", err)
+			}
+			if tt.exact {
+				if math.Abs(got.DegPerPx-tt.want.DegPerPx) > tt.want.DegPerPx*0.01 ||
+					math.Abs(got.DeadZone-tt.want.DeadZone) > 1e-6 ||
+					math.Abs(got.Exponent-tt.want.Exponent) > 1e-6 ||
+					got.RMS > 0.01 {
+					t.Errorf("Fit() = %+v, want %+v", *got, tt.want)
+				}
+				return
+			}
+			for _, px := range testSwipes {
+				if d := math.Abs(got.Yaw(px) - tt.want.Yaw(px)); d > 1 {
+					t.Errorf("Fit() = %+v yaws %.2f at %v px, want %.2f", *got, got.Yaw(px), px, tt.want.Yaw(px))
+				}
+			}
+			if got.RMS <= 0 || got.RMS > 1 {
+				t.Errorf("Fit() RMS = %.3f, want within (0, 1]", got.RMS)
+			}
+		})
+	}
+}
+
+func TestFitErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []Sample
+	}{
+		{"no samples", nil},
+		{"single sample", []Sample{{Pixels: 60, Degrees: 30}}},
+		{"no rotation", []Sample{{Pixels: 30, Degrees: 0}, {Pixels: 60, Degrees: 0}, {Pixels: -60, Degrees: 0}}},
+		{"opposite rotation", []Sample{{Pixels: 30, Degrees: -15}, {Pixels: 60, Degrees: -30}}},
+		{"no swipe", []Sample{{Pixels: 0, Degrees: 0}, {Pixels: 60, Degrees: 30}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Fit(tt.samples); err == nil {
+				t.Errorf("Fit(%v) = %+v, want error", tt.samples, *got)
+			}
+		})
+	}
+}
+
+func TestSensitivityPixels(t *testing.T) {
+	tests := []struct {
+		name string
+		s    Sensitivity
+		deg  float64
+		want int
+	}{
+		{"default", Default, 45, 90},
+		{"default left", Default, -45, -90},
+		{"zero", Default, 0, 0},
+		{"dead zone", Sensitivity{DegPerPx: 0.5, DeadZone: 4, Exponent: 1}, 30, 64},
+		{"concave", Sensitivity{DegPerPx: 1, DeadZone: 2, Exponent: 0.5}, -10, -102},
+		{"uncalibrated", Sensitivity{}, 30, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.s.Pixels(tt.deg)
+			if got != tt.want {
+				t.Fatalf("Pixels(%v) = %d, want %d", tt.deg, got, tt.want)
+			}
+			// The swipe must produce the yaw back, up to the rounding of pixels
+			if tt.s.DegPerPx > 0 && math.Abs(tt.s.Yaw(float64(got))-tt.deg) > tt.s.DegPerPx*2 {
+				t.Errorf("Yaw(Pixels(%v)) = %.2f", tt.deg, tt.s.Yaw(float64(got)))
+			}
+		})
+	}
+}
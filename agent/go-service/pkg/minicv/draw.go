@@ -0,0 +1,109 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// DrawPoint sets a square dot of the given radius centered at (x, y), clipped to the image bounds
+func DrawPoint(img *image.RGBA, x, y, radius int, c color.RGBA) {
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			if (image.Point{x + dx, y + dy}).In(img.Rect) {
+				img.SetRGBA(x+dx, y+dy, c)
+			}
+		}
+	}
+}
+
+// DrawLine draws a line from (x0, y0) to (x1, y1) with the given half width, using Bresenham's algorithm
+func DrawLine(img *image.RGBA, x0, y0, x1, y1, halfWidth int, c color.RGBA) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	err := dx + dy
+	for {
+		DrawPoint(img, x0, y0, halfWidth, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		if e2 := 2 * err; e2 >= dy {
+			err += dy
+			x0 += sx
+		} else {
+			err += dx
+			y0 += sy
+		}
+	}
+}
+
+// DrawRect draws the outline of a rectangle
+func DrawRect(img *image.RGBA, r image.Rectangle, halfWidth int, c color.RGBA) {
+	x0, y0, x1, y1 := r.Min.X, r.Min.Y, r.Max.X-1, r.Max.Y-1
+	DrawLine(img, x0, y0, x1, y0, halfWidth, c)
+	DrawLine(img, x1, y0, x1, y1, halfWidth, c)
+	DrawLine(img, x1, y1, x0, y1, halfWidth, c)
+	DrawLine(img, x0, y1, x0, y0, halfWidth, c)
+}
+
+// DrawCircle draws the outline of a circle
+func DrawCircle(img *image.RGBA, cx, cy, radius, halfWidth int, c color.RGBA) {
+	steps := max(16, int(2*math.Pi*float64(radius)))
+	for k := range steps {
+		a := 2 * math.Pi * float64(k) / float64(steps)
+		x := cx + int(math.Round(float64(radius)*math.Sin(a)))
+		y := cy - int(math.Round(float64(radius)*math.Cos(a)))
+		DrawPoint(img, x, y, halfWidth, c)
+	}
+}
+
+// DrawArrow draws an arrow from (x, y) pointing to angle degrees, where 0 is up and increasing clockwise
+func DrawArrow(img *image.RGBA, x, y int, angle float64, length, halfWidth int, c color.RGBA) {
+	point := func(a float64, l float64) (int, int) {
+		rad := a * math.Pi / 180
+		return x + int(math.Round(l*math.Sin(rad))), y - int(math.Round(l*math.Cos(rad)))
+	}
+	tx, ty := point(angle, float64(length))
+	DrawLine(img, x, y, tx, ty, halfWidth, c)
+
+	// Arrow head, as two short lines back from the tip
+	head := float64(length) / 3
+	for _, side := range [2]float64{150, -150} {
+		rad := (angle + side) * math.Pi / 180
+		hx := tx + int(math.Round(head*math.Sin(rad)))
+		hy := ty - int(math.Round(head*math.Cos(rad)))
+		DrawLine(img, tx, ty, hx, hy, halfWidth, c)
+	}
+}
+
+// DrawText draws a single line of ASCII text with its top-left corner at (x, y) in a 7x13 bitmap font
+func DrawText(img *image.RGBA, x, y int, text string, c color.RGBA) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y+basicfont.Face7x13.Ascent),
+	}
+	d.DrawString(text)
+}
+
+// FillRect fills a rectangle, clipped to the image bounds
+func FillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
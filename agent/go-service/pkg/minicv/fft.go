@@ -0,0 +1,276 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"image"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+const (
+	fftMaxConcurrent = 2    // Max concurrent FFT matches, each holding two buffers of the padded region size
+	fftWorkers       = 4    // Goroutines transforming rows or columns of one FFT
+	fftCostPerPoint  = 10.0 // Cost of one point per FFT level, relative to one multiply-add of spatial matching
+)
+
+var (
+	fftSem     = make(chan struct{}, fftMaxConcurrent)
+	fftBufPool sync.Pool
+	fftPlans   sync.Map // FFT plans by transform length
+)
+
+// nextPow2 returns the smallest power of two not less than n
+func nextPow2(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << bits.Len(uint(n-1))
+}
+
+// getFFTBuffer returns a zeroed buffer of length n from the pool
+func getFFTBuffer(n int) []complex128 {
+	if v, ok := fftBufPool.Get().(*[]complex128); ok && cap(*v) >= n {
+		buf := (*v)[:n]
+		clear(buf)
+		return buf
+	}
+	return make([]complex128, n)
+}
+
+// putFFTBuffer returns a buffer to the pool
+func putFFTBuffer(buf []complex128) {
+	fftBufPool.Put(&buf)
+}
+
+// fftPlan holds the tables of a radix-2 FFT of one length
+type fftPlan struct {
+	rev []int          // Bit-reversed index of each index
+	fwd [][]complex128 // Twiddles exp(-2*pi*i*k/size) of each stage, for k in [0, size/2)
+	inv [][]complex128 // Conjugated twiddles of each stage
+}
+
+// getFFTPlan returns the plan of length n, cached by n
+func getFFTPlan(n int) *fftPlan {
+	if v, ok := fftPlans.Load(n); ok {
+		return v.(*fftPlan)
+	}
+	p := &fftPlan{rev: make([]int, n)}
+	shift := bits.UintSize - bits.Len(uint(n-1))
+	for i := 1; i < n; i++ {
+		p.rev[i] = int(bits.Reverse(uint(i)) >> shift)
+	}
+	for size := 2; size <= n; size <<= 1 {
+		fwd, inv := make([]complex128, size/2), make([]complex128, size/2)
+		for k := range fwd {
+			s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(size))
+			fwd[k] = complex(c, s)
+			inv[k] = complex(c, -s)
+		}
+		p.fwd = append(p.fwd, fwd)
+		p.inv = append(p.inv, inv)
+	}
+	fftPlans.Store(n, p)
+	return p
+}
+
+// twiddles returns the twiddles of each stage in the direction of the transform
+func (p *fftPlan) twiddles(inverse bool) [][]complex128 {
+	if inverse {
+		return p.inv
+	}
+	return p.fwd
+}
+
+// fftRow performs an in-place unnormalized radix-2 FFT of a, whose length is that of the plan
+func fftRow(a []complex128, p *fftPlan, inverse bool) {
+	for i, j := range p.rev {
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for _, tw := range p.twiddles(inverse) {
+		half := len(tw)
+		for start := 0; start < len(a); start += 2 * half {
+			lo := a[start : start+half]
+			hi := a[start+half : start+2*half]
+			hi = hi[:len(lo)]
+			tw = tw[:len(lo)]
+			for k := range lo {
+				u, v := lo[k], hi[k]
+				vr := real(v)*real(tw[k]) - imag(v)*imag(tw[k])
+				vi := real(v)*imag(tw[k]) + imag(v)*real(tw[k])
+				lo[k] = complex(real(u)+vr, imag(u)+vi)
+				hi[k] = complex(real(u)-vr, imag(u)-vi)
+			}
+		}
+	}
+}
+
+// fftCols performs in-place unnormalized radix-2 FFTs of the columns [x0, x1) of a w-wide row-major buffer,
+// whose height is that of the plan. The butterflies combine whole row segments,
+// so that the inner loop runs over contiguous memory.
+func fftCols(a []complex128, w, x0, x1 int, p *fftPlan, inverse bool) {
+	row := func(y int) []complex128 {
+		return a[y*w+x0 : y*w+x1]
+	}
+	for i, j := range p.rev {
+		if i < j {
+			ri, rj := row(i), row(j)
+			rj = rj[:len(ri)]
+			for x := range ri {
+				ri[x], rj[x] = rj[x], ri[x]
+			}
+		}
+	}
+	for _, tw := range p.twiddles(inverse) {
+		half := len(tw)
+		for start := 0; start < len(p.rev); start += 2 * half {
+			for k, t := range tw {
+				lo, hi := row(start+k), row(start+k+half)
+				hi = hi[:len(lo)]
+				for x := range lo {
+					u, v := lo[x], hi[x]
+					vr := real(v)*real(t) - imag(v)*imag(t)
+					vi := real(v)*imag(t) + imag(v)*real(t)
+					lo[x] = complex(real(u)+vr, imag(u)+vi)
+					hi[x] = complex(real(u)-vr, imag(u)-vi)
+				}
+			}
+		}
+	}
+}
+
+// fft2 performs an in-place unnormalized 2D FFT of a w*h row-major buffer, both powers of two
+func fft2(a []complex128, w, h int, inverse bool) {
+	rowPlan, colPlan := getFFTPlan(w), getFFTPlan(h)
+	var wg sync.WaitGroup
+	for id := range fftWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for y := id; y < h; y += fftWorkers {
+				fftRow(a[y*w:(y+1)*w], rowPlan, inverse)
+			}
+		}()
+	}
+	wg.Wait()
+	chunk := (w + fftWorkers - 1) / fftWorkers
+	for x0 := 0; x0 < w; x0 += chunk {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fftCols(a, w, x0, min(w, x0+chunk), colPlan, inverse)
+		}()
+	}
+	wg.Wait()
+}
+
+// fftLoad writes two channels of the rectangle (x, y, w, h) of an image into buf of row length n,
+// as real and imaginary parts after subtracting offset. A channel index below 0 keeps that part.
+func fftLoad(buf []complex128, n int, img *image.RGBA, x, y, w, h int, re, im int, offset float64) {
+	ipx, is := img.Pix, img.Stride
+	for j := range h {
+		off := (y+j)*is + x*4
+		row := buf[j*n : j*n+w]
+		for i := range row {
+			r, c := real(row[i]), imag(row[i])
+			if re >= 0 {
+				r = float64(ipx[off+re]) - offset
+			}
+			if im >= 0 {
+				c = float64(ipx[off+im]) - offset
+			}
+			row[i] = complex(r, c)
+			off += 4
+		}
+	}
+}
+
+// mulConj sets a[k] = a[k] * conj(b[k])
+func mulConj(a, b []complex128) {
+	b = b[:len(a)]
+	for k := range a {
+		a[k] *= complex(real(b[k]), -imag(b[k]))
+	}
+}
+
+// addSplitMulConj adds F(p) * conj(F(q)) to acc, given X = F(p + iq) of two real signals p and q on an n x m grid.
+// It uses F(p)[k] = (X[k] + conj(X[-k])) / 2 and F(q)[k] = (X[k] - conj(X[-k])) / 2i,
+// which saves one transform for correlating a single channel.
+func addSplitMulConj(acc, x []complex128, n, m int) {
+	for ky := range m {
+		row, mirror := x[ky*n:(ky+1)*n], x[((m-ky)%m)*n:((m-ky)%m+1)*n]
+		dst := acc[ky*n : (ky+1)*n]
+		for kx := range row {
+			xk, xm := row[kx], mirror[(n-kx)%n]
+			p := xk + complex(real(xm), -imag(xm))
+			q := complex(real(xk), -imag(xk)) - xm
+			// p * q * i / 4
+			r := p * q
+			dst[kx] += complex(-imag(r)/4, real(r)/4)
+		}
+	}
+}
+
+// nccScoresFFT computes the NCC scores of the template at all top-left corners within [minX, maxX] x [minY, maxY]
+// by cross-correlation in the frequency domain. Returns the scores row by row with maxX-minX+1 columns.
+func nccScoresFFT(img *image.RGBA, imgIntArr IntegralArray, tpl *image.RGBA, tplStats StatsResult, minX, minY, maxX, maxY int) []float64 {
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	cols, rows := maxX-minX+1, maxY-minY+1
+	rw, rh := cols+tw-1, rows+th-1
+	n, m := nextPow2(rw), nextPow2(rh)
+
+	fftSem <- struct{}{}
+	defer func() { <-fftSem }()
+
+	// Both sides are centered, as the centered template cancels any constant in the image
+	// and the smaller magnitudes keep the rounding error low. The transforms are in float64,
+	// since the error relative to the whole region would dominate the scores of nearly flat windows in float32.
+	// R and G are correlated together as one complex signal, since the real part of
+	// (R1 + iG1)(R2 - iG2) is R1R2 + G1G2, then B is added in the frequency domain.
+	imgMean := imgIntArr.GetAreaStats(minX, minY, rw, rh).Mean
+	tplMean := tplStats.Mean
+	acc, buf := getFFTBuffer(n*m), getFFTBuffer(n*m)
+	defer putFFTBuffer(acc)
+	defer putFFTBuffer(buf)
+
+	fftLoad(acc, n, img, minX, minY, rw, rh, 0, 1, imgMean)
+	fft2(acc, n, m, false)
+	fftLoad(buf, n, tpl, 0, 0, tw, th, 0, 1, tplMean)
+	fft2(buf, n, m, false)
+	mulConj(acc, buf)
+
+	clear(buf)
+	fftLoad(buf, n, img, minX, minY, rw, rh, 2, -1, imgMean)
+	fftLoad(buf, n, tpl, 0, 0, tw, th, -1, 2, tplMean)
+	fft2(buf, n, m, false)
+	addSplitMulConj(acc, buf, n, m)
+
+	fft2(acc, n, m, true)
+
+	scale := 1 / float64(n*m)
+	scores := make([]float64, cols*rows)
+	for y := range rows {
+		for x := range cols {
+			imgStats := imgIntArr.GetAreaStats(minX+x, minY+y, tw, th)
+			stdProd := imgStats.Std * tplStats.Std
+			if stdProd < 1e-12 {
+				continue
+			}
+			scores[y*cols+x] = real(acc[y*n+x]) * scale / stdProd
+		}
+	}
+	return scores
+}
+
+// useFFT reports whether cross-correlation in the frequency domain is estimated to be cheaper
+// than evaluating one of every step*step top-left corners within a cols x rows area spatially
+func useFFT(cols, rows, tw, th, step int) bool {
+	if cols <= 0 || rows <= 0 {
+		return false
+	}
+	spatial := float64(cols*rows) / float64(step*step) * float64(tw*th*3)
+	points := float64(nextPow2(cols+tw-1) * nextPow2(rows+th-1))
+	return fftCostPerPoint*points*math.Log2(points) < spatial
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+const (
+	houghPeaksFactor  = 8 // Center peaks to evaluate per requested candidate
+	houghRefineRadius = 2 // Neighborhood radius to refine each center peak
+)
+
+// CircleCandidate represents a detected circle, where Score is the edge support relative to its circumference
+type CircleCandidate struct {
+	X, Y, R int
+	Score   float64
+}
+
+// edgePoint represents a pixel on an edge with its normalized gradient direction
+type edgePoint struct {
+	x, y   int
+	gx, gy float64
+}
+
+// HoughCircles detects circles with radius in [minR, maxR] using the gradient Hough transform.
+// Circle centers are voted along the gradient directions of edge pixels first,
+// then the radius of each center is chosen by the edges that are radial around it.
+// Returns at most k candidates whose centers are at least minDist apart, sorted by score.
+func HoughCircles(img *image.RGBA, minR, maxR, k, minDist int) []CircleCandidate {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	if w < 3 || h < 3 || minR < 1 || maxR < minR {
+		return nil
+	}
+
+	// Grayscale
+	ipx, is := img.Pix, img.Stride
+	gray := make([]float64, w*h)
+	for y := range h {
+		off := y * is
+		for x := range w {
+			gray[y*w+x] = (float64(ipx[off]) + float64(ipx[off+1]) + float64(ipx[off+2])) / 3
+			off += 4
+		}
+	}
+
+	// Sobel gradients, keeping pixels above a quarter of the strongest gradient as edges
+	mags := make([]float64, w*h)
+	gxs := make([]float64, w*h)
+	gys := make([]float64, w*h)
+	maxMag := 0.0
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			p := func(dx, dy int) float64 { return gray[(y+dy)*w+x+dx] }
+			gx := p(1, -1) + 2*p(1, 0) + p(1, 1) - p(-1, -1) - 2*p(-1, 0) - p(-1, 1)
+			gy := p(-1, 1) + 2*p(0, 1) + p(1, 1) - p(-1, -1) - 2*p(0, -1) - p(1, -1)
+			i := y*w + x
+			gxs[i], gys[i], mags[i] = gx, gy, math.Hypot(gx, gy)
+			maxMag = max(maxMag, mags[i])
+		}
+	}
+	if maxMag < 1e-6 {
+		return nil
+	}
+	edges := make([]edgePoint, 0)
+	for i, m := range mags {
+		if m >= maxMag/4 {
+			edges = append(edges, edgePoint{i % w, i / w, gxs[i] / m, gys[i] / m})
+		}
+	}
+
+	// Vote for centers on both sides of each edge, as the circle may be brighter or darker than outside
+	acc := make([]int32, w*h)
+	for _, e := range edges {
+		for r := minR; r <= maxR; r++ {
+			for _, sign := range [2]float64{1, -1} {
+				cx := e.x + int(math.Round(sign*e.gx*float64(r)))
+				cy := e.y + int(math.Round(sign*e.gy*float64(r)))
+				if cx >= 0 && cx < w && cy >= 0 && cy < h {
+					acc[cy*w+cx]++
+				}
+			}
+		}
+	}
+
+	// Collect center peaks with non-maximum suppression
+	type peak struct {
+		x, y  int
+		votes int32
+	}
+	peaks := make([]peak, 0)
+	for y := range h {
+		for x := range w {
+			v := acc[y*w+x]
+			if v == 0 {
+				continue
+			}
+			isMax := true
+			for dy := -1; dy <= 1 && isMax; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx >= 0 && nx < w && ny >= 0 && ny < h && acc[ny*w+nx] > v {
+						isMax = false
+						break
+					}
+				}
+			}
+			if isMax {
+				peaks = append(peaks, peak{x, y, v})
+			}
+		}
+	}
+	sort.Slice(peaks, func(a, b int) bool {
+		return peaks[a].votes > peaks[b].votes
+	})
+
+	// Choose the best radius for the strongest centers, refining each center in its neighborhood
+	hist := make([]int, maxR-minR+3)
+	bestRadius := func(x, y int) (int, float64) {
+		clear(hist)
+		for _, e := range edges {
+			dx, dy := float64(e.x-x), float64(e.y-y)
+			d := math.Hypot(dx, dy)
+			ri := int(math.Round(d))
+			if ri < minR || ri > maxR {
+				continue
+			}
+			// Only count edges whose gradient is radial around the center
+			if math.Abs(dx*e.gx+dy*e.gy)/d < 0.9 {
+				continue
+			}
+			hist[ri-minR+1]++
+		}
+		bestR, bestScore := 0, 0.0
+		for r := minR; r <= maxR; r++ {
+			i := r - minR + 1
+			score := float64(hist[i-1]+hist[i]+hist[i+1]) / (2 * math.Pi * float64(r))
+			if score > bestScore {
+				bestR, bestScore = r, score
+			}
+		}
+		return bestR, bestScore
+	}
+
+	candidates := make([]CircleCandidate, 0, k*houghPeaksFactor)
+	for _, p := range peaks {
+		if len(candidates) >= k*houghPeaksFactor {
+			break
+		}
+		if tooClose(candidates, p.x, p.y, houghRefineRadius*2+1) {
+			continue
+		}
+		best := CircleCandidate{}
+		for dy := -houghRefineRadius; dy <= houghRefineRadius; dy++ {
+			for dx := -houghRefineRadius; dx <= houghRefineRadius; dx++ {
+				if r, score := bestRadius(p.x+dx, p.y+dy); score > best.Score {
+					best = CircleCandidate{p.x + dx, p.y + dy, r, score}
+				}
+			}
+		}
+		if best.R > 0 {
+			candidates = append(candidates, best)
+		}
+	}
+
+	sort.Slice(candidates, func(a, b int) bool {
+		return candidates[a].Score > candidates[b].Score
+	})
+	result := make([]CircleCandidate, 0, k)
+	for _, c := range candidates {
+		if len(result) >= k {
+			break
+		}
+		if !tooClose(result, c.X, c.Y, minDist) {
+			result = append(result, c)
+		}
+	}
+	for i := range result {
+		result[i].X += img.Rect.Min.X
+		result[i].Y += img.Rect.Min.Y
+	}
+	return result
+}
+
+// tooClose checks whether (x, y) is within minDist (Chebyshev distance) of any candidate
+func tooClose(candidates []CircleCandidate, x, y, minDist int) bool {
+	for _, c := range candidates {
+		if abs(c.X-x) < minDist && abs(c.Y-y) < minDist {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// testDisks draws filled disks [cx, cy, r] of color fg on a background of color bg
+func testDisks(rect image.Rectangle, bg, fg color.RGBA, disks ...[3]int) *image.RGBA {
+	img := image.NewRGBA(rect)
+	FillRect(img, rect, bg)
+	for _, d := range disks {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				if dx, dy := x-d[0], y-d[1]; dx*dx+dy*dy <= d[2]*d[2] {
+					img.SetRGBA(x, y, fg)
+				}
+			}
+		}
+	}
+	return img
+}
+
+func TestHoughCircles(t *testing.T) {
+	black := color.RGBA{0, 0, 0, 255}
+	white := color.RGBA{255, 255, 255, 255}
+	gray := color.RGBA{90, 100, 110, 255}
+	ring := image.NewRGBA(image.Rect(0, 0, 120, 120))
+	FillRect(ring, ring.Rect, black)
+	DrawCircle(ring, 62, 57, 35, 0, white)
+
+	tests := []struct {
+		name       string
+		img        *image.RGBA
+		minR, maxR int
+		k          int
+		want       [][3]int // expected circles in any order, nil if none should be found
+	}{
+		{"bright disk", testDisks(image.Rect(0, 0, 120, 120), black, white, [3]int{60, 58, 30}), 20, 40, 1, [][3]int{{60, 58, 30}}},
+		{"dark disk", testDisks(image.Rect(0, 0, 120, 120), white, gray, [3]int{55, 64, 25}), 20, 40, 1, [][3]int{{55, 64, 25}}},
+		{"outline", ring, 25, 45, 1, [][3]int{{62, 57, 35}}},
+		{"offset image", testDisks(image.Rect(200, 100, 320, 220), black, white, [3]int{250, 170, 28}), 20, 40, 1, [][3]int{{250, 170, 28}}},
+		{"two disks", testDisks(image.Rect(0, 0, 200, 100), black, white, [3]int{50, 50, 30}, [3]int{145, 48, 35}), 20, 40, 2, [][3]int{{50, 50, 30}, {145, 48, 35}}},
+		{"blank", testDisks(image.Rect(0, 0, 120, 120), gray, gray), 20, 40, 1, nil},
+		{"invalid radius range", testDisks(image.Rect(0, 0, 120, 120), black, white, [3]int{60, 58, 30}), 40, 20, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HoughCircles(tt.img, tt.minR, tt.maxR, tt.k, tt.minR)
+			if tt.want == nil {
+				if len(got) != 0 {
+					t.Fatalf("HoughCircles() = %v, want none", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("HoughCircles() = %v, want %d circles", got, len(tt.want))
+			}
+			for _, w := range tt.want {
+				found := false
+				for _, c := range got {
+					if abs(c.X-w[0]) <= 1 && abs(c.Y-w[1]) <= 1 && abs(c.R-w[2]) <= 1 {
+						found = true
+						if c.Score < 0.5 {
+							t.Errorf("circle %v has score %.2f, want at least 0.5", w, c.Score)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("HoughCircles() = %v, want one near %v", got, w)
+				}
+			}
+		})
+	}
+}
@@ -44,6 +44,42 @@ func ImageRotate(img *image.RGBA, angle float64) *image.RGBA {
 	return dst
 }
 
+// ImageRotateBilinear rotates an image by the given angle (degrees) around its center using bilinear interpolation,
+// which keeps small angle changes visible on small images, unlike ImageRotate
+func ImageRotateBilinear(img *image.RGBA, angle float64) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	cx, cy := float64(w)/2, float64(h)/2
+
+	rad := angle * math.Pi / 180.0
+	cos, sin := math.Cos(rad), math.Sin(rad)
+
+	dst := image.NewRGBA(img.Rect)
+	dpx, ds := dst.Pix, dst.Stride
+	ipx, is := img.Pix, img.Stride
+
+	for y := range h {
+		for x := range w {
+			// Sample at pixel centers
+			fx, fy := float64(x)+0.5-cx, float64(y)+0.5-cy
+			sx, sy := fx*cos+fy*sin+cx-0.5, -fx*sin+fy*cos+cy-0.5
+			x0, y0 := int(math.Floor(sx)), int(math.Floor(sy))
+			if x0 < 0 || y0 < 0 || x0+1 >= w || y0+1 >= h {
+				continue
+			}
+			ax, ay := sx-float64(x0), sy-float64(y0)
+			o00 := y0*is + x0*4
+			o01, o10, o11 := o00+4, o00+is, o00+is+4
+			dOff := y*ds + x*4
+			for c := range 4 {
+				top := float64(ipx[o00+c])*(1-ax) + float64(ipx[o01+c])*ax
+				bottom := float64(ipx[o10+c])*(1-ax) + float64(ipx[o11+c])*ax
+				dpx[dOff+c] = uint8(top*(1-ay) + bottom*ay + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
 // ImageScale scales an image by the given factor using bilinear interpolation
 func ImageScale(img *image.RGBA, scale float64) *image.RGBA {
 	if scale <= 0 {
@@ -4,6 +4,8 @@ import (
 	"image"
 )
 
+const matchScanStep = 3 // Step of the coarse pass of matchScan
+
 // ComputeNCC computes the normalized cross-correlation between a rectangle region in the haystack image
 // and a template image, using precomputed integral array for efficiency
 func ComputeNCC(img *image.RGBA, imgIntArr IntegralArray, tpl *image.RGBA, tplStats StatsResult, ox, oy int) float64 {
@@ -52,6 +54,32 @@ func MatchTemplate(
 	return MatchTemplateInArea(img, imgIntArr, tpl, tplStats, 0, 0, iw, ih)
 }
 
+// MatchMethod selects how MatchTemplateInAreaWith computes the scores
+type MatchMethod int
+
+const (
+	MatchAuto      MatchMethod = iota // Pick MatchFFT or MatchPacked by the estimated cost
+	MatchPacked                       // Coarse-to-fine scan over packed float32 images
+	MatchFFT                          // Exhaustive cross-correlation in the frequency domain
+	MatchReference                    // Coarse-to-fine scan with ComputeNCC
+)
+
+// String returns the name of the method
+func (m MatchMethod) String() string {
+	switch m {
+	case MatchAuto:
+		return "auto"
+	case MatchPacked:
+		return "packed"
+	case MatchFFT:
+		return "fft"
+	case MatchReference:
+		return "reference"
+	default:
+		return "unknown"
+	}
+}
+
 // MatchTemplateInArea performs template matching such that the center of the template
 // remains within the specified rectangle (ax, ay, aw, ah).
 // Returns (x, y, score) of the best match, where (x, y) is the top-left corner.
@@ -62,23 +90,79 @@ func MatchTemplateInArea(
 	tplStats StatsResult,
 	ax, ay, aw, ah int,
 ) (int, int, float64) {
-	iw, ih := img.Rect.Dx(), img.Rect.Dy()
-	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
-
-	// Calculate search bounds for the top-left corner (x, y)
-	minX, minY := max(0, ax-tw/2), max(0, ay-th/2)
-	maxX, maxY := min(iw-tw, ax+aw-tw/2), min(ih-th, ay+ah-th/2)
+	return MatchTemplateInAreaWith(MatchAuto, img, imgIntArr, tpl, tplStats, ax, ay, aw, ah)
+}
 
-	if minX > maxX || minY > maxY {
+// MatchTemplateInAreaWith is MatchTemplateInArea with the specified method.
+// All methods give the same scores up to floating-point rounding, while MatchFFT evaluates every position
+// and the others evaluate every third position followed by a fine pass around the best one.
+func MatchTemplateInAreaWith(
+	method MatchMethod,
+	img *image.RGBA,
+	imgIntArr IntegralArray,
+	tpl *image.RGBA,
+	tplStats StatsResult,
+	ax, ay, aw, ah int,
+) (int, int, float64) {
+	minX, minY, maxX, maxY, ok := matchBounds(img, tpl, ax, ay, aw, ah)
+	if !ok {
 		return 0, 0, 0.0
 	}
 
+	if method == MatchAuto {
+		method = ChooseMatchMethod(img, tpl, ax, ay, aw, ah)
+	}
+
+	switch method {
+	case MatchFFT:
+		scores := nccScoresFFT(img, imgIntArr, tpl, tplStats, minX, minY, maxX, maxY)
+		cols := maxX - minX + 1
+		bi, bs := 0, -1.0
+		for i, s := range scores {
+			if s > bs {
+				bi, bs = i, s
+			}
+		}
+		return minX + bi%cols, minY + bi/cols, bs
+	case MatchPacked:
+		m := newPackedMatcher(img, imgIntArr, tpl, tplStats, minX, minY, maxX, maxY)
+		return matchScan(m.score, minX, minY, maxX, maxY, matchScanStep)
+	default:
+		return matchScan(func(x, y int) float64 {
+			return ComputeNCC(img, imgIntArr, tpl, tplStats, x, y)
+		}, minX, minY, maxX, maxY, matchScanStep)
+	}
+}
+
+// ChooseMatchMethod returns the method that MatchTemplateInArea uses for the arguments,
+// i.e. MatchFFT if it is estimated to be faster than MatchPacked, otherwise MatchPacked
+func ChooseMatchMethod(img, tpl *image.RGBA, ax, ay, aw, ah int) MatchMethod {
+	minX, minY, maxX, maxY, ok := matchBounds(img, tpl, ax, ay, aw, ah)
+	if ok && useFFT(maxX-minX+1, maxY-minY+1, tpl.Rect.Dx(), tpl.Rect.Dy(), matchScanStep) {
+		return MatchFFT
+	}
+	return MatchPacked
+}
+
+// matchBounds returns the bounds [minX, maxX] x [minY, maxY] of the top-left corner of the template,
+// such that its center remains within the rectangle (ax, ay, aw, ah) and itself within the image
+func matchBounds(img, tpl *image.RGBA, ax, ay, aw, ah int) (minX, minY, maxX, maxY int, ok bool) {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	minX, minY = max(0, ax-tw/2), max(0, ay-th/2)
+	maxX, maxY = min(iw-tw, ax+aw-tw/2), min(ih-th, ay+ah-th/2)
+	return minX, minY, maxX, maxY, minX <= maxX && minY <= maxY
+}
+
+// matchScan evaluates every step-th top-left corner within [minX, maxX] x [minY, maxY] in parallel,
+// then every corner around the best one. Returns (x, y, score) of the best match.
+func matchScan(score func(x, y int) float64, minX, minY, maxX, maxY, step int) (int, int, float64) {
 	type result struct {
 		x, y int
 		s    float64
 	}
 
-	numWorkers, step := 4, 3
+	numWorkers := 4
 	resChan := make(chan result, numWorkers)
 
 	for i := range numWorkers {
@@ -86,7 +170,7 @@ func MatchTemplateInArea(
 			lx, ly, lm := 0, 0, -1.0
 			for y := minY + id*step; y <= maxY; y += numWorkers * step {
 				for x := minX; x <= maxX; x += step {
-					s := ComputeNCC(img, imgIntArr, tpl, tplStats, x, y)
+					s := score(x, y)
 					if s > lm {
 						lm, lx, ly = s, x, y
 					}
@@ -108,7 +192,7 @@ func MatchTemplateInArea(
 	// Fine-tuning pass around the best result
 	for y := max(minY, bc.y-step+1); y <= min(maxY, bc.y+step-1); y++ {
 		for x := max(minX, bc.x-step+1); x <= min(maxX, bc.x+step-1); x++ {
-			s := ComputeNCC(img, imgIntArr, tpl, tplStats, x, y)
+			s := score(x, y)
 			if s > fm {
 				fm, fx, fy = s, x, y
 			}
@@ -0,0 +1,148 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand/v2"
+	"testing"
+)
+
+// testTexture returns a w x h image of smooth random texture, with a flat square of side flat at its top-left corner.
+// The texture is blurred noise, so that scores vary smoothly over a few pixels like on real maps.
+func testTexture(w, h, flat int, seed uint64) *image.RGBA {
+	const blur = 3
+	rng := rand.New(rand.NewPCG(seed, 1))
+	noise := make([][3]float64, (w+2*blur)*(h+2*blur))
+	for i := range noise {
+		noise[i] = [3]float64{rng.Float64(), rng.Float64(), rng.Float64()}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			var sum [3]float64
+			for dy := 0; dy <= 2*blur; dy++ {
+				for dx := 0; dx <= 2*blur; dx++ {
+					n := noise[(y+dy)*(w+2*blur)+x+dx]
+					sum[0], sum[1], sum[2] = sum[0]+n[0], sum[1]+n[1], sum[2]+n[2]
+				}
+			}
+			k := 255.0 * 3 / float64((2*blur+1)*(2*blur+1))
+			c := func(v float64) uint8 { return uint8(max(0, min(255, (v*k)-255))) }
+			img.SetRGBA(x, y, color.RGBA{c(sum[0]), c(sum[1]), c(sum[2]), 255})
+		}
+	}
+	FillRect(img, image.Rect(0, 0, flat, flat), color.RGBA{120, 130, 140, 255})
+	return img
+}
+
+// testCrop returns a copy of the w x h region of the image at (x, y)
+func testCrop(img *image.RGBA, x, y, w, h int) *image.RGBA {
+	tpl := image.NewRGBA(image.Rect(0, 0, w, h))
+	for ty := range h {
+		copy(tpl.Pix[ty*tpl.Stride:ty*tpl.Stride+w*4], img.Pix[(y+ty)*img.Stride+x*4:])
+	}
+	return tpl
+}
+
+func TestNCCScoresMatchReference(t *testing.T) {
+	tests := []struct {
+		name           string
+		iw, ih, tw, th int
+		area           [4]int
+	}{
+		{"square", 96, 96, 16, 16, [4]int{0, 0, 96, 96}},
+		{"odd sizes", 83, 71, 13, 9, [4]int{0, 0, 83, 71}},
+		{"area inside", 120, 100, 20, 24, [4]int{40, 30, 30, 20}},
+		{"area clipped", 100, 100, 21, 21, [4]int{-20, 70, 60, 60}},
+		{"single position", 40, 40, 40, 40, [4]int{0, 0, 40, 40}},
+		{"single row", 64, 30, 15, 30, [4]int{0, 0, 64, 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := testTexture(tt.iw, tt.ih, 24, 1)
+			tpl := testCrop(testTexture(tt.tw+8, tt.th+8, 0, 2), 3, 5, tt.tw, tt.th)
+			intArr, tplStats := GetIntegralArray(img), GetImageStats(tpl)
+			minX, minY, maxX, maxY, ok := matchBounds(img, tpl, tt.area[0], tt.area[1], tt.area[2], tt.area[3])
+			if !ok {
+				t.Fatalf("matchBounds(%v) is empty", tt.area)
+			}
+
+			fftScores := nccScoresFFT(img, intArr, tpl, tplStats, minX, minY, maxX, maxY)
+			packed := newPackedMatcher(img, intArr, tpl, tplStats, minX, minY, maxX, maxY)
+			cols := maxX - minX + 1
+			if len(fftScores) != cols*(maxY-minY+1) {
+				t.Fatalf("nccScoresFFT() returned %d scores, want %d", len(fftScores), cols*(maxY-minY+1))
+			}
+			for y := minY; y <= maxY; y++ {
+				for x := minX; x <= maxX; x++ {
+					want := ComputeNCC(img, intArr, tpl, tplStats, x, y)
+					if got := packed.score(x, y); got != want {
+						t.Errorf("packed score at (%d, %d) = %v, want %v", x, y, got, want)
+					}
+					if got := fftScores[(y-minY)*cols+x-minX]; math.Abs(got-want) > 1e-6 {
+						t.Errorf("FFT score at (%d, %d) = %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMatchTemplateInAreaWith(t *testing.T) {
+	img := testTexture(200, 160, 30, 3)
+	intArr := GetIntegralArray(img)
+	tests := []struct {
+		name         string
+		tplX, tplY   int
+		tw, th       int
+		area         [4]int
+		wantX, wantY int
+		wantScore    float64
+	}{
+		{"whole image", 101, 47, 24, 24, [4]int{0, 0, 200, 160}, 101, 47, 1},
+		{"within area", 64, 90, 17, 21, [4]int{60, 90, 20, 20}, 64, 90, 1},
+		{"at the image corner", 176, 136, 24, 24, [4]int{150, 120, 50, 40}, 176, 136, 1},
+		{"area outside the image", 10, 10, 20, 20, [4]int{300, 300, 20, 20}, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		tpl := testCrop(img, tt.tplX, tt.tplY, tt.tw, tt.th)
+		tplStats := GetImageStats(tpl)
+		for _, method := range []MatchMethod{MatchAuto, MatchPacked, MatchFFT, MatchReference} {
+			t.Run(fmt.Sprintf("%s/%s", tt.name, method), func(t *testing.T) {
+				x, y, score := MatchTemplateInAreaWith(method, img, intArr, tpl, tplStats, tt.area[0], tt.area[1], tt.area[2], tt.area[3])
+				if x != tt.wantX || y != tt.wantY || math.Abs(score-tt.wantScore) > 1e-6 {
+					t.Errorf("MatchTemplateInAreaWith() = (%d, %d, %.6f), want (%d, %d, %.6f)", x, y, score, tt.wantX, tt.wantY, tt.wantScore)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkMatchTemplateInAreaWith(b *testing.B) {
+	img := testTexture(600, 600, 0, 4)
+	intArr := GetIntegralArray(img)
+	sizes := []struct {
+		name   string
+		tw, th int
+		area   int
+	}{
+		{"small area", 80, 80, 40},
+		{"medium area", 80, 80, 160},
+		{"whole image", 80, 80, 600},
+	}
+	for _, size := range sizes {
+		tpl := testCrop(img, 300-size.tw/2, 300-size.th/2, size.tw, size.th)
+		tplStats := GetImageStats(tpl)
+		ax, ay := 300-size.area/2, 300-size.area/2
+		for _, method := range []MatchMethod{MatchReference, MatchPacked, MatchFFT, MatchAuto} {
+			b.Run(fmt.Sprintf("%s/%s", size.name, method), func(b *testing.B) {
+				for b.Loop() {
+					MatchTemplateInAreaWith(method, img, intArr, tpl, tplStats, ax, ay, size.area, size.area)
+				}
+			})
+		}
+	}
+}
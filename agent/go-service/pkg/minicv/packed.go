@@ -0,0 +1,115 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"image"
+)
+
+const packedExactBlock = 1024 // Max values of one block of dot32
+
+// PackedImage holds the RGB values of an image as float32 without alpha, row by row,
+// so that the inner loop of template matching runs over contiguous memory
+type PackedImage struct {
+	Pix  []float32 // R, G, B of each pixel, row by row
+	W, H int
+}
+
+// ImagePack packs the rectangle (x, y, w, h) of an image
+func ImagePack(img *image.RGBA, x, y, w, h int) *PackedImage {
+	p := &PackedImage{Pix: make([]float32, w*h*3), W: w, H: h}
+	ipx, is := img.Pix, img.Stride
+	for j := range h {
+		off := (y+j)*is + x*4
+		row := p.Pix[j*w*3 : (j+1)*w*3]
+		for i := 0; i < len(row); i += 3 {
+			row[i] = float32(ipx[off])
+			row[i+1] = float32(ipx[off+1])
+			row[i+2] = float32(ipx[off+2])
+			off += 4
+		}
+	}
+	return p
+}
+
+// dot32 returns the dot product of a and b[:len(a)] of 8-bit values.
+// Each of the independent accumulators sums at most packedExactBlock/4 products of at most 255*255,
+// which stays below 2^24 so that float32 represents every partial sum exactly.
+func dot32(a, b []float32) float64 {
+	b = b[:len(a)]
+	var sum float64
+	for len(a) > 0 {
+		n := min(len(a), packedExactBlock)
+		x, y := a[:n], b[:n]
+		var s0, s1, s2, s3 float32
+		i := 0
+		for ; i+4 <= len(x); i += 4 {
+			s0 += x[i] * y[i]
+			s1 += x[i+1] * y[i+1]
+			s2 += x[i+2] * y[i+2]
+			s3 += x[i+3] * y[i+3]
+		}
+		for ; i < len(x); i++ {
+			s0 += x[i] * y[i]
+		}
+		sum += float64(s0) + float64(s1) + float64(s2) + float64(s3)
+		a, b = a[n:], b[n:]
+	}
+	return sum
+}
+
+// packedCorrelation returns the sum of products of the packed image at (ox, oy) and the packed template
+func packedCorrelation(img, tpl *PackedImage, ox, oy int) float64 {
+	rowLen := tpl.W * 3
+	is := img.W * 3
+	iOff := oy*is + ox*3
+	var sum float64
+	for y := range tpl.H {
+		sum += dot32(tpl.Pix[y*rowLen:(y+1)*rowLen], img.Pix[iOff:])
+		iOff += is
+	}
+	return sum
+}
+
+// packedMatcher computes NCC scores of a template on a region of an image through packed images,
+// which are identical to those of ComputeNCC as the correlation is exact
+type packedMatcher struct {
+	img        *PackedImage
+	tpl        *PackedImage
+	imgIntArr  IntegralArray
+	tplStats   StatsResult
+	rx, ry     int // Top-left corner of the packed region on the image
+	tw, th     int
+	maxX, maxY int // Max top-left corner of the template on the image
+}
+
+// newPackedMatcher packs the region of the image that templates at top-left corners
+// within [minX, maxX] x [minY, maxY] cover
+func newPackedMatcher(img *image.RGBA, imgIntArr IntegralArray, tpl *image.RGBA, tplStats StatsResult, minX, minY, maxX, maxY int) *packedMatcher {
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	return &packedMatcher{
+		img:       ImagePack(img, minX, minY, maxX-minX+tw, maxY-minY+th),
+		tpl:       ImagePack(tpl, 0, 0, tw, th),
+		imgIntArr: imgIntArr,
+		tplStats:  tplStats,
+		rx:        minX,
+		ry:        minY,
+		tw:        tw,
+		th:        th,
+		maxX:      maxX,
+		maxY:      maxY,
+	}
+}
+
+// score returns the NCC score with the template at (x, y) on the image, 0 if outside the packed region
+func (m *packedMatcher) score(x, y int) float64 {
+	if x < m.rx || y < m.ry || x > m.maxX || y > m.maxY {
+		return 0.0
+	}
+	imgStats := m.imgIntArr.GetAreaStats(x, y, m.tw, m.th)
+	stdProd := imgStats.Std * m.tplStats.Std
+	if stdProd < 1e-12 {
+		return 0.0
+	}
+	count := float64(m.tw * m.th * 3)
+	return (packedCorrelation(m.img, m.tpl, x-m.rx, y-m.ry) - count*imgStats.Mean*m.tplStats.Mean) / stdProd
+}
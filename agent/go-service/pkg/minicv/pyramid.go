@@ -0,0 +1,144 @@
+// Copyright (c) 2026 Harry Huang
+package minicv
+
+import (
+	"image"
+	"sort"
+)
+
+// MatchCandidate represents a candidate match position, where (X, Y) is the top-left corner
+type MatchCandidate struct {
+	X, Y  int
+	Score float64
+}
+
+// ImageDownsample shrinks an image by an integer factor using box filtering,
+// which avoids the aliasing of point sampling when building coarse pyramid levels
+func ImageDownsample(img *image.RGBA, factor int) *image.RGBA {
+	if factor <= 1 {
+		return img
+	}
+	w, h := img.Rect.Dx()/factor, img.Rect.Dy()/factor
+	w, h = max(w, 1), max(h, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	dpx, ds := dst.Pix, dst.Stride
+	ipx, is := img.Pix, img.Stride
+	area := uint32(factor * factor)
+
+	for y := range h {
+		for x := range w {
+			var r, g, b, a uint32
+			for sy := y * factor; sy < min((y+1)*factor, img.Rect.Dy()); sy++ {
+				off := sy*is + x*factor*4
+				for sx := x * factor; sx < min((x+1)*factor, img.Rect.Dx()); sx++ {
+					r += uint32(ipx[off])
+					g += uint32(ipx[off+1])
+					b += uint32(ipx[off+2])
+					a += uint32(ipx[off+3])
+					off += 4
+				}
+			}
+			dOff := y*ds + x*4
+			dpx[dOff] = uint8(r / area)
+			dpx[dOff+1] = uint8(g / area)
+			dpx[dOff+2] = uint8(b / area)
+			dpx[dOff+3] = uint8(a / area)
+		}
+	}
+	return dst
+}
+
+// MatchTemplateTopK performs exhaustive template matching on the whole image,
+// returns at most k best candidates that are at least minDist apart (Chebyshev distance), sorted by score
+func MatchTemplateTopK(
+	img *image.RGBA,
+	imgIntArr IntegralArray,
+	tpl *image.RGBA,
+	tplStats StatsResult,
+	k int,
+	minDist int,
+) []MatchCandidate {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	if k <= 0 || tw > iw || th > ih {
+		return nil
+	}
+
+	// Score every position, then pick local maxima greedily
+	cols, rows := iw-tw+1, ih-th+1
+	var scores []float64
+	if useFFT(cols, rows, tw, th, 1) {
+		scores = nccScoresFFT(img, imgIntArr, tpl, tplStats, 0, 0, cols-1, rows-1)
+	} else {
+		m := newPackedMatcher(img, imgIntArr, tpl, tplStats, 0, 0, cols-1, rows-1)
+		scores = make([]float64, cols*rows)
+		numWorkers := 4
+		done := make(chan struct{}, numWorkers)
+		for i := range numWorkers {
+			go func(id int) {
+				for y := id; y < rows; y += numWorkers {
+					for x := range cols {
+						scores[y*cols+x] = m.score(x, y)
+					}
+				}
+				done <- struct{}{}
+			}(i)
+		}
+		for range numWorkers {
+			<-done
+		}
+	}
+
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	candidates := make([]MatchCandidate, 0, k)
+	for _, idx := range order {
+		x, y := idx%cols, idx/cols
+		suppressed := false
+		for _, c := range candidates {
+			if abs(c.X-x) < minDist && abs(c.Y-y) < minDist {
+				suppressed = true
+				break
+			}
+		}
+		if suppressed {
+			continue
+		}
+		candidates = append(candidates, MatchCandidate{x, y, scores[idx]})
+		if len(candidates) >= k {
+			break
+		}
+	}
+	return candidates
+}
+
+// RefineMatch refines a candidate found on an image downsampled by factor,
+// by matching around its corresponding position on the full resolution image.
+// Returns (x, y, score) of the best match, where (x, y) is the top-left corner.
+func RefineMatch(
+	img *image.RGBA,
+	imgIntArr IntegralArray,
+	tpl *image.RGBA,
+	tplStats StatsResult,
+	cand MatchCandidate,
+	factor int,
+) (int, int, float64) {
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	radius := factor + 1
+	cx, cy := cand.X*factor+tw/2, cand.Y*factor+th/2
+	return MatchTemplateInArea(img, imgIntArr, tpl, tplStats, cx-radius, cy-radius, radius*2+1, radius*2+1)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}